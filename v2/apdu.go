@@ -0,0 +1,143 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "errors"
+import "fmt"
+
+// ISO/IEC 7816-4 status words used by the card emulators in this package.
+const (
+	SWSuccess                = 0x9000
+	SWWrongLength            = 0x6700
+	SWSecurityNotSatisfied   = 0x6982
+	SWConditionsNotSatisfied = 0x6985
+	SWWrongData              = 0x6a80
+	SWFileNotFound           = 0x6a82
+	SWIncorrectP1P2          = 0x6a86
+	SWWrongP1P2              = 0x6b00
+	SWINSNotSupported        = 0x6d00
+	SWCLANotSupported        = 0x6e00
+	SWUnknown                = 0x6f00
+)
+
+// ISO/IEC 7816-4 instructions.
+const (
+	APDUSelect       = 0xa4
+	APDUReadBinary   = 0xb0
+	APDUUpdateBinary = 0xd6
+)
+
+// APDU is an ISO/IEC 7816-4 command APDU.
+type APDU struct {
+	CLA, INS, P1, P2 byte
+	Data             []byte // command data, nil if Lc is absent
+	Le               int    // expected response length, 0 if absent
+}
+
+// Parse a short or extended command APDU. An Le of 0x00 (or 0x0000 for
+// extended APDUs) is returned as 256 (65536).
+func ParseAPDU(b []byte) (a APDU, err error) {
+	if len(b) < 4 {
+		err = errors.New("APDU: too short")
+		return
+	}
+
+	a.CLA, a.INS, a.P1, a.P2 = b[0], b[1], b[2], b[3]
+	b = b[4:]
+
+	switch {
+	case len(b) == 0:
+		// case 1
+
+	case len(b) == 1:
+		// case 2 short
+		a.Le = int(b[0])
+		if a.Le == 0 {
+			a.Le = 256
+		}
+
+	case b[0] != 0:
+		// case 3 or 4 short
+		lc := int(b[0])
+		switch len(b) {
+		case 1 + lc:
+		case 2 + lc:
+			a.Le = int(b[1+lc])
+			if a.Le == 0 {
+				a.Le = 256
+			}
+		default:
+			err = fmt.Errorf("APDU: Lc %d does not match length %d", lc, len(b)-1)
+			return
+		}
+
+		a.Data = b[1 : 1+lc]
+
+	case len(b) == 3:
+		// case 2 extended
+		a.Le = int(b[1])<<8 | int(b[2])
+		if a.Le == 0 {
+			a.Le = 65536
+		}
+
+	default:
+		// case 3 or 4 extended
+		if len(b) < 3 {
+			err = errors.New("APDU: truncated")
+			return
+		}
+
+		lc := int(b[1])<<8 | int(b[2])
+		switch len(b) {
+		case 3 + lc:
+		case 5 + lc:
+			a.Le = int(b[3+lc])<<8 | int(b[4+lc])
+			if a.Le == 0 {
+				a.Le = 65536
+			}
+		default:
+			err = fmt.Errorf("APDU: Lc %d does not match length %d", lc, len(b)-3)
+			return
+		}
+
+		a.Data = b[3 : 3+lc]
+	}
+
+	return
+}
+
+// SWError is an error carrying an ISO/IEC 7816-4 status word. Card
+// applications return an SWError to answer a command with that status word.
+type SWError uint16
+
+func (e SWError) Error() string {
+	return fmt.Sprintf("APDU: status word %04x", uint16(e))
+}
+
+// Turn status word sw into an error, nil for SWSuccess.
+func swError(sw uint16) error {
+	if sw == SWSuccess {
+		return nil
+	}
+
+	return SWError(sw)
+}
+
+// Make a response APDU from data and status word sw.
+func apduResponse(data []byte, sw uint16) []byte {
+	res := make([]byte, len(data), len(data)+2)
+	copy(res, data)
+	return append(res, byte(sw>>8), byte(sw))
+}
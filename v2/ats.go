@@ -0,0 +1,210 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "errors"
+import "time"
+
+// Default values of the ATS fields if the corresponding interface byte is
+// absent. See ISO/IEC 14443-4 sec. 5.2.
+const (
+	atsDefaultFSCI = 2
+	atsDefaultTA   = 0x00
+	atsDefaultTB   = 0x40
+	atsDefaultTC   = 0x02
+)
+
+// Category indicators found in the first historical byte. See ISO/IEC
+// 7816-4 sec. 8.1.1.
+const (
+	ATSCategoryStatusLast = 0x00 // compact-TLV, status indicator in last three bytes
+	ATSCategoryDIR        = 0x10 // DIR data reference follows
+	ATSCategoryTLV        = 0x80 // compact-TLV, possibly including a status indicator
+)
+
+// Tag of the compact-TLV object holding the status indicator.
+const compactTLVStatus = 0x8
+
+// A compact-TLV data object from the historical bytes. See ISO/IEC 7816-4
+// sec. 8.1.1.2.
+type CompactTLV struct {
+	Tag   byte // 4 bit tag
+	Value []byte
+}
+
+// ATS holds the decoded contents of an answer to select. See ISO/IEC
+// 14443-4 sec. 5.2. Fields whose interface byte is absent from the ATS hold
+// the default value given by the standard.
+type ATS struct {
+	FSCI  int           // frame size for proximity card integer
+	FSC   int           // maximum frame size accepted by the PICC
+	SameD bool          // PICC requires the same divisor in both directions
+	DS    []int         // supported divisors PICC to PCD, always including 1
+	DR    []int         // supported divisors PCD to PICC, always including 1
+	FWI   int           // frame waiting time integer
+	FWT   time.Duration // frame waiting time
+	SFGI  int           // start-up frame guard time integer
+	SFGT  time.Duration // start-up frame guard time, 0 if none required
+	NAD   bool          // PICC supports NAD
+	CID   bool          // PICC supports CID
+	TA    byte          // raw interface bytes TA(1), TB(1) and TC(1)
+	TB    byte
+	TC    byte
+
+	Historical []byte       // historical bytes T1 to Tk
+	Category   byte         // category indicator, first historical byte
+	DIR        byte         // DIR data reference if Category is ATSCategoryDIR
+	TLV        []CompactTLV // compact-TLV data objects if Category is 0x00 or 0x80
+	Status     []byte       // status indicator, if any
+}
+
+// Compute a frame waiting time or start-up frame guard time from its
+// integer: 256 * 16 / fc * 2^i.
+func atsWaitingTime(i int) time.Duration {
+	return time.Duration(256*16<<uint(i)) * time.Second / 13560000
+}
+
+// Decode an ATS as stored in the Ats field of an ISO14443aTarget, i.e.
+// starting with the format byte T0 and without the length byte TL. An empty
+// ATS decodes to the default values. The historical bytes are decoded
+// into compact-TLV objects if the category indicator says so; if that
+// fails, TLV and Status are left nil without an error, as the historical
+// bytes are often proprietary anyway.
+func ParseATS(ats []byte) (*ATS, error) {
+	a := &ATS{FSCI: atsDefaultFSCI, TA: atsDefaultTA, TB: atsDefaultTB, TC: atsDefaultTC}
+
+	if len(ats) > 0 {
+		t0 := ats[0]
+		a.FSCI = int(t0 & 0x0f)
+		offset := 1
+		for _, ib := range []struct {
+			mask byte
+			b    *byte
+		}{{0x10, &a.TA}, {0x20, &a.TB}, {0x40, &a.TC}} {
+			if t0&ib.mask == 0 {
+				continue
+			}
+
+			if offset >= len(ats) {
+				return nil, errors.New("ATS: truncated interface bytes")
+			}
+
+			*ib.b = ats[offset]
+			offset++
+		}
+
+		if offset < len(ats) {
+			a.Historical = ats[offset:]
+		}
+	}
+
+	// FSCI values past 8 are RFU and interpreted as 8
+	if a.FSCI < len(describeFrameSizes) {
+		a.FSC = describeFrameSizes[a.FSCI]
+	} else {
+		a.FSC = describeFrameSizes[len(describeFrameSizes)-1]
+	}
+
+	a.SameD = a.TA&0x80 != 0
+	a.DS = []int{1}
+	a.DR = []int{1}
+	for i, d := range []int{2, 4, 8} {
+		if a.TA&(0x10<<uint(i)) != 0 {
+			a.DS = append(a.DS, d)
+		}
+
+		if a.TA&(0x01<<uint(i)) != 0 {
+			a.DR = append(a.DR, d)
+		}
+	}
+
+	// FWI and SFGI 15 are RFU and interpreted as the default values
+	a.FWI = int(a.TB >> 4)
+	if a.FWI == 15 {
+		a.FWI = atsDefaultTB >> 4
+	}
+
+	a.FWT = atsWaitingTime(a.FWI)
+	a.SFGI = int(a.TB & 0x0f)
+	if a.SFGI == 15 {
+		a.SFGI = 0
+	}
+
+	if a.SFGI != 0 {
+		a.SFGT = atsWaitingTime(a.SFGI)
+	}
+
+	a.NAD = a.TC&0x01 != 0
+	a.CID = a.TC&0x02 != 0
+
+	a.parseHistorical()
+	return a, nil
+}
+
+// Decode the historical bytes by their category indicator.
+func (a *ATS) parseHistorical() {
+	if len(a.Historical) == 0 {
+		return
+	}
+
+	a.Category = a.Historical[0]
+	rest := a.Historical[1:]
+	switch a.Category {
+	case ATSCategoryStatusLast:
+		if len(rest) < 3 {
+			return
+		}
+
+		tlv, err := ParseCompactTLV(rest[:len(rest)-3])
+		if err != nil {
+			return
+		}
+
+		a.TLV = tlv
+		a.Status = rest[len(rest)-3:]
+	case ATSCategoryDIR:
+		if len(rest) > 0 {
+			a.DIR = rest[0]
+		}
+	case ATSCategoryTLV:
+		tlv, err := ParseCompactTLV(rest)
+		if err != nil {
+			return
+		}
+
+		a.TLV = tlv
+		if len(tlv) > 0 && tlv[len(tlv)-1].Tag == compactTLVStatus {
+			a.Status = tlv[len(tlv)-1].Value
+		}
+	}
+}
+
+// Decode a sequence of compact-TLV data objects. Each object begins with a
+// byte holding the tag in its upper and the length in its lower nibble.
+func ParseCompactTLV(b []byte) ([]CompactTLV, error) {
+	var tlv []CompactTLV
+
+	for len(b) > 0 {
+		tag, n := b[0]>>4, int(b[0]&0x0f)
+		if 1+n > len(b) {
+			return nil, errors.New("ATS: truncated compact-TLV object")
+		}
+
+		tlv = append(tlv, CompactTLV{Tag: tag, Value: b[1 : 1+n]})
+		b = b[1+n:]
+	}
+
+	return tlv, nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "reflect"
+import "testing"
+import "time"
+
+func TestParseATS(t *testing.T) {
+	// MIFARE DESFire EV1
+	a, err := ParseATS([]byte{0x75, 0x77, 0x81, 0x02, 0x80})
+	if err != nil {
+		t.Fatal("ParseATS():", err)
+	}
+
+	want := &ATS{
+		FSCI: 5, FSC: 64,
+		SameD: false, DS: []int{1, 2, 4, 8}, DR: []int{1, 2, 4, 8},
+		FWI: 8, FWT: 77328613 * time.Nanosecond,
+		SFGI: 1, SFGT: 604129 * time.Nanosecond,
+		NAD: false, CID: true,
+		TA: 0x77, TB: 0x81, TC: 0x02,
+		Historical: []byte{0x80}, Category: ATSCategoryTLV,
+	}
+
+	if !reflect.DeepEqual(a, want) {
+		t.Errorf("ParseATS() = %+v, want %+v", a, want)
+	}
+
+	// defaults
+	a, err = ParseATS(nil)
+	if err != nil || a.FSC != 32 || a.FWI != 4 || a.SFGT != 0 || !a.CID || a.NAD || len(a.DS) != 1 {
+		t.Errorf("ParseATS(nil) = %+v, %v", a, err)
+	}
+
+	// compact-TLV with status indicator in the last three bytes
+	a, err = ParseATS([]byte{0x78, 0x33, 0xa0, 0x03, 0x00, 0x31, 0xfe, 0x45, 0x90, 0x00})
+	if err != nil {
+		t.Fatal("ParseATS():", err)
+	}
+
+	if len(a.TLV) != 1 || a.TLV[0].Tag != 0x3 || !bytes.Equal(a.TLV[0].Value, []byte{0xfe}) {
+		t.Errorf("TLV = %+v", a.TLV)
+	}
+
+	if !bytes.Equal(a.Status, []byte{0x45, 0x90, 0x00}) {
+		t.Errorf("Status = % x", a.Status)
+	}
+
+	if a.SameD || len(a.DS) != 3 || len(a.DR) != 3 {
+		t.Errorf("DS = %v, DR = %v", a.DS, a.DR)
+	}
+
+	if _, err = ParseATS([]byte{0x75, 0x77}); err == nil {
+		t.Error("ParseATS() accepted truncated interface bytes")
+	}
+}
+
+func TestLocateHistoricalBytes(t *testing.T) {
+	tests := []struct {
+		ats, want []byte
+	}{
+		{[]byte{0x75, 0x77, 0x81, 0x02, 0x80}, []byte{0x80}},
+		{[]byte{0x48, 0x80, 0x70, 0x02, 0xc1, 0x05}, []byte{0x70, 0x02, 0xc1, 0x05}},
+		{[]byte{0x05}, nil},
+		{[]byte{0x75}, nil},
+		{nil, nil},
+	}
+
+	for _, tt := range tests {
+		if got := ISO14443aLocateHistoricalBytes(tt.ats); !bytes.Equal(got, tt.want) {
+			t.Errorf("ISO14443aLocateHistoricalBytes(% x) = % x, want % x", tt.ats, got, tt.want)
+		}
+	}
+}
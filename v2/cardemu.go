@@ -0,0 +1,221 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+// the package has its own type called context
+import gocontext "context"
+import "bytes"
+import "errors"
+import "time"
+
+// CardApplication is an application of an emulated ISO-DEP card, selected
+// by its AID. See CardEmulator.
+//
+// The methods return the response data of a command. If they return nil
+// error, the status word SWSuccess is appended. If they return an SWError,
+// its status word is sent instead and the data is discarded. Other errors
+// are answered with SWUnknown.
+type CardApplication interface {
+	// Called when the application is selected with SELECT by AID. The
+	// SELECT command is passed.
+	Select(cmd APDU) ([]byte, error)
+
+	// Called for each command while the application is selected.
+	Process(cmd APDU) ([]byte, error)
+
+	// Called when another application is selected or the session ends.
+	Deselect()
+}
+
+// An application registered with a CardEmulator.
+type cardRoute struct {
+	aid []byte
+	app CardApplication
+}
+
+// CardEmulator emulates an ISO-DEP (ISO14443-4) card hosting several
+// applications, similar to host card emulation on Android. SELECT by AID
+// commands are routed to the registered applications; SELECT of an unknown
+// AID is answered with SWFileNotFound. All other commands go to the
+// currently selected application. The ISO14443-4 layer is handled by the
+// device, which therefore needs to support it (e.g. the PN532).
+type CardEmulator struct {
+	Device  TargetDevice    // device used to emulate the card
+	Target  ISO14443aTarget // the card emulated, must have an ATS
+	Timeout time.Duration   // see Device.TargetReceiveBytesTimeout()
+
+	routes  []cardRoute
+	current CardApplication
+}
+
+// The default ISO14443-4 target emulated: a PN53x can only emulate UIDs
+// beginning with 0x08.
+func defaultISODEPTarget() ISO14443aTarget {
+	t := ISO14443aTarget{
+		Atqa:   [2]byte{0x00, 0x04},
+		Sak:    0x20,
+		UIDLen: 4,
+		UID:    [10]byte{0x08, 0x00, 0xb0, 0x0b},
+		AtsLen: 4,
+		Baud:   Nbr106,
+	}
+
+	copy(t.Ats[:], []byte{0x75, 0x33, 0x92, 0x03})
+
+	return t
+}
+
+// Make a new CardEmulator using d with a default target and no
+// applications. The timeout is NoTimeout, so the emulator waits for
+// readers indefinitely.
+func NewCardEmulator(d TargetDevice) *CardEmulator {
+	return &CardEmulator{Device: d, Target: defaultISODEPTarget()}
+}
+
+// Register app under aid. SELECT commands for aid are routed to app.
+// Registering an AID again replaces the previous application.
+func (e *CardEmulator) Register(aid []byte, app CardApplication) {
+	for i := range e.routes {
+		if bytes.Equal(e.routes[i].aid, aid) {
+			e.routes[i].app = app
+			return
+		}
+	}
+
+	e.routes = append(e.routes, cardRoute{append([]byte(nil), aid...), app})
+}
+
+// Find the application registered under aid.
+func (e *CardEmulator) lookup(aid []byte) CardApplication {
+	for _, r := range e.routes {
+		if bytes.Equal(r.aid, aid) {
+			return r.app
+		}
+	}
+
+	return nil
+}
+
+// Deselect the current application, if any.
+func (e *CardEmulator) deselect() {
+	if e.current != nil {
+		e.current.Deselect()
+		e.current = nil
+	}
+}
+
+// Make a response APDU from the results of a CardApplication.
+func cardResponse(data []byte, err error) []byte {
+	var sw SWError
+	switch {
+	case err == nil:
+		return apduResponse(data, SWSuccess)
+	case errors.As(err, &sw):
+		return apduResponse(nil, uint16(sw))
+	default:
+		return apduResponse(nil, SWUnknown)
+	}
+}
+
+// Process a command APDU and return the response APDU.
+func (e *CardEmulator) Process(cmd []byte) []byte {
+	a, err := ParseAPDU(cmd)
+	if err != nil {
+		return apduResponse(nil, SWWrongLength)
+	}
+
+	if a.INS == APDUSelect && a.P1 == 0x04 {
+		app := e.lookup(a.Data)
+		if app == nil {
+			e.deselect()
+			return apduResponse(nil, SWFileNotFound)
+		}
+
+		if app != e.current {
+			e.deselect()
+			e.current = app
+		}
+
+		return cardResponse(app.Select(a))
+	}
+
+	if e.current == nil {
+		return apduResponse(nil, SWConditionsNotSatisfied)
+	}
+
+	return cardResponse(e.current.Process(a))
+}
+
+// Emulate the card for one session: wait for a reader with TargetInit() and
+// answer its commands until it deselects or releases the card, in which case
+// nil is returned, or until ctx is cancelled, in which case ctx.Err() is
+// returned. Call Run() in a loop to serve one reader after another.
+// Cancellation aborts the pending command if the device has a method
+// AbortCommand() like Device does.
+func (e *CardEmulator) Run(ctx gocontext.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	timeout, err := timeoutMillis(e.Timeout)
+	if err != nil {
+		return err
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+
+	if a, ok := e.Device.(interface{ AbortCommand() error }); ok {
+		go func() {
+			select {
+			case <-ctx.Done():
+				a.AbortCommand()
+			case <-stop:
+			}
+		}()
+	}
+
+	defer e.deselect()
+
+	rx := make([]byte, 264)
+	n, _, err := e.Device.TargetInit(&e.Target, rx, timeout)
+	if err == nil {
+		err = e.Device.SetPropertyBool(EasyFraming, true)
+	}
+
+	for err == nil && ctx.Err() == nil {
+		// S(DESELECT) in case the device passes it on
+		if n == 1 && rx[0] == 0xc2 {
+			_, err = e.Device.TargetSendBytes(rx[:1], timeout)
+			break
+		}
+
+		if _, err = e.Device.TargetSendBytes(e.Process(rx[:n]), timeout); err != nil {
+			break
+		}
+
+		n, err = e.Device.TargetReceiveBytes(rx, timeout)
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if err == Error(ETGRELEASED) {
+		err = nil
+	}
+
+	return err
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import gocontext "context"
+import "bytes"
+import "errors"
+import "testing"
+
+// A CardApplication echoing the data of each command.
+type echoApplication struct {
+	selected bool
+}
+
+func (e *echoApplication) Select(cmd APDU) ([]byte, error) {
+	e.selected = true
+	return []byte("echo"), nil
+}
+
+func (e *echoApplication) Process(cmd APDU) ([]byte, error) {
+	switch cmd.INS {
+	case 0x01:
+		return cmd.Data, nil
+	case 0x02:
+		return nil, errors.New("failure")
+	default:
+		return nil, SWError(SWINSNotSupported)
+	}
+}
+
+func (e *echoApplication) Deselect() {
+	e.selected = false
+}
+
+// Route APDUs to an echo application and a Type 4 Tag application.
+func TestCardEmulator(t *testing.T) {
+	msg := []byte{0xd1, 0x01, 0x04, 'U', 0x04, 'a', '.', 'b'}
+	echoAID := []byte{0xf0, 0x01, 0x02, 0x03, 0x04, 0x05}
+
+	dev := &fakeTargetDevice{script: [][]byte{
+		{0x00, 0x01, 0x00, 0x00, 0x01, 0xaa},
+		append([]byte{0x00, 0xa4, 0x04, 0x00, 0x06}, echoAID...),
+		{0x00, 0x01, 0x00, 0x00, 0x02, 0xaa, 0xbb},
+		{0x00, 0x02, 0x00, 0x00},
+		{0x00, 0x03, 0x00, 0x00},
+		{0x00, 0xa4, 0x04, 0x00, 0x07, 0xd2, 0x76, 0x00, 0x00, 0x85, 0x01, 0x01, 0x00},
+		{0x00, 0xa4, 0x00, 0x0c, 0x02, 0xe1, 0x04},
+		{0x00, 0xb0, 0x00, 0x02, 0x08},
+		{0x00, 0xa4, 0x04, 0x00, 0x03, 0xa0, 0x00, 0x00},
+		{0x00, 0xb0, 0x00, 0x02, 0x08},
+		{0x00, 0xa4},
+	}}
+
+	echo := &echoApplication{}
+	e := NewCardEmulator(dev)
+	e.Register(echoAID, echo)
+	e.Register(Type4AID, NewType4Emulator(nil, NewNDEFBuffer(msg, false)).Application())
+
+	if err := e.Run(gocontext.Background()); err != nil {
+		t.Fatal("Run():", err)
+	}
+
+	want := [][]byte{
+		{0x69, 0x85},
+		{'e', 'c', 'h', 'o', 0x90, 0x00},
+		{0xaa, 0xbb, 0x90, 0x00},
+		{0x6f, 0x00},
+		{0x6d, 0x00},
+		{0x90, 0x00},
+		{0x90, 0x00},
+		append(append([]byte(nil), msg...), 0x90, 0x00),
+		{0x6a, 0x82},
+		{0x69, 0x85},
+		{0x67, 0x00},
+	}
+
+	if len(dev.sent) != len(want) {
+		t.Fatalf("got %d responses, want %d", len(dev.sent), len(want))
+	}
+
+	for i := range want {
+		if !bytes.Equal(dev.sent[i], want[i]) {
+			t.Errorf("response %d: got % x, want % x", i, dev.sent[i], want[i])
+		}
+	}
+
+	if echo.selected {
+		t.Error("echo application still selected after Run()")
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if err := e.Run(ctx); err != gocontext.Canceled {
+		t.Errorf("Run() with cancelled context: got error %v, want %v", err, gocontext.Canceled)
+	}
+}
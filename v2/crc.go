@@ -49,6 +49,7 @@ func ISO14443bCRC(data []byte) [2]byte {
 		crc = (crc >> 8) ^ (bt32 << 8) ^ (bt32 << 3) ^ (bt32 >> 4)
 	}
 
+	crc = ^crc
 	return [2]byte{byte(crc & 0xff), byte((crc >> 8) & 0xff)}
 }
 
@@ -59,19 +60,19 @@ func AppendISO14443bCRC(data []byte) []byte {
 }
 
 // Locate historical bytes according to ISO/IEC 14443-4 sec. 5.2.7. Return nil
-// if that fails.
+// if that fails. See ParseATS() for a full decoding of the ATS.
 func ISO14443aLocateHistoricalBytes(ats []byte) []byte {
 	if len(ats) > 0 {
 		offset := 1
-		if ats[0]&0x10 != 0 {
+		if ats[0]&0x10 != 0 { // TA
 			offset++
 		}
 
-		if ats[1]&0x20 != 0 {
+		if ats[0]&0x20 != 0 { // TB
 			offset++
 		}
 
-		if ats[2]&0x40 != 0 {
+		if ats[0]&0x40 != 0 { // TC
 			offset++
 		}
 
@@ -0,0 +1,42 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "testing"
+
+// HLTA and REQB with their well-known CRCs. The CRC_B is the complement of
+// the CRC register, see ISO/IEC 14443-3 annex B.
+func TestCRC(t *testing.T) {
+	if crc := ISO14443aCRC([]byte{0x50, 0x00}); crc != [2]byte{0x57, 0xcd} {
+		t.Errorf("ISO14443aCRC(50 00) = % x, want 57 cd", crc)
+	}
+
+	if crc := ISO14443bCRC([]byte{0x05, 0x00, 0x08}); crc != [2]byte{0x39, 0x73} {
+		t.Errorf("ISO14443bCRC(05 00 08) = % x, want 39 73", crc)
+	}
+
+	if crc := ISO14443bCRC(nil); crc != [2]byte{0x00, 0x00} {
+		t.Errorf("ISO14443bCRC() = % x, want 00 00", crc)
+	}
+
+	if b := AppendISO14443aCRC([]byte{0x50, 0x00}); !bytes.Equal(b, []byte{0x50, 0x00, 0x57, 0xcd}) {
+		t.Errorf("AppendISO14443aCRC(50 00) = % x", b)
+	}
+
+	if b := AppendISO14443bCRC([]byte{0x05, 0x00, 0x08}); !bytes.Equal(b, []byte{0x05, 0x00, 0x08, 0x39, 0x73}) {
+		t.Errorf("AppendISO14443bCRC(05 00 08) = % x", b)
+	}
+}
@@ -0,0 +1,599 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "crypto/rand"
+import "errors"
+import "fmt"
+import "time"
+
+// NFC-DEP (ISO/IEC 18092) command bytes. Each request CMD1 is answered by a
+// response with CMD1 + 1. Requests are sent with CMD0 DEPReq, responses with
+// CMD0 DEPRes.
+const (
+	DEPReq = 0xd4
+	DEPRes = 0xd5
+
+	DEPAtrReq = 0x00
+	DEPWupReq = 0x02
+	DEPPslReq = 0x04
+	DEPDepReq = 0x06
+	DEPDslReq = 0x08
+	DEPRlsReq = 0x0a
+)
+
+// Bits of the PFB byte of DEP_REQ and DEP_RES.
+const (
+	depPFBType  = 0xe0 // mask for the PDU type
+	depPFBInfo  = 0x00 // information PDU
+	depPFBAck   = 0x40 // ACK/NACK PDU
+	depPFBSuper = 0x80 // supervisory PDU (ATN/RTOX)
+	depPFBMI    = 0x10 // more information (information), NACK (ACK), RTOX (supervisory)
+	depPFBNAD   = 0x08 // NAD present
+	depPFBDID   = 0x04 // DID present
+	depPFBPNI   = 0x03 // mask for the packet number
+)
+
+// Maximum size of an NFC-DEP frame including the length byte.
+const depMaxFrame = 256
+
+// Return the general bytes of t.
+func (t *DEPTarget) GeneralBytes() []byte {
+	return t.GB[:t.GBLen]
+}
+
+// Set the general bytes of t to gb, which must not be longer than 48 bytes.
+func (t *DEPTarget) SetGeneralBytes(gb []byte) error {
+	if len(gb) > len(t.GB) {
+		return Error(EINVARG)
+	}
+
+	t.GB = [48]byte{}
+	t.GBLen = copy(t.GB[:], gb)
+	return nil
+}
+
+// Return the maximum payload length of the transport data of a frame that
+// can be sent to a peer with the given PP byte (the LR value), accounting for
+// the header of a DEP_REQ/DEP_RES.
+func depMaxPayload(pp byte, did byte) int {
+	n := [4]int{64, 128, 192, 254}[pp>>4&3] - 3
+	if did != 0 {
+		n--
+	}
+
+	return n
+}
+
+// Strip the start byte and length byte from a received NFC-DEP frame if
+// present and verify the length.
+func depStripFrame(rx []byte) ([]byte, error) {
+	if len(rx) > 0 && rx[0] == 0xf0 {
+		rx = rx[1:]
+	}
+
+	if len(rx) > 0 && int(rx[0]) == len(rx) {
+		rx = rx[1:]
+	}
+
+	if len(rx) < 2 || rx[0] != DEPReq && rx[0] != DEPRes {
+		return nil, errors.New("DEP: malformed frame")
+	}
+
+	return rx, nil
+}
+
+// Parse an ATR_REQ frame as received by TargetInit() into a DEPTarget holding
+// the parameters of the initiator.
+func parseDEPAtrReq(rx []byte) (t DEPTarget, err error) {
+	rx, err = depStripFrame(rx)
+	if err != nil {
+		return
+	}
+
+	if len(rx) < 16 || rx[0] != DEPReq || rx[1] != DEPAtrReq {
+		err = errors.New("DEP: malformed ATR_REQ")
+		return
+	}
+
+	copy(t.NFCID3[:], rx[2:12])
+	t.DID = rx[12]
+	t.BS = rx[13]
+	t.BR = rx[14]
+	t.PP = rx[15]
+	if rx[15]&0x02 != 0 {
+		err = t.SetGeneralBytes(rx[16:])
+	}
+
+	return
+}
+
+// depLink transports single NFC-DEP frames starting with CMD0. For the
+// initiator, transceive sends a request and returns the response. For the
+// target, it sends a response (unless tx is nil) and returns the next
+// request. send transmits a frame without waiting for an answer.
+type depLink interface {
+	transceive(tx []byte) ([]byte, error)
+	send(tx []byte) error
+}
+
+// Frame transport over a Device configured as initiator.
+type depInitiatorLink struct {
+	d       DEPInitiator
+	sb      bool // prepend start byte 0xf0 (passive mode, 106 kbps)
+	timeout int  // in ms
+}
+
+func (l *depInitiatorLink) transceive(tx []byte) ([]byte, error) {
+	frame := make([]byte, 0, len(tx)+2)
+	if l.sb {
+		frame = append(frame, 0xf0)
+	}
+
+	frame = append(frame, byte(len(tx)+1))
+	frame = append(frame, tx...)
+
+	rx := make([]byte, depMaxFrame+1)
+	n, err := l.d.InitiatorTransceiveBytes(frame, rx, l.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return depStripFrame(rx[:n])
+}
+
+func (l *depInitiatorLink) send(tx []byte) error {
+	_, err := l.transceive(tx)
+	return err
+}
+
+// Frame transport over a Device configured as target.
+type depTargetLink struct {
+	d       TargetDevice
+	timeout int // in ms
+}
+
+func (l *depTargetLink) send(tx []byte) error {
+	frame := append([]byte{byte(len(tx) + 1)}, tx...)
+	_, err := l.d.TargetSendBytes(frame, l.timeout)
+	return err
+}
+
+func (l *depTargetLink) transceive(tx []byte) ([]byte, error) {
+	if tx != nil {
+		if err := l.send(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	rx := make([]byte, depMaxFrame+1)
+	n, err := l.d.TargetReceiveBytes(rx, l.timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return depStripFrame(rx[:n])
+}
+
+// DEPSession is an NFC-DEP (ISO/IEC 18092) peer-to-peer session between an
+// initiator and a target. The activation (ATR_REQ/ATR_RES) is performed by
+// the device; DEPSession implements the DEP_REQ/DEP_RES protocol on top of
+// it, including chaining of messages that exceed the frame size of the peer.
+// Create a session with DEPConnect() on the initiator or DEPListen() on the
+// target.
+//
+// Communication is half duplex: the initiator sends a message with
+// Exchange() and receives the answer of the target; the target answers the
+// last message with Exchange() and receives the next one.
+type DEPSession struct {
+	Mode   int       // Active or Passive
+	Baud   int       // baud rate
+	Local  DEPTarget // parameters of the local side
+	Remote DEPTarget // parameters of the peer as received in ATR_REQ/ATR_RES
+
+	link      depLink
+	initiator bool
+	did       byte
+	pni       byte // PNI of the last request
+	mtu       int  // maximum payload per frame sent to the peer
+}
+
+// Make a DEPSession over link. did is the device identifier or 0 if none is
+// used; pp is the PP byte of the peer determining the frame size.
+func newDEPSession(link depLink, initiator bool, did, pp byte) *DEPSession {
+	s := &DEPSession{
+		link:      link,
+		initiator: initiator,
+		did:       did,
+		pni:       depPFBPNI, // the first request carries PNI 0
+		mtu:       depMaxPayload(pp, did),
+	}
+
+	return s
+}
+
+// DEPInitiator is the subset of the methods of Device needed by
+// DEPConnect(). Device implements DEPInitiator.
+type DEPInitiator interface {
+	InitiatorSelectDEPTarget(mode, baud int, initiator *DEPTarget, timeout int) (*DEPTarget, error)
+	InitiatorTransceiveBytes(tx, rx []byte, timeout int) (n int, err error)
+	SetPropertyBool(property int, value bool) error
+}
+
+// Select a DEP target with d and establish a session. mode is Active or
+// Passive, baud the baud rate. gb holds the general bytes sent in ATR_REQ,
+// e.g. the LLCP parameters; it may be nil. This function disables
+// EasyFraming on d as DEPSession generates the frames itself. timeout applies
+// to the selection and to each frame exchanged in the session.
+func DEPConnect(d DEPInitiator, mode, baud int, gb []byte, timeout time.Duration) (*DEPSession, error) {
+	ms, err := timeoutMillis(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	local := DEPTarget{DepMode: mode, Baud: baud}
+	if _, err = rand.Read(local.NFCID3[:]); err != nil {
+		return nil, err
+	}
+
+	if err = local.SetGeneralBytes(gb); err != nil {
+		return nil, err
+	}
+
+	remote, err := d.InitiatorSelectDEPTarget(mode, baud, &local, ms)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = d.SetPropertyBool(EasyFraming, false); err != nil {
+		return nil, err
+	}
+
+	link := &depInitiatorLink{d: d, sb: mode == Passive && baud == Nbr106, timeout: ms}
+	s := newDEPSession(link, true, remote.DID, remote.PP)
+	s.Mode = mode
+	s.Baud = baud
+	s.Local = local
+	s.Remote = *remote
+
+	return s, nil
+}
+
+// Configure d as DEP target t and wait for an initiator to establish a
+// session. The general bytes of t are sent in ATR_RES. Returns the session
+// and the first message of the initiator, which is to be answered with
+// Exchange(). Like DEPConnect(), this function disables EasyFraming on d.
+// timeout applies to TargetInit() and to each frame exchanged in the session.
+func DEPListen(d TargetDevice, t *DEPTarget, timeout time.Duration) (*DEPSession, []byte, error) {
+	ms, err := timeoutMillis(timeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rx := make([]byte, depMaxFrame+1)
+	n, tt, err := d.TargetInit(t, rx, ms)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// TargetInit() turns EasyFraming on, which makes the device strip the
+	// DEP header from the frames received
+	if err = d.SetPropertyBool(EasyFraming, false); err != nil {
+		return nil, nil, err
+	}
+
+	remote, err := parseDEPAtrReq(rx[:n])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	local, ok := tt.(*DEPTarget)
+	if !ok {
+		local = t
+	}
+
+	s := newDEPSession(&depTargetLink{d: d, timeout: ms}, false, remote.DID, remote.PP)
+	s.Mode = local.DepMode
+	s.Baud = local.Baud
+	s.Local = *local
+	s.Remote = remote
+
+	req, err := s.link.transceive(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	msg, err := s.receive(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return s, msg, nil
+}
+
+// Build a DEP frame with pfb and payload data.
+func (s *DEPSession) frame(cmd, pfb byte, data []byte) []byte {
+	cmd0 := byte(DEPReq)
+	if !s.initiator {
+		cmd0 = DEPRes
+		cmd++
+	}
+
+	f := make([]byte, 0, 4+len(data))
+	f = append(f, cmd0, cmd)
+	if cmd == DEPDepReq || cmd == DEPDepReq+1 {
+		if s.did != 0 {
+			pfb |= depPFBDID
+		}
+
+		f = append(f, pfb)
+	}
+
+	if s.did != 0 {
+		f = append(f, s.did)
+	}
+
+	return append(f, data...)
+}
+
+// Parse a DEP_REQ (target) or DEP_RES (initiator) frame into its PFB and
+// payload.
+func (s *DEPSession) parse(f []byte) (pfb byte, data []byte, err error) {
+	want := [2]byte{DEPRes, DEPDepReq + 1}
+	if !s.initiator {
+		want = [2]byte{DEPReq, DEPDepReq}
+	}
+
+	if len(f) < 3 || f[0] != want[0] || f[1] != want[1] {
+		err = fmt.Errorf("DEP: unexpected frame % x", f)
+		return
+	}
+
+	pfb = f[2]
+	data = f[3:]
+	if pfb&depPFBDID != 0 {
+		if len(data) < 1 || data[0] != s.did {
+			err = errors.New("DEP: DID mismatch")
+			return
+		}
+
+		data = data[1:]
+	}
+
+	if pfb&depPFBNAD != 0 {
+		if len(data) < 1 {
+			err = errors.New("DEP: malformed frame")
+			return
+		}
+
+		data = data[1:]
+	}
+
+	return
+}
+
+// Split msg into chunks no longer than the MTU. An empty message yields a
+// single empty chunk.
+func (s *DEPSession) split(msg []byte) [][]byte {
+	chunks := [][]byte{}
+	for len(msg) > s.mtu {
+		chunks = append(chunks, msg[:s.mtu])
+		msg = msg[s.mtu:]
+	}
+
+	return append(chunks, msg)
+}
+
+// Send a DEP_REQ with pfb and data and return the DEP_RES, answering
+// timeout extension requests of the target. Only for the initiator.
+func (s *DEPSession) request(pfb byte, data []byte) (byte, []byte, error) {
+	s.pni = pfb & depPFBPNI
+
+	tx := s.frame(DEPDepReq, pfb, data)
+	for {
+		rx, err := s.link.transceive(tx)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		rpfb, rdata, err := s.parse(rx)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// RTOX: echo the request to grant the extension
+		if rpfb&depPFBType == depPFBSuper && rpfb&depPFBMI != 0 {
+			tx = s.frame(DEPDepReq, rpfb&^depPFBDID, rdata)
+			continue
+		}
+
+		if rpfb&depPFBType == depPFBSuper || rpfb&depPFBPNI != s.pni {
+			return 0, nil, fmt.Errorf("DEP: unexpected PFB %#02x", rpfb)
+		}
+
+		return rpfb, rdata, nil
+	}
+}
+
+// Return the next PNI.
+func (s *DEPSession) nextPNI() byte {
+	return (s.pni + 1) & depPFBPNI
+}
+
+// Exchange a message with the peer, using chaining if it exceeds the frame
+// size. On the initiator, msg is sent to the target and the answer is
+// returned. On the target, msg is sent as the answer to the last message
+// and the next message of the initiator is returned. If the initiator
+// releases or deselects the target, ETGRELEASED is returned.
+func (s *DEPSession) Exchange(msg []byte) ([]byte, error) {
+	if s.initiator {
+		return s.initiatorExchange(msg)
+	}
+
+	return s.targetExchange(msg)
+}
+
+func (s *DEPSession) initiatorExchange(msg []byte) ([]byte, error) {
+	chunks := s.split(msg)
+	for i, c := range chunks {
+		pfb := depPFBInfo | s.nextPNI()
+		if i < len(chunks)-1 {
+			pfb |= depPFBMI
+		}
+
+		rpfb, data, err := s.request(pfb, c)
+		if err != nil {
+			return nil, err
+		}
+
+		if i < len(chunks)-1 {
+			if rpfb&depPFBType != depPFBAck || rpfb&depPFBMI != 0 {
+				return nil, fmt.Errorf("DEP: expected ACK, got PFB %#02x", rpfb)
+			}
+
+			continue
+		}
+
+		// collect the answer, acknowledging each chained frame
+		var res []byte
+		for {
+			if rpfb&depPFBType != depPFBInfo {
+				return nil, fmt.Errorf("DEP: expected information PDU, got PFB %#02x", rpfb)
+			}
+
+			res = append(res, data...)
+			if rpfb&depPFBMI == 0 {
+				return res, nil
+			}
+
+			rpfb, data, err = s.request(depPFBAck|s.nextPNI(), nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	panic("unreachable")
+}
+
+// Receive a possibly chained message starting with frame req. Only for the
+// target.
+func (s *DEPSession) receive(req []byte) ([]byte, error) {
+	var msg []byte
+	for {
+		if len(req) >= 2 && req[0] == DEPReq && (req[1] == DEPRlsReq || req[1] == DEPDslReq) {
+			// acknowledge and end the session
+			if err := s.link.send(s.frame(req[1], 0, nil)); err != nil {
+				return nil, err
+			}
+
+			return nil, Error(ETGRELEASED)
+		}
+
+		pfb, data, err := s.parse(req)
+		if err != nil {
+			return nil, err
+		}
+
+		var res []byte
+		switch {
+		case pfb&depPFBType == depPFBSuper && pfb&depPFBMI == 0:
+			// ATN: answer with ATN
+			res = s.frame(DEPDepReq, depPFBSuper, nil)
+
+		case pfb&depPFBType != depPFBInfo || pfb&depPFBPNI != s.nextPNI():
+			return nil, fmt.Errorf("DEP: unexpected PFB %#02x", pfb)
+
+		default:
+			s.pni = pfb & depPFBPNI
+			msg = append(msg, data...)
+			if pfb&depPFBMI == 0 {
+				return msg, nil
+			}
+
+			res = s.frame(DEPDepReq, depPFBAck|s.pni, nil)
+		}
+
+		req, err = s.link.transceive(res)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *DEPSession) targetExchange(msg []byte) ([]byte, error) {
+	chunks := s.split(msg)
+	for i, c := range chunks {
+		pfb := depPFBInfo | s.pni
+		if i < len(chunks)-1 {
+			pfb |= depPFBMI
+		}
+
+		tx := s.frame(DEPDepReq, pfb, c)
+		req, err := s.link.transceive(tx)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == len(chunks)-1 {
+			return s.receive(req)
+		}
+
+		// the initiator acknowledges each chained frame with the next
+		// PNI; a NACK asks for retransmission
+		for {
+			rpfb, _, err := s.parse(req)
+			if err != nil {
+				return nil, err
+			}
+
+			if rpfb&depPFBType == depPFBAck && rpfb&depPFBMI != 0 && rpfb&depPFBPNI == s.pni {
+				if req, err = s.link.transceive(tx); err != nil {
+					return nil, err
+				}
+
+				continue
+			}
+
+			if rpfb&depPFBType != depPFBAck || rpfb&depPFBMI != 0 || rpfb&depPFBPNI != s.nextPNI() {
+				return nil, fmt.Errorf("DEP: expected ACK, got PFB %#02x", rpfb)
+			}
+
+			s.pni = rpfb & depPFBPNI
+			break
+		}
+	}
+
+	panic("unreachable")
+}
+
+// Release the session. The initiator sends RLS_REQ and waits for RLS_RES;
+// on the target, Close does nothing.
+func (s *DEPSession) Close() error {
+	if !s.initiator {
+		return nil
+	}
+
+	rx, err := s.link.transceive(s.frame(DEPRlsReq, 0, nil))
+	if err != nil {
+		return err
+	}
+
+	if len(rx) < 2 || rx[0] != DEPRes || rx[1] != DEPRlsReq+1 {
+		return errors.New("DEP: malformed RLS_RES")
+	}
+
+	return nil
+}
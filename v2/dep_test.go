@@ -0,0 +1,214 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "testing"
+
+// One end of a simulated NFC-DEP link. Frames travel through channels.
+type depPipeEnd struct {
+	in, out   chan []byte
+	initiator bool
+}
+
+func (p *depPipeEnd) send(tx []byte) error {
+	p.out <- append([]byte(nil), tx...)
+	return nil
+}
+
+func (p *depPipeEnd) transceive(tx []byte) ([]byte, error) {
+	if tx != nil || p.initiator {
+		p.send(tx)
+	}
+
+	rx, ok := <-p.in
+	if !ok {
+		return nil, Error(ETGRELEASED)
+	}
+
+	return rx, nil
+}
+
+// Make a pair of DEP sessions connected back to back. pp is the PP byte
+// both sides advertise.
+func depPipe(pp byte) (ini, tgt *DEPSession) {
+	a, b := make(chan []byte, 1), make(chan []byte, 1)
+	ini = newDEPSession(&depPipeEnd{in: b, out: a, initiator: true}, true, 0, pp)
+	tgt = newDEPSession(&depPipeEnd{in: a, out: b}, false, 0, pp)
+	return
+}
+
+// Verify that messages longer than a frame are chained in both directions
+// and that releasing the session is reported to the target.
+func TestDEPChaining(t *testing.T) {
+	ini, tgt := depPipe(0x00)
+
+	req := bytes.Repeat([]byte("request "), 100)
+	res := bytes.Repeat([]byte("response "), 50)
+
+	done := make(chan error)
+	go func() {
+		frame, err := tgt.link.transceive(nil)
+		if err != nil {
+			done <- err
+			return
+		}
+
+		msg, err := tgt.receive(frame)
+		if err != nil {
+			done <- err
+			return
+		}
+
+		if !bytes.Equal(msg, req) {
+			t.Errorf("target received %q, want %q", msg, req)
+		}
+
+		_, err = tgt.Exchange(res)
+		done <- err
+	}()
+
+	msg, err := ini.Exchange(req)
+	if err != nil {
+		t.Fatal("Exchange():", err)
+	}
+
+	if !bytes.Equal(msg, res) {
+		t.Errorf("initiator received %q, want %q", msg, res)
+	}
+
+	if err = ini.Close(); err != nil {
+		t.Error("Close():", err)
+	}
+
+	if err := <-done; err != Error(ETGRELEASED) {
+		t.Errorf("target: got error %v, want %v", err, Error(ETGRELEASED))
+	}
+}
+
+// A DEP initiator answering each DEP_REQ with a DEP_RES carrying res. Like
+// a PN53x, it strips the DEP header unless EasyFraming is disabled.
+type depFakeInitiator struct {
+	easyFraming bool
+	res         []byte
+}
+
+func (f *depFakeInitiator) InitiatorSelectDEPTarget(mode, baud int, initiator *DEPTarget, timeout int) (*DEPTarget, error) {
+	return &DEPTarget{DepMode: mode, Baud: baud, PP: 0x30}, nil
+}
+
+func (f *depFakeInitiator) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (int, error) {
+	if f.easyFraming {
+		return copy(rx, f.res), nil
+	}
+
+	if len(tx) < 4 || int(tx[0]) != len(tx) || tx[1] != DEPReq || tx[2] != DEPDepReq {
+		return 0, Error(ERFTRANS)
+	}
+
+	frame := append([]byte{byte(len(f.res) + 4), DEPRes, DEPDepReq + 1, tx[3]}, f.res...)
+	return copy(rx, frame), nil
+}
+
+func (f *depFakeInitiator) SetPropertyBool(property int, value bool) error {
+	if property == EasyFraming {
+		f.easyFraming = value
+	}
+
+	return nil
+}
+
+// A DEP target receiving an ATR_REQ and then the frames in req. Like a
+// PN53x, TargetInit() enables EasyFraming, which strips the DEP header.
+type depFakeTarget struct {
+	easyFraming bool
+	req         [][]byte
+}
+
+func (f *depFakeTarget) TargetInit(t Target, rx []byte, timeout int) (int, Target, error) {
+	f.easyFraming = true
+	atr := []byte{17, DEPReq, DEPAtrReq, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 0, 0, 0, 0x30}
+	return copy(rx, atr), t, nil
+}
+
+func (f *depFakeTarget) TargetSendBytes(tx []byte, timeout int) (int, error) {
+	return len(tx), nil
+}
+
+func (f *depFakeTarget) TargetReceiveBytes(rx []byte, timeout int) (int, error) {
+	if len(f.req) == 0 {
+		return 0, Error(ETGRELEASED)
+	}
+
+	req := f.req[0]
+	f.req = f.req[1:]
+	if f.easyFraming {
+		req = req[4:]
+	}
+
+	return copy(rx, req), nil
+}
+
+func (f *depFakeTarget) TargetSendBits(tx []byte, txPar []byte, txLength uint) (int, error) {
+	return 0, Error(EDEVNOTSUPP)
+}
+
+func (f *depFakeTarget) TargetTransceiveBits(rx []byte, rxPar []byte, rxLength uint) (int, error) {
+	return 0, Error(EDEVNOTSUPP)
+}
+
+func (f *depFakeTarget) SetPropertyBool(property int, value bool) error {
+	if property == EasyFraming {
+		f.easyFraming = value
+	}
+
+	return nil
+}
+
+// DEPConnect() and DEPListen() must disable EasyFraming as they build the
+// DEP frames themselves.
+func TestDEPEasyFraming(t *testing.T) {
+	ini := &depFakeInitiator{easyFraming: true, res: []byte("pong")}
+	s, err := DEPConnect(ini, Active, Nbr424, nil, 0)
+	if err != nil {
+		t.Fatal("DEPConnect():", err)
+	}
+
+	if ini.easyFraming {
+		t.Error("DEPConnect() left EasyFraming enabled")
+	}
+
+	if msg, err := s.Exchange([]byte("ping")); err != nil || string(msg) != "pong" {
+		t.Errorf("Exchange() = %q, %v, want %q", msg, err, "pong")
+	}
+
+	tgt := &depFakeTarget{req: [][]byte{{8, DEPReq, DEPDepReq, 0x00, 'p', 'i', 'n', 'g'}}}
+	s, msg, err := DEPListen(tgt, &DEPTarget{DepMode: Active, Baud: Nbr424}, 0)
+	if err != nil {
+		t.Fatal("DEPListen():", err)
+	}
+
+	if tgt.easyFraming {
+		t.Error("DEPListen() left EasyFraming enabled")
+	}
+
+	if string(msg) != "ping" {
+		t.Errorf("DEPListen() received %q, want %q", msg, "ping")
+	}
+
+	if s.Remote.NFCID3 != [10]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10} {
+		t.Errorf("DEPListen(): unexpected NFCID3 % x", s.Remote.NFCID3)
+	}
+}
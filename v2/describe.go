@@ -0,0 +1,681 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "fmt"
+import "strconv"
+
+// This file contains the Describe() methods of the targets. They produce
+// the same output as str_nfc_target() from libnfc's target-subr.c, but
+// without going through C.
+
+// Names of the modulation types as printed by str_nfc_modulation_type().
+var describeModulationTypes = [...]string{
+	ISO14443a:       "ISO/IEC 14443A",
+	Jewel:           "Innovision Jewel",
+	ISO14443b:       "ISO/IEC 14443-4B",
+	ISO14443bi:      "ISO/IEC 14443-4B'",
+	ISO14443b2sr:    "ISO/IEC 14443-2B ST SRx",
+	ISO14443b2ct:    "ISO/IEC 14443-2B ASK CTx",
+	Felica:          "FeliCa",
+	DEP:             "D.E.P.",
+	Barcode:         "Thinfilm NFC Barcode",
+	ISO14443biClass: "ISO/IEC 14443-2B-3B iClass (Picopass)",
+}
+
+// Names of the baud rates as printed by str_nfc_baud_rate().
+var describeBaudRates = [...]string{
+	Undefined: "undefined baud rate",
+	Nbr106:    "106 kbps",
+	Nbr212:    "212 kbps",
+	Nbr424:    "424 kbps",
+	Nbr847:    "847 kbps",
+}
+
+// Write the first line of a description.
+func describeHeader(b *bytes.Buffer, m Modulation, mode string) {
+	nmt, nbr := "???", "???"
+	if 0 < m.Type && m.Type < len(describeModulationTypes) {
+		nmt = describeModulationTypes[m.Type]
+	}
+
+	if 0 <= m.BaudRate && m.BaudRate < len(describeBaudRates) {
+		nbr = describeBaudRates[m.BaudRate]
+	}
+
+	fmt.Fprintf(b, "%s (%s%s) target:\n", nmt, nbr, mode)
+}
+
+// Write data in hex like snprint_hex() does.
+func describeHex(b *bytes.Buffer, data []byte) {
+	for _, x := range data {
+		fmt.Fprintf(b, "%02x  ", x)
+	}
+
+	b.WriteByte('\n')
+}
+
+// Format a frame waiting time or start-up frame guard time with the given
+// exponent like libnfc does.
+func describeFWT(exp uint) string {
+	return strconv.FormatFloat(256.0*16.0*float64(uint(1)<<exp)/13560.0, 'g', 4, 64)
+}
+
+// Maximum frame sizes by FSCI / FSDI.
+var describeFrameSizes = [...]int{16, 24, 32, 40, 48, 64, 96, 128, 256}
+
+// ATQA / SAK combinations from NXP AN10833 (MIFARE type identification
+// procedure) as used by libnfc.
+var describeATQAs = []struct {
+	atqa, mask uint16
+	name       string
+	saks       []int // indices into describeSAKs
+}{
+	{0x0044, 0xffff, "MIFARE Ultralight", []int{0}},
+	{0x0044, 0xffff, "MIFARE Ultralight C", []int{0}},
+	{0x0004, 0xff0f, "MIFARE Mini 0.3K", []int{1}},
+	{0x0004, 0xff0f, "MIFARE Classic 1K", []int{2}},
+	{0x0002, 0xff0f, "MIFARE Classic 4K", []int{3}},
+	{0x0004, 0xffff, "MIFARE Plus (4 Byte UID or 4 Byte RID)", []int{4, 5, 6, 7, 8, 9}},
+	{0x0002, 0xffff, "MIFARE Plus (4 Byte UID or 4 Byte RID)", []int{4, 5, 6, 7, 8, 9}},
+	{0x0044, 0xffff, "MIFARE Plus (7 Byte UID)", []int{4, 5, 6, 7, 8, 9}},
+	{0x0042, 0xffff, "MIFARE Plus (7 Byte UID)", []int{4, 5, 6, 7, 8, 9}},
+	{0x0344, 0xffff, "MIFARE DESFire", []int{10, 11}},
+	{0x0044, 0xffff, "P3SR008", nil},
+	{0x0004, 0xf0ff, "SmartMX with MIFARE 1K emulation", []int{12}},
+	{0x0002, 0xf0ff, "SmartMX with MIFARE 4K emulation", []int{12}},
+	{0x0048, 0xf0ff, "SmartMX with 7 Byte UID", []int{12}},
+}
+
+var describeSAKs = []struct {
+	sak, mask byte
+	name      string
+}{
+	{0x00, 0xff, ""},                      // MIFARE Ultralight / Ultralight C
+	{0x09, 0xff, ""},                      // MIFARE Mini
+	{0x08, 0xff, ""},                      // MIFARE Classic 1K
+	{0x18, 0xff, ""},                      // MIFARE Classic 4K
+	{0x08, 0xff, " 2K, Security level 1"}, // MIFARE Plus
+	{0x18, 0xff, " 4K, Security level 1"}, // MIFARE Plus
+	{0x10, 0xff, " 2K, Security level 2"}, // MIFARE Plus
+	{0x11, 0xff, " 4K, Security level 2"}, // MIFARE Plus
+	{0x20, 0xff, " 2K, Security level 3"}, // MIFARE Plus
+	{0x20, 0xff, " 4K, Security level 3"}, // MIFARE Plus
+	{0x20, 0xff, " 4K"},                   // MIFARE DESFire
+	{0x20, 0xff, " EV1 2K/4K/8K"},         // MIFARE DESFire
+	{0x00, 0x00, ""},                      // SmartMX
+}
+
+// Other ATQA / SAK combinations seen in the field.
+var describeATQASAKs = map[uint32][]string{
+	0x000488: {"Mifare Classic 1K Infineon"},
+	0x000298: {"Gemplus MPCOS"},
+	0x030428: {"JCOP31"},
+	0x004820: {"JCOP31 v2.4.1", "JCOP31 v2.2"},
+	0x000428: {"JCOP31 v2.3.1"},
+	0x000453: {"Fudan FM1208SH01"},
+	0x000820: {"Fudan FM1208"},
+	0x000238: {"MFC 4K emulated by Nokia 6212 Classic"},
+	0x000838: {"MFC 4K emulated by Nokia 6131 NFC"},
+}
+
+// Describe the target like str_nfc_target() does. If verbose is set,
+// additional information decoded from the target data is included.
+func (t *ISO14443aTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	describeHeader(&b, t.Modulation(), "")
+	b.WriteString("    ATQA (SENS_RES): ")
+	describeHex(&b, t.Atqa[:])
+	if verbose {
+		b.WriteString("* UID size: ")
+		b.WriteString([...]string{"single\n", "double\n", "triple\n", "RFU\n"}[t.Atqa[1]>>6])
+		b.WriteString("* bit frame anticollision ")
+		switch t.Atqa[1] & 0x1f {
+		case 0x01, 0x02, 0x04, 0x08, 0x10:
+			b.WriteString("supported\n")
+		default:
+			b.WriteString("not supported\n")
+		}
+	}
+
+	nfcid := '1'
+	if t.UID[0] == 0x08 {
+		nfcid = '3'
+	}
+
+	fmt.Fprintf(&b, "       UID (NFCID%c): ", nfcid)
+	describeHex(&b, t.UID[:clampLen(t.UIDLen, len(t.UID))])
+	if verbose && t.UID[0] == 0x08 {
+		b.WriteString("* Random UID\n")
+	}
+
+	b.WriteString("      SAK (SEL_RES): ")
+	describeHex(&b, []byte{t.Sak})
+	if verbose {
+		if t.Sak&0x04 != 0 {
+			b.WriteString("* Warning! Cascade bit set: UID not complete\n")
+		}
+
+		if t.Sak&0x20 != 0 {
+			b.WriteString("* Compliant with ISO/IEC 14443-4\n")
+		} else {
+			b.WriteString("* Not compliant with ISO/IEC 14443-4\n")
+		}
+
+		if t.Sak&0x40 != 0 {
+			b.WriteString("* Compliant with ISO/IEC 18092\n")
+		} else {
+			b.WriteString("* Not compliant with ISO/IEC 18092\n")
+		}
+	}
+
+	atsLen := clampLen(t.AtsLen, len(t.Ats))
+	if atsLen > 0 {
+		b.WriteString("                ATS: ")
+		describeHex(&b, t.Ats[:atsLen])
+		if verbose {
+			describeATS(&b, t.Ats[:], atsLen)
+		}
+	}
+
+	if verbose {
+		describeFingerprint(&b, t.Atqa, t.Sak)
+	}
+
+	return b.String()
+}
+
+// Clamp a length field to [0, max].
+func clampLen(n, limit int) int {
+	if n < 0 {
+		return 0
+	} else if n > limit {
+		return limit
+	}
+
+	return n
+}
+
+// Decode an ATS of length n stored in ats according to ISO/IEC 14443-4
+// (5.2 Answer to select). ats is the whole Ats array of the target, reads
+// past n see its padding just like they do in libnfc.
+func describeATS(b *bytes.Buffer, ats []byte, n int) {
+	at := func(i int) byte {
+		if i < len(ats) {
+			return ats[i]
+		}
+
+		return 0
+	}
+
+	fsci := int(ats[0] & 0x0f)
+	if fsci < len(describeFrameSizes) {
+		fmt.Fprintf(b, "* Max Frame Size accepted by PICC: %d bytes\n", describeFrameSizes[fsci])
+	} else {
+		b.WriteString("* Max Frame Size accepted by PICC: RFU\n")
+	}
+
+	offset := 1
+	if ats[0]&0x10 != 0 { // TA(1) present
+		ta := at(offset)
+		offset++
+		b.WriteString("* Bit Rate Capability:\n")
+		if ta == 0 {
+			b.WriteString("  * PICC supports only 106 kbits/s in both directions\n")
+		}
+
+		if ta&(1<<7) != 0 {
+			b.WriteString("  * Same bitrate in both directions mandatory\n")
+		}
+
+		if ta&(1<<4) != 0 {
+			b.WriteString("  * PICC to PCD, DS=2, bitrate 212 kbits/s supported\n")
+		}
+
+		if ta&(1<<5) != 0 {
+			b.WriteString("  * PICC to PCD, DS=4, bitrate 424 kbits/s supported\n")
+		}
+
+		if ta&(1<<6) != 0 {
+			b.WriteString("  * PICC to PCD, DS=8, bitrate 847 kbits/s supported\n")
+		}
+
+		if ta&(1<<0) != 0 {
+			b.WriteString("  * PCD to PICC, DR=2, bitrate 212 kbits/s supported\n")
+		}
+
+		if ta&(1<<1) != 0 {
+			b.WriteString("  * PCD to PICC, DR=4, bitrate 424 kbits/s supported\n")
+		}
+
+		if ta&(1<<2) != 0 {
+			b.WriteString("  * PCD to PICC, DR=8, bitrate 847 kbits/s supported\n")
+		}
+
+		if ta&(1<<3) != 0 {
+			b.WriteString("  * ERROR unknown value\n")
+		}
+	}
+
+	if ats[0]&0x20 != 0 { // TB(1) present
+		tb := at(offset)
+		offset++
+		fmt.Fprintf(b, "* Frame Waiting Time: %s ms\n", describeFWT(uint(tb>>4)))
+		if tb&0x0f == 0 {
+			b.WriteString("* No Start-up Frame Guard Time required\n")
+		} else {
+			fmt.Fprintf(b, "* Start-up Frame Guard Time: %s ms\n", describeFWT(uint(tb&0x0f)))
+		}
+	}
+
+	if ats[0]&0x40 != 0 { // TC(1) present
+		tc := at(offset)
+		offset++
+		if tc&0x01 != 0 {
+			b.WriteString("* Node Address supported\n")
+		} else {
+			b.WriteString("* Node Address not supported\n")
+		}
+
+		if tc&0x02 != 0 {
+			b.WriteString("* Card IDentifier supported\n")
+		} else {
+			b.WriteString("* Card IDentifier not supported\n")
+		}
+	}
+
+	if n <= offset {
+		return
+	}
+
+	b.WriteString("* Historical bytes Tk: ")
+	describeHex(b, ats[offset:n])
+	cib := at(offset)
+	offset++
+	switch {
+	case cib == 0x00:
+		b.WriteString("  * Tk after 0x00 consist of optional consecutive COMPACT-TLV data objects\n")
+		b.WriteString("    followed by a mandatory status indicator (the last three bytes, not in TLV)\n")
+		b.WriteString("    See ISO/IEC 7816-4 8.1.1.3 for more info\n")
+	case cib == 0x10:
+		fmt.Fprintf(b, "  * DIR data reference: %02x\n", at(offset))
+	case cib == 0x80:
+		if n == offset {
+			b.WriteString("  * No COMPACT-TLV objects found, no status found\n")
+		} else {
+			b.WriteString("  * Tk after 0x80 consist of optional consecutive COMPACT-TLV data objects;\n")
+			b.WriteString("    the last data object may carry a status indicator of one, two or three bytes.\n")
+			b.WriteString("    See ISO/IEC 7816-4 8.1.1.3 for more info\n")
+		}
+	case cib&0xf0 == 0x80:
+		// reserved for ISO/IEC 7816-4, nothing to print
+	default:
+		b.WriteString("  * Proprietary format\n")
+		if cib == 0xc1 {
+			describeTypeIdentification(b, at, n, offset)
+		}
+	}
+}
+
+// Decode the proprietary MIFARE type identification coding in the
+// historical bytes starting at offset. libnfc does this computation with
+// unsigned lengths, so do we.
+func describeTypeIdentification(b *bytes.Buffer, at func(int) byte, n, offset int) {
+	b.WriteString("    * Tag byte: Mifare or virtual cards of various types\n")
+	l := at(offset)
+	offset++
+	if int(l) != n-offset {
+		fmt.Fprintf(b, "    * Warning: Type Identification Coding length (%d)", l)
+		fmt.Fprintf(b, " not matching Tk length (%d)\n", n-offset)
+	}
+
+	if uint(n)-uint(offset)-2 > 0 { // omit 2 CRC bytes
+		ctc := at(offset)
+		offset++
+		b.WriteString("    * Chip Type: ")
+		switch ctc & 0xf0 {
+		case 0x00:
+			b.WriteString("(Multiple) Virtual Cards\n")
+		case 0x10:
+			b.WriteString("Mifare DESFire\n")
+		case 0x20:
+			b.WriteString("Mifare Plus\n")
+		default:
+			b.WriteString("RFU\n")
+		}
+
+		b.WriteString("    * Memory size: ")
+		switch ctc & 0x0f {
+		case 0x00:
+			b.WriteString("<1 kbyte\n")
+		case 0x01:
+			b.WriteString("1 kbyte\n")
+		case 0x02:
+			b.WriteString("2 kbyte\n")
+		case 0x03:
+			b.WriteString("4 kbyte\n")
+		case 0x04:
+			b.WriteString("8 kbyte\n")
+		case 0x0f:
+			b.WriteString("Unspecified\n")
+		default:
+			b.WriteString("RFU\n")
+		}
+	}
+
+	if uint(n)-uint(offset) > 0 {
+		cvc := at(offset)
+		offset++
+		b.WriteString("    * Chip Status: ")
+		switch cvc & 0xf0 {
+		case 0x00:
+			b.WriteString("Engineering sample\n")
+		case 0x20:
+			b.WriteString("Released\n")
+		default:
+			b.WriteString("RFU\n")
+		}
+
+		b.WriteString("    * Chip Generation: ")
+		switch cvc & 0x0f {
+		case 0x00:
+			b.WriteString("Generation 1\n")
+		case 0x01:
+			b.WriteString("Generation 2\n")
+		case 0x02:
+			b.WriteString("Generation 3\n")
+		case 0x0f:
+			b.WriteString("Unspecified\n")
+		default:
+			b.WriteString("RFU\n")
+		}
+	}
+
+	if uint(n)-uint(offset) > 0 {
+		vcs := at(offset)
+		b.WriteString("    * Specifics (Virtual Card Selection):\n")
+		if vcs&0x09 == 0x00 {
+			b.WriteString("      * Only VCSL supported\n")
+		} else if vcs&0x09 == 0x01 {
+			b.WriteString("      * VCS, VCSL and SVC supported\n")
+		}
+
+		switch {
+		case vcs&0x0e == 0x00:
+			b.WriteString("      * SL1, SL2(?), SL3 supported\n")
+		case vcs&0x0e == 0x02:
+			b.WriteString("      * SL3 only card\n")
+		case vcs&0x0f == 0x0e:
+			b.WriteString("      * No VCS command supported\n")
+		case vcs&0x0f == 0x0f:
+			b.WriteString("      * Unspecified\n")
+		default:
+			b.WriteString("      * RFU\n")
+		}
+	}
+}
+
+// Guess the type of an ISO14443A card from ATQA and SAK.
+func describeFingerprint(b *bytes.Buffer, atqaBytes [2]byte, sak byte) {
+	found := false
+
+	b.WriteString("\nFingerprinting based on MIFARE type Identification Procedure:\n")
+	atqa := uint16(atqaBytes[0])<<8 | uint16(atqaBytes[1])
+	for _, ca := range describeATQAs {
+		if atqa&ca.mask != ca.atqa {
+			continue
+		}
+
+		for _, i := range ca.saks {
+			cs := describeSAKs[i]
+			if sak&cs.mask == cs.sak {
+				fmt.Fprintf(b, "* %s%s\n", ca.name, cs.name)
+				found = true
+			}
+		}
+	}
+
+	b.WriteString("Other possible matches based on ATQA & SAK values:\n")
+	atqasak := uint32(atqa)<<8 | uint32(sak)
+	for _, name := range describeATQASAKs[atqasak] {
+		fmt.Fprintf(b, "* %s\n", name)
+		found = true
+	}
+
+	if !found {
+		b.WriteString("* Unknown card, sorry\n")
+	}
+}
+
+// Describe the target like str_nfc_target() does. verbose has no effect.
+func (t *JewelTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	describeHeader(&b, t.Modulation(), "")
+	b.WriteString("    ATQA (SENS_RES): ")
+	describeHex(&b, t.SensRes[:])
+	b.WriteString("      4-LSB JEWELID: ")
+	describeHex(&b, t.ID[:])
+
+	return b.String()
+}
+
+// Describe the target like str_nfc_target() does. verbose has no effect.
+func (t *BarcodeTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	n := clampLen(t.DataLen, len(t.Data))
+	describeHeader(&b, t.Modulation(), "")
+	fmt.Fprintf(&b, "        Size (bits): %d\n", 8*n)
+	b.WriteString("            Content: ")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, "%02X", t.Data[i])
+		if i%8 == 7 && i < n-1 {
+			b.WriteString("\n                     ")
+		}
+	}
+
+	b.WriteByte('\n')
+
+	return b.String()
+}
+
+// Describe the target like str_nfc_target() does. verbose has no effect.
+func (t *FelicaTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	describeHeader(&b, t.Modulation(), "")
+	b.WriteString("        ID (NFCID2): ")
+	describeHex(&b, t.ID[:])
+	b.WriteString("    Parameter (PAD): ")
+	describeHex(&b, t.Pad[:])
+	b.WriteString("   System Code (SC): ")
+	describeHex(&b, t.SysCode[:])
+
+	return b.String()
+}
+
+// Describe the target like str_nfc_target() does. If verbose is set, the
+// protocol info is decoded.
+func (t *ISO14443bTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	describeHeader(&b, t.Modulation(), "")
+	b.WriteString("               PUPI: ")
+	describeHex(&b, t.Pupi[:])
+	b.WriteString("   Application Data: ")
+	describeHex(&b, t.ApplicationData[:])
+	b.WriteString("      Protocol Info: ")
+	describeHex(&b, t.ProtocolInfo[:])
+	if !verbose {
+		return b.String()
+	}
+
+	pi := t.ProtocolInfo
+	b.WriteString("* Bit Rate Capability:\n")
+	if pi[0] == 0 {
+		b.WriteString(" * PICC supports only 106 kbits/s in both directions\n")
+	}
+
+	if pi[0]&(1<<7) != 0 {
+		b.WriteString(" * Same bitrate in both directions mandatory\n")
+	}
+
+	if pi[0]&(1<<4) != 0 {
+		b.WriteString(" * PICC to PCD, 1etu=64/fc, bitrate 212 kbits/s supported\n")
+	}
+
+	if pi[0]&(1<<5) != 0 {
+		b.WriteString(" * PICC to PCD, 1etu=32/fc, bitrate 424 kbits/s supported\n")
+	}
+
+	if pi[0]&(1<<6) != 0 {
+		b.WriteString(" * PICC to PCD, 1etu=16/fc, bitrate 847 kbits/s supported\n")
+	}
+
+	if pi[0]&(1<<0) != 0 {
+		b.WriteString(" * PCD to PICC, 1etu=64/fc, bitrate 212 kbits/s supported\n")
+	}
+
+	if pi[0]&(1<<1) != 0 {
+		b.WriteString(" * PCD to PICC, 1etu=32/fc, bitrate 424 kbits/s supported\n")
+	}
+
+	if pi[0]&(1<<2) != 0 {
+		b.WriteString(" * PCD to PICC, 1etu=16/fc, bitrate 847 kbits/s supported\n")
+	}
+
+	if pi[0]&(1<<3) != 0 {
+		b.WriteString(" * ERROR unknown value\n")
+	}
+
+	if pi[1]&0xf0 <= 0x80 {
+		fmt.Fprintf(&b, "* Maximum frame sizes: %d bytes\n", describeFrameSizes[pi[1]>>4])
+	}
+
+	if pi[1]&0x01 != 0 {
+		b.WriteString("* Protocol types supported: ISO/IEC 14443-4\n")
+	}
+
+	fmt.Fprintf(&b, "* Frame Waiting Time: %s ms\n", describeFWT(uint(pi[2]>>4)))
+	if pi[2]&0x03 != 0 {
+		b.WriteString("* Frame options supported: ")
+		if pi[2]&0x01 != 0 {
+			b.WriteString("NAD ")
+		}
+
+		if pi[2]&0x02 != 0 {
+			b.WriteString("CID ")
+		}
+
+		b.WriteByte('\n')
+	}
+
+	return b.String()
+}
+
+// Describe the target like str_nfc_target() does. If verbose is set, the
+// software version is decoded.
+func (t *ISO14443biTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	describeHeader(&b, t.Modulation(), "")
+	b.WriteString("                DIV: ")
+	describeHex(&b, t.DIV[:])
+	if verbose {
+		version := (t.VerLog & 0x1e) >> 1
+		b.WriteString("   Software Version: ")
+		if version == 15 {
+			b.WriteString("Undefined\n")
+		} else {
+			fmt.Fprintf(&b, "%d\n", version)
+		}
+
+		// libnfc omits the newline here
+		if t.VerLog&0x80 != 0 && t.Config&0x80 != 0 {
+			b.WriteString("        Wait Enable: yes")
+		}
+	}
+
+	if t.VerLog&0x80 != 0 && t.Config&0x40 != 0 {
+		b.WriteString("                ATS: ")
+		describeHex(&b, t.Atr[:clampLen(t.AtrLen, len(t.Atr))])
+	}
+
+	return b.String()
+}
+
+// Describe the target like str_nfc_target() does. verbose has no effect.
+func (t *ISO14443b2srTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	describeHeader(&b, t.Modulation(), "")
+	b.WriteString("                UID: ")
+	describeHex(&b, t.UID[:])
+
+	return b.String()
+}
+
+// Describe the target like str_nfc_target() does. verbose has no effect.
+func (t *ISO14443b2ctTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	uid := uint32(t.UID[3])<<24 | uint32(t.UID[2])<<16 | uint32(t.UID[1])<<8 | uint32(t.UID[0])
+	describeHeader(&b, t.Modulation(), "")
+	b.WriteString("                UID: ")
+	describeHex(&b, t.UID[:])
+	fmt.Fprintf(&b, "      UID (decimal): %010d\n", uid)
+	fmt.Fprintf(&b, "       Product Code: %02X\n", t.ProdCode)
+	fmt.Fprintf(&b, "           Fab Code: %02X\n", t.FabCode)
+
+	return b.String()
+}
+
+// Describe the target like str_nfc_target() does. verbose has no effect.
+func (t *ISO14443biClassTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	describeHeader(&b, t.Modulation(), "")
+	b.WriteString("                UID: ")
+	describeHex(&b, t.UID[:])
+
+	return b.String()
+}
+
+// Describe the target like str_nfc_target() does. verbose has no effect.
+func (t *DEPTarget) Describe(verbose bool) string {
+	var b bytes.Buffer
+
+	mode := ", passive mode"
+	if t.DepMode == Active {
+		mode = ", active mode"
+	}
+
+	describeHeader(&b, t.Modulation(), mode)
+	b.WriteString("       NFCID3: ")
+	describeHex(&b, t.NFCID3[:])
+	fmt.Fprintf(&b, "           BS: %02x\n", t.BS)
+	fmt.Fprintf(&b, "           BR: %02x\n", t.BR)
+	fmt.Fprintf(&b, "           TO: %02x\n", t.TO)
+	fmt.Fprintf(&b, "           PP: %02x\n", t.PP)
+	if gbLen := clampLen(t.GBLen, len(t.GB)); gbLen > 0 {
+		b.WriteString("General Bytes: ")
+		describeHex(&b, t.GB[:gbLen])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "testing"
+
+// Compare the output of Describe() with what nfc-list prints for some
+// well-known cards.
+func TestDescribe(t *testing.T) {
+	mfc := &ISO14443aTarget{Atqa: [2]byte{0x00, 0x04}, Sak: 0x08, UIDLen: 4, UID: [10]byte{0xde, 0xad, 0xbe, 0xef}, Baud: Nbr106}
+	want := "ISO/IEC 14443A (106 kbps) target:\n" +
+		"    ATQA (SENS_RES): 00  04  \n" +
+		"       UID (NFCID1): de  ad  be  ef  \n" +
+		"      SAK (SEL_RES): 08  \n"
+	if got := mfc.Describe(false); got != want {
+		t.Errorf("Describe(false) = %q, want %q", got, want)
+	}
+
+	want = "ISO/IEC 14443A (106 kbps) target:\n" +
+		"    ATQA (SENS_RES): 00  04  \n" +
+		"* UID size: single\n" +
+		"* bit frame anticollision supported\n" +
+		"       UID (NFCID1): de  ad  be  ef  \n" +
+		"      SAK (SEL_RES): 08  \n" +
+		"* Not compliant with ISO/IEC 14443-4\n" +
+		"* Not compliant with ISO/IEC 18092\n" +
+		"\n" +
+		"Fingerprinting based on MIFARE type Identification Procedure:\n" +
+		"* MIFARE Classic 1K\n" +
+		"* MIFARE Plus (4 Byte UID or 4 Byte RID) 2K, Security level 1\n" +
+		"* SmartMX with MIFARE 1K emulation\n" +
+		"Other possible matches based on ATQA & SAK values:\n"
+	if got := mfc.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	desfire := testTargets[0].(*ISO14443aTarget)
+	want = "ISO/IEC 14443A (106 kbps) target:\n" +
+		"    ATQA (SENS_RES): 03  44  \n" +
+		"* UID size: double\n" +
+		"* bit frame anticollision supported\n" +
+		"       UID (NFCID1): 04  01  02  03  04  05  06  \n" +
+		"      SAK (SEL_RES): 20  \n" +
+		"* Compliant with ISO/IEC 14443-4\n" +
+		"* Not compliant with ISO/IEC 18092\n" +
+		"                ATS: 75  77  81  02  80  \n" +
+		"* Max Frame Size accepted by PICC: 64 bytes\n" +
+		"* Bit Rate Capability:\n" +
+		"  * PICC to PCD, DS=2, bitrate 212 kbits/s supported\n" +
+		"  * PICC to PCD, DS=4, bitrate 424 kbits/s supported\n" +
+		"  * PICC to PCD, DS=8, bitrate 847 kbits/s supported\n" +
+		"  * PCD to PICC, DR=2, bitrate 212 kbits/s supported\n" +
+		"  * PCD to PICC, DR=4, bitrate 424 kbits/s supported\n" +
+		"  * PCD to PICC, DR=8, bitrate 847 kbits/s supported\n" +
+		"* Frame Waiting Time: 77.33 ms\n" +
+		"* Start-up Frame Guard Time: 0.6041 ms\n" +
+		"* Node Address not supported\n" +
+		"* Card IDentifier supported\n" +
+		"* Historical bytes Tk: 80  \n" +
+		"  * No COMPACT-TLV objects found, no status found\n" +
+		"\n" +
+		"Fingerprinting based on MIFARE type Identification Procedure:\n" +
+		"* MIFARE DESFire 4K\n" +
+		"* MIFARE DESFire EV1 2K/4K/8K\n" +
+		"Other possible matches based on ATQA & SAK values:\n"
+	if got := desfire.Describe(true); got != want {
+		t.Errorf("Describe(true) = %q, want %q", got, want)
+	}
+
+	felica := testTargets[7].(*FelicaTarget)
+	want = "FeliCa (212 kbps) target:\n" +
+		"        ID (NFCID2): 01  2e  01  02  03  04  05  06  \n" +
+		"    Parameter (PAD): 00  f1  00  00  00  00  00  00  \n" +
+		"   System Code (SC): 12  fc  \n"
+	if got := felica.Describe(true); got != want {
+		t.Errorf("Describe(true) = %q, want %q", got, want)
+	}
+
+	dep := testTargets[8].(*DEPTarget)
+	want = "D.E.P. (424 kbps, passive mode) target:\n" +
+		"       NFCID3: 01  02  03  00  00  00  00  00  00  00  \n" +
+		"           BS: 00\n" +
+		"           BR: 00\n" +
+		"           TO: 0e\n" +
+		"           PP: 32\n" +
+		"General Bytes: 46  66  6d  \n"
+	if got := dep.Describe(false); got != want {
+		t.Errorf("Describe(false) = %q, want %q", got, want)
+	}
+
+	// all targets can be described without running into trouble
+	for _, tt := range testTargets {
+		if tt.String() == "" {
+			t.Errorf("%T.String() is empty", tt)
+		}
+	}
+}
@@ -20,16 +20,49 @@ import "C"
 import "unsafe"
 import "errors"
 import "fmt"
+import "sync"
 import "time"
 
-// NFC device
+// NFC device. A Device and its copies may be used from multiple goroutines
+// at once. Operations on a device are performed one at a time, except for
+// AbortCommand(), which may be called while another operation is running
+// to abort it. Close() waits for running operations to finish; operations
+// started afterwards fail.
 type Device struct {
-	d **C.nfc_device
+	d     **C.nfc_device
+	state *deviceState
+	b     backend // if not nil, performs calls instead of libnfc
+}
+
+// State shared between all copies of a Device.
+type deviceState struct {
+	op     sync.Mutex   // held while an operation is performed
+	ptr    sync.RWMutex // held for writing while the nfc_device is closed
+	tracer *tracerState
+}
+
+// Make the state of a new Device.
+func newDeviceState() *deviceState {
+	return &deviceState{tracer: &tracerState{}}
+}
+
+// Make the state of a Device wrapping a Device with state s. The wrapping
+// Device serializes its operations on its own as its backend performs them
+// on the wrapped Device, but shares its tracer.
+func (s *deviceState) wrap() *deviceState {
+	return &deviceState{tracer: s.tracer}
 }
 
 // Return a pointer to the wrapped nfc_device. This is useful if you try to use
 // this wrapper to wrap other C code that builds onto the libnfc.
 func (d Device) Pointer() uintptr {
+	if d.d == nil {
+		return 0
+	}
+
+	d.state.ptr.RLock()
+	defer d.state.ptr.RUnlock()
+
 	return uintptr(unsafe.Pointer(*d.d))
 }
 
@@ -53,7 +86,7 @@ func (c *context) open(conn string) (d Device, err error) {
 		return
 	}
 
-	d = Device{&dev}
+	d = Device{d: &dev, state: newDeviceState()}
 	return
 }
 
@@ -73,6 +106,18 @@ func Open(conn string) (Device, error) {
 // functions operating on an nfc_device should call this function and return the
 // result. This wraps nfc_device_get_last_error.
 func (d Device) LastError() error {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "LastError"}
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.LastError()) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -88,7 +133,28 @@ func (d Device) LastError() error {
 
 // Close an NFC device.
 func (d Device) Close() error {
-	if d.d == nil || *d.d == nil {
+	if d.state == nil {
+		// closing the zero Device is a nop
+		return nil
+	}
+
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "Close"}
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.Close()) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
+	// keep AbortCommand() out while closing
+	d.state.ptr.Lock()
+	defer d.state.ptr.Unlock()
+
+	if *d.d == nil {
 		// closing a closed device is a nop
 		return nil
 	}
@@ -104,6 +170,13 @@ func (d Device) Close() error {
 // initiator request). This function attempt to abort the current running
 // command.
 func (d Device) AbortCommand() error {
+	if d.b != nil {
+		return d.b.abort()
+	}
+
+	d.state.ptr.RLock()
+	defer d.state.ptr.RUnlock()
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -116,6 +189,18 @@ func (d Device) AbortCommand() error {
 // emulation is stoped (no target available from external initiator) and the
 // device is set to low power mode (if avaible).
 func (d Device) Idle() error {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "Idle"}
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.Idle()) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -125,6 +210,23 @@ func (d Device) Idle() error {
 
 // Print information about an NFC device.
 func (d Device) Information() (string, error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "Information"}
+		err := d.b.call(&c, func(d Device) {
+			info, err := d.Information()
+			c.Info = info
+			c.setErr(err)
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return c.Info, c.err()
+	}
+
 	if *d.d == nil {
 		return "", errors.New("device closed")
 	}
@@ -148,6 +250,13 @@ func (d Device) Information() (string, error) {
 // Returns the device's connection string. If the device has been closed before,
 // this function returns the empty string.
 func (d Device) Connection() string {
+	if d.b != nil {
+		return d.b.connection()
+	}
+
+	d.state.ptr.RLock()
+	defer d.state.ptr.RUnlock()
+
 	if *d.d == nil {
 		return ""
 	}
@@ -159,6 +268,13 @@ func (d Device) Connection() string {
 // Returns the device's name. This information is not enough to uniquely
 // determine the device.
 func (d Device) String() string {
+	if d.b != nil {
+		return d.b.name()
+	}
+
+	d.state.ptr.RLock()
+	defer d.state.ptr.RUnlock()
+
 	if *d.d == nil {
 		return ""
 	}
@@ -169,16 +285,33 @@ func (d Device) String() string {
 
 // Return Go code that could be used to reproduce this device.
 func (d Device) GoString() string {
-	if *d.d == nil {
+	conn := ""
+	if d.d != nil {
+		conn = d.Connection()
+	}
+
+	if conn == "" {
 		return "nil"
 	}
 
-	return fmt.Sprintf("nfc.Open(%q)", d.Connection())
+	return fmt.Sprintf("nfc.Open(%q)", conn)
 }
 
 // Set a device's integer-property value. Returns nil on success, otherwise an
 // error. See integer constants in this package for possible properties.
 func (d Device) SetPropertyInt(property, value int) error {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "SetPropertyInt", callArgs: callArgs{Property: property, Value: value}}
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.SetPropertyInt(property, value)) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -195,6 +328,22 @@ func (d Device) SetPropertyInt(property, value int) error {
 // Set a device's boolean-property value. Returns nil on success, otherwise an
 // error. See integer constants in this package for possible properties.
 func (d Device) SetPropertyBool(property int, value bool) error {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "SetPropertyBool", callArgs: callArgs{Property: property}}
+		if value {
+			c.Value = 1
+		}
+
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.SetPropertyBool(property, value)) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -212,6 +361,25 @@ func (d Device) SetPropertyBool(property int, value bool) error {
 // error. Pass either TARGET or INITIATOR as mode. This function wraps
 // nfc_device_get_supported_modulation()
 func (d Device) SupportedModulations(mode int) ([]int, error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "SupportedModulations", callArgs: callArgs{Mode: mode}}
+		err := d.b.call(&c, func(d Device) {
+			mods, err := d.SupportedModulations(mode)
+			c.Ints = mods
+			c.setErr(err)
+		})
+		if err != nil {
+			return nil, err
+		} else if err = c.err(); err != nil {
+			return nil, err
+		}
+
+		return append([]int{}, c.Ints...), nil
+	}
+
 	if *d.d == nil {
 		return nil, errors.New("device closed")
 	}
@@ -245,6 +413,25 @@ func (d Device) SupportedModulations(mode int) ([]int, error) {
 // nfc_device_get_supported_baud_rate_target_mode() depending on the mode
 // argument.
 func (d Device) supportedBaudRatesForMode(mode int, modulationType int) ([]int, error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "SupportedBaudRates", callArgs: callArgs{Mode: mode, Type: modulationType}}
+		err := d.b.call(&c, func(d Device) {
+			brs, err := d.supportedBaudRatesForMode(mode, modulationType)
+			c.Ints = brs
+			c.setErr(err)
+		})
+		if err != nil {
+			return nil, err
+		} else if err = c.err(); err != nil {
+			return nil, err
+		}
+
+		return append([]int{}, c.Ints...), nil
+	}
+
 	if *d.d == nil {
 		return nil, errors.New("device closed")
 	}
@@ -319,10 +506,29 @@ func (d Device) SupportedBaudRatesTargetMode(modulationType int) ([]int, error)
 // raised or function is completed). If timeout equals to -1, the default
 // timeout will be used.
 func (d Device) TargetInit(t Target, rx []byte, timeout int) (n int, tt Target, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "TargetInit", callArgs: callArgs{Target: newJSONTarget(t), RxLen: len(rx), Timeout: timeout}}
+		err = d.b.call(&c, func(d Device) {
+			n, tt, err := d.TargetInit(t, rx, timeout)
+			c.setBytes(rx, n)
+			c.Result = newJSONTarget(tt)
+			c.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, t, err
+		}
+
+		return c.getData(rx, nil), c.target(), c.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, t, errors.New("device closed")
 	}
 
+	start := time.Now()
 	tar := (*C.nfc_target)(unsafe.Pointer(t.Marshall()))
 	defer C.free(unsafe.Pointer(tar))
 
@@ -337,6 +543,7 @@ func (d Device) TargetInit(t Target, rx []byte, timeout int) (n int, tt Target,
 	}
 
 	tt = unmarshallTarget(tar)
+	d.trace("TargetInit", start, traceFrame{}, traceBytes(rx, n), 0, err)
 	return
 }
 
@@ -352,10 +559,28 @@ func (d Device) TargetInit(t Target, rx []byte, timeout int) (n int, tt Target,
 // raised or function is completed). If timeout equals to -1, the default
 // timeout will be used.
 func (d Device) TargetSendBytes(tx []byte, timeout int) (n int, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "TargetSendBytes", callArgs: callArgs{Tx: nilIfEmpty(tx), Timeout: timeout}}
+		err = d.b.call(&c, func(d Device) {
+			n, err := d.TargetSendBytes(tx, timeout)
+			c.N = n
+			c.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, err
+		}
+
+		return c.N, c.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, errors.New("device closed")
 	}
 
+	start := time.Now()
 	n = int(C.nfc_target_send_bytes(
 		*d.d,
 		(*C.uint8_t)(&tx[0]), C.size_t(len(tx)),
@@ -366,6 +591,7 @@ func (d Device) TargetSendBytes(tx []byte, timeout int) (n int, err error) {
 		err = Error(n)
 	}
 
+	d.trace("TargetSendBytes", start, traceBytes(tx, len(tx)), traceFrame{}, 0, err)
 	return
 }
 
@@ -381,10 +607,28 @@ func (d Device) TargetSendBytes(tx []byte, timeout int) (n int, err error) {
 // raised or function is completed). If timeout equals to -1, the default
 // timeout will be used.
 func (d Device) TargetReceiveBytes(rx []byte, timeout int) (n int, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "TargetReceiveBytes", callArgs: callArgs{RxLen: len(rx), Timeout: timeout}}
+		err = d.b.call(&c, func(d Device) {
+			n, err := d.TargetReceiveBytes(rx, timeout)
+			c.setBytes(rx, n)
+			c.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, err
+		}
+
+		return c.getData(rx, nil), c.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, errors.New("device closed")
 	}
 
+	start := time.Now()
 	n = int(C.nfc_target_receive_bytes(
 		*d.d,
 		(*C.uint8_t)(&rx[0]), C.size_t(len(rx)),
@@ -395,6 +639,7 @@ func (d Device) TargetReceiveBytes(rx []byte, timeout int) (n int, err error) {
 		err = Error(n)
 	}
 
+	d.trace("TargetReceiveBytes", start, traceFrame{}, traceBytes(rx, n), 0, err)
 	return
 }
 
@@ -411,6 +656,23 @@ func (d Device) TargetReceiveBytes(rx []byte, timeout int) (n int, err error) {
 // his function can be used to transmit (raw) bit-frames to the initiator using
 // the specified NFC device (configured as target).
 func (d Device) TargetSendBits(tx []byte, txPar []byte, txLength uint) (n int, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "TargetSendBits", callArgs: callArgs{Tx: nilIfEmpty(tx), TxPar: nilIfEmpty(txPar), TxBits: txLength}}
+		err = d.b.call(&c, func(d Device) {
+			n, err := d.TargetSendBits(tx, txPar, txLength)
+			c.N = n
+			c.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, err
+		}
+
+		return c.N, c.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, errors.New("device closed")
 	}
@@ -423,6 +685,7 @@ func (d Device) TargetSendBits(tx []byte, txPar []byte, txLength uint) (n int, e
 		return ESOFT, errors.New("slice shorter than specified bit count")
 	}
 
+	start := time.Now()
 	n = int(C.nfc_target_send_bits(
 		*d.d,
 		(*C.uint8_t)(&tx[0]),
@@ -434,6 +697,7 @@ func (d Device) TargetSendBits(tx []byte, txPar []byte, txLength uint) (n int, e
 		err = Error(n)
 	}
 
+	d.trace("TargetSendBits", start, traceBits(tx, txPar, int(txLength)), traceFrame{}, 0, err)
 	return
 }
 
@@ -454,6 +718,23 @@ func (d Device) TargetSendBits(tx []byte, txPar []byte, txLength uint) (n int, e
 // ACCEPT_MULTIPLE_FRAMES configuration option to avoid losing transmitted
 // frames.
 func (d Device) TargetTransceiveBits(rx []byte, rxPar []byte, rxLength uint) (n int, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "TargetTransceiveBits", callArgs: callArgs{RxLen: len(rx), RxBits: rxLength}}
+		err = d.b.call(&c, func(d Device) {
+			n, err := d.TargetTransceiveBits(rx, rxPar, rxLength)
+			c.setBits(rx, rxPar, n)
+			c.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, err
+		}
+
+		return c.getData(rx, rxPar), c.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, errors.New("device closed")
 	}
@@ -466,6 +747,7 @@ func (d Device) TargetTransceiveBits(rx []byte, rxPar []byte, rxLength uint) (n
 		return ESOFT, errors.New("slice shorter than specified bit count")
 	}
 
+	start := time.Now()
 	n = int(C.nfc_target_receive_bits(
 		*d.d,
 		(*C.uint8_t)(&rx[0]),
@@ -477,6 +759,7 @@ func (d Device) TargetTransceiveBits(rx []byte, rxPar []byte, rxLength uint) (n
 		err = Error(n)
 	}
 
+	d.trace("TargetTransceiveBits", start, traceFrame{}, traceBits(rx, rxPar, n), 0, err)
 	return
 }
 
@@ -484,19 +767,46 @@ func (d Device) TargetTransceiveBits(rx []byte, rxPar []byte, rxLength uint) (n
 // modulations is a slice of all modulation types to poll for.  times is the
 // number of times we desire to poll (indefinite polling is not supported).
 // period is the time to wait between polls.  It is rounded up to the next
-// 150 ms and must be between 150 ms and 2.25 s.  If targets are
+// multiple of PollPeriodUnit and must not exceed MaxPollPeriod.  If targets are
 // found, one is selected and returned.
 //
 // This function wraps nfc_initiator_poll_target but is extended to lack
 // its limitation to 255 polls.
 func (d Device) InitiatorPollTarget(modulations []Modulation, times int, period time.Duration) (n int, t Target, err error) {
-	ms := period.Milliseconds()
-	if ms <= 0 || ms > 2250 || times < 0 {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorPollTarget", callArgs: callArgs{Modulations: modulations, Times: times, Period: period}}
+		if len(modulations) == 0 {
+			c.Modulations = nil
+		}
+
+		err = d.b.call(&c, func(d Device) {
+			n, t, err := d.InitiatorPollTarget(modulations, times, period)
+			c.N = n
+			c.Result = newJSONTarget(t)
+			c.setErr(err)
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+
+		return c.N, c.target(), c.err()
+	}
+
+	units, err := pollPeriodUnits(period)
+	if err != nil || times < 0 {
 		err = Error(EINVARG)
 		return
 	}
 
-	uiPeriod := C.uchar((ms + 149) / 150)
+	if *d.d == nil {
+		err = errors.New("device closed")
+		return
+	}
+
+	uiPeriod := C.uchar(units)
 
 	if modulations == nil {
 		modulations = make([]Modulation, 0)
@@ -532,3 +842,16 @@ func (d Device) InitiatorPollTarget(modulations []Modulation, times int, period
 	// nothing found
 	return
 }
+
+// TargetDevice is the subset of the methods of Device needed to emulate a
+// target.  The emulators in this package are written against this interface
+// instead of Device so they can be tested without hardware.  Device
+// implements TargetDevice.
+type TargetDevice interface {
+	TargetInit(t Target, rx []byte, timeout int) (n int, tt Target, err error)
+	TargetSendBytes(tx []byte, timeout int) (n int, err error)
+	TargetReceiveBytes(rx []byte, timeout int) (n int, err error)
+	TargetSendBits(tx []byte, txPar []byte, txLength uint) (n int, err error)
+	TargetTransceiveBits(rx []byte, rxPar []byte, rxLength uint) (n int, err error)
+	SetPropertyBool(property int, value bool) error
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "sync"
+import "testing"
+import "time"
+
+// A backend for testing concurrent use of a Device. Its fields are not
+// protected by a lock, so the race detector complains if calls overlap.
+type mockBackend struct {
+	ops     []string      // operations performed in order
+	block   chan struct{} // if not nil, TargetInit waits until it is closed
+	started chan struct{} // if not nil, closed once TargetInit has started
+	aborted chan struct{} // closed by abort()
+	once    sync.Once
+}
+
+// Make a Device backed by a new mockBackend.
+func newMockDevice() (Device, *mockBackend) {
+	b := &mockBackend{aborted: make(chan struct{})}
+	return Device{state: newDeviceState(), b: b}, b
+}
+
+func (b *mockBackend) call(c *deviceCall, do func(d Device)) error {
+	b.ops = append(b.ops, c.Op)
+	if c.Op == "TargetInit" && b.block != nil {
+		close(b.started)
+		<-b.block
+	}
+
+	return nil
+}
+
+func (b *mockBackend) abort() error {
+	b.once.Do(func() { close(b.aborted) })
+	return nil
+}
+
+func (b *mockBackend) name() string {
+	return "mock"
+}
+
+func (b *mockBackend) connection() string {
+	return "mock:0"
+}
+
+// Perform calls on copies of a Device from many goroutines at once.
+func TestDeviceConcurrent(t *testing.T) {
+	d, b := newMockDevice()
+
+	const goroutines, calls = 8, 100
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(d Device) {
+			defer wg.Done()
+
+			rx := make([]byte, 16)
+			for j := 0; j < calls; j++ {
+				d.InitiatorTransceiveBytes([]byte{0x30, byte(j)}, rx, -1)
+				d.SetPropertyBool(EasyFraming, j%2 == 0)
+				d.Idle()
+			}
+		}(d)
+	}
+
+	wg.Wait()
+
+	if len(b.ops) != goroutines*calls*3 {
+		t.Errorf("got %d calls, want %d", len(b.ops), goroutines*calls*3)
+	}
+}
+
+// AbortCommand() must get through while another call is running, and
+// Close() must wait for it to finish.
+func TestDeviceAbortClose(t *testing.T) {
+	d, b := newMockDevice()
+	b.block = make(chan struct{})
+	b.started = make(chan struct{})
+
+	initDone := make(chan struct{})
+	go func() {
+		defer close(initDone)
+		d.TargetInit(&ISO14443aTarget{}, make([]byte, 16), 0)
+	}()
+
+	<-b.started
+
+	closeDone := make(chan struct{})
+	go func() {
+		defer close(closeDone)
+		d.Close()
+	}()
+
+	if err := d.AbortCommand(); err != nil {
+		t.Error("AbortCommand():", err)
+	}
+
+	select {
+	case <-b.aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("AbortCommand() did not reach the backend")
+	}
+
+	select {
+	case <-closeDone:
+		t.Fatal("Close() did not wait for TargetInit()")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(b.block)
+	<-initDone
+	<-closeDone
+
+	if len(b.ops) != 2 || b.ops[0] != "TargetInit" || b.ops[1] != "Close" {
+		t.Errorf("got calls %v, want [TargetInit Close]", b.ops)
+	}
+}
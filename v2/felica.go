@@ -0,0 +1,450 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "time"
+
+// FeliCa command codes. The response code of each command is the command
+// code plus one.
+const (
+	FelicaPolling                = 0x00
+	FelicaRequestService         = 0x02
+	FelicaRequestResponse        = 0x04
+	FelicaReadWithoutEncryption  = 0x06
+	FelicaWriteWithoutEncryption = 0x08
+	FelicaSearchServiceCode      = 0x0a
+	FelicaRequestSystemCode      = 0x0c
+)
+
+// Request codes for the Polling command.
+const (
+	FelicaRequestNone          = 0x00 // no request data
+	FelicaRequestSysCode       = 0x01 // request the system code
+	FelicaRequestCommunication = 0x02 // request communication performance
+)
+
+// Wildcard system code for the Polling command, matching any system.
+const FelicaSystemWildcard = 0xffff
+
+// Maximum length of a FeliCa frame including the length byte.
+const felicaMaxFrame = 255
+
+// Maximum number of blocks in a single Read or Write Without Encryption.
+const FelicaMaxBlocks = 15
+
+// An element of the block list of Read and Write Without Encryption. Block
+// list elements with a block number of up to 255 are encoded in two bytes,
+// all others in three.
+type FelicaBlock struct {
+	Service    int    // index into the service code list of the command
+	AccessMode byte   // access mode, 0 for normal access
+	Number     uint16 // block number
+}
+
+// Append the encoding of a block list to buf.
+func appendFelicaBlockList(buf []byte, blocks []FelicaBlock) ([]byte, error) {
+	for _, b := range blocks {
+		if b.Service < 0 || b.Service > 0xf || b.AccessMode > 0x7 {
+			return nil, Error(EINVARG)
+		}
+
+		head := b.AccessMode<<4 | byte(b.Service)
+		if b.Number <= 0xff {
+			buf = append(buf, 0x80|head, byte(b.Number))
+		} else {
+			buf = append(buf, head, byte(b.Number), byte(b.Number>>8))
+		}
+	}
+
+	return buf, nil
+}
+
+// Parse a block list of n elements from the beginning of buf. Returns the
+// block list and the remainder of buf.
+func parseFelicaBlockList(buf []byte, n int) ([]FelicaBlock, []byte, error) {
+	blocks := make([]FelicaBlock, n)
+	for i := range blocks {
+		if len(buf) < 2 || buf[0]&0x80 == 0 && len(buf) < 3 {
+			return nil, nil, errors.New("FeliCa: truncated block list")
+		}
+
+		blocks[i].Service = int(buf[0] & 0xf)
+		blocks[i].AccessMode = buf[0] >> 4 & 0x7
+		if buf[0]&0x80 != 0 {
+			blocks[i].Number = uint16(buf[1])
+			buf = buf[2:]
+		} else {
+			blocks[i].Number = binary.LittleEndian.Uint16(buf[1:3])
+			buf = buf[3:]
+		}
+	}
+
+	return blocks, buf, nil
+}
+
+// Append a list of service or node codes to buf. Codes are little endian.
+func appendFelicaCodes(buf []byte, codes []uint16) []byte {
+	for _, c := range codes {
+		buf = append(buf, byte(c), byte(c>>8))
+	}
+
+	return buf
+}
+
+// Error returned by a FeliCa card through the status flags of a response.
+// Flag1 is 0xff if the error is not related to a particular element of the
+// block list; otherwise it indicates the erroneous element. Flag2 gives the
+// reason.
+type FelicaStatusError struct {
+	Flag1, Flag2 byte
+}
+
+var felicaStatusMessages = map[byte]string{
+	0x01: "purse data under/overflow",
+	0x02: "cashback data exceeded",
+	0x70: "memory error",
+	0x71: "excessive write count",
+	0xa1: "illegal number of services",
+	0xa2: "illegal command packet",
+	0xa3: "illegal block list",
+	0xa4: "illegal service type",
+	0xa5: "access not allowed",
+	0xa6: "illegal service code list",
+	0xa7: "illegal block list access mode",
+	0xa8: "illegal block number",
+	0xa9: "data write failure",
+	0xaa: "key change failure",
+	0xab: "illegal package parity or format",
+	0xac: "illegal state",
+	0xb0: "illegal purse data",
+	0xb1: "illegal cashback",
+	0xb2: "illegal issue",
+}
+
+func (e FelicaStatusError) Error() string {
+	msg, ok := felicaStatusMessages[e.Flag2]
+	if !ok {
+		msg = fmt.Sprintf("status %#02x", e.Flag2)
+	}
+
+	if e.Flag1 == 0xff {
+		return "FeliCa: " + msg
+	}
+
+	return fmt.Sprintf("FeliCa: %s (flag1 %#02x)", msg, e.Flag1)
+}
+
+// Decode status flags into an error. Returns nil if both flags are zero.
+func felicaStatus(flag1, flag2 byte) error {
+	if flag1 == 0 && flag2 == 0 {
+		return nil
+	}
+
+	return FelicaStatusError{flag1, flag2}
+}
+
+// Response to the Polling command. RequestData holds the two bytes of
+// requested data, if a request code other than FelicaRequestNone was given
+// and the card supports it.
+type FelicaPollingResponse struct {
+	IDm         [8]byte
+	PMm         [8]byte
+	RequestData []byte
+}
+
+// A node found by the Search Service Code command. For areas, Code is the
+// area code and End the end service code of the area; for services, End
+// equals Code.
+type FelicaNode struct {
+	Code   uint16
+	End    uint16
+	IsArea bool
+}
+
+// FelicaCard is a command layer for FeliCa cards. Frames are exchanged with
+// Device.InitiatorTransceiveBytes() and carry a leading length byte as on
+// the air interface. Most commands address the card by its IDm; set IDm
+// from FelicaTarget.ID after selecting the card.
+type FelicaCard struct {
+	Device  Initiator     // device used to communicate with the card
+	IDm     [8]byte       // manufacture ID of the card
+	Timeout time.Duration // see Device.InitiatorTransceiveBytesTimeout()
+}
+
+// Make a new command layer for the FeliCa card t using the default timeout.
+func NewFelicaCard(d Initiator, t *FelicaTarget) *FelicaCard {
+	return &FelicaCard{Device: d, IDm: t.ID, Timeout: DefaultTimeout}
+}
+
+// Send command cmd with parameters params and return the parameters of the
+// response. If withIDm is set, the IDm is inserted before the parameters
+// and checked in the response.
+func (f *FelicaCard) exchange(cmd byte, withIDm bool, params []byte) ([]byte, error) {
+	tx := make([]byte, 2, felicaMaxFrame)
+	tx[1] = cmd
+	if withIDm {
+		tx = append(tx, f.IDm[:]...)
+	}
+
+	tx = append(tx, params...)
+	if len(tx) > felicaMaxFrame {
+		return nil, Error(EOVFLOW)
+	}
+
+	tx[0] = byte(len(tx))
+
+	rx := make([]byte, felicaMaxFrame)
+	n, err := transceiveBytesTimeout(f.Device, tx, rx, f.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	rx = rx[:n]
+	if n < 2 || int(rx[0]) != n {
+		return nil, fmt.Errorf("FeliCa: malformed response to command %#02x", cmd)
+	}
+
+	if rx[1] != cmd+1 {
+		return nil, fmt.Errorf("FeliCa: expected response code %#02x, got %#02x", cmd+1, rx[1])
+	}
+
+	rx = rx[2:]
+	if withIDm {
+		if len(rx) < len(f.IDm) {
+			return nil, fmt.Errorf("FeliCa: truncated response to command %#02x", cmd)
+		}
+
+		for i := range f.IDm {
+			if rx[i] != f.IDm[i] {
+				return nil, errors.New("FeliCa: response from wrong card")
+			}
+		}
+
+		rx = rx[len(f.IDm):]
+	}
+
+	return rx, nil
+}
+
+// Issue Polling for systemCode (FelicaSystemWildcard for any system) with
+// the given request code and number of time slots minus one. The IDm of
+// the responding card is not stored in f.
+func (f *FelicaCard) Polling(systemCode uint16, requestCode, timeSlot byte) (*FelicaPollingResponse, error) {
+	rx, err := f.exchange(FelicaPolling, false,
+		[]byte{byte(systemCode >> 8), byte(systemCode), requestCode, timeSlot})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rx) < 16 {
+		return nil, errors.New("FeliCa: truncated polling response")
+	}
+
+	resp := &FelicaPollingResponse{}
+	copy(resp.IDm[:], rx[0:8])
+	copy(resp.PMm[:], rx[8:16])
+	if len(rx) > 16 {
+		resp.RequestData = append([]byte(nil), rx[16:]...)
+	}
+
+	return resp, nil
+}
+
+// Issue Request Service for the given area and service codes. Returns the
+// key version of each node, 0xffff for nodes that do not exist.
+func (f *FelicaCard) RequestService(nodes []uint16) ([]uint16, error) {
+	if len(nodes) == 0 || len(nodes) > 32 {
+		return nil, Error(EINVARG)
+	}
+
+	params := appendFelicaCodes([]byte{byte(len(nodes))}, nodes)
+	rx, err := f.exchange(FelicaRequestService, true, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rx) < 1 || len(rx) != 1+2*int(rx[0]) {
+		return nil, errors.New("FeliCa: malformed Request Service response")
+	}
+
+	versions := make([]uint16, rx[0])
+	for i := range versions {
+		versions[i] = binary.LittleEndian.Uint16(rx[1+2*i:])
+	}
+
+	return versions, nil
+}
+
+// Issue Request Response. Returns the current mode of the card.
+func (f *FelicaCard) RequestResponse() (mode byte, err error) {
+	rx, err := f.exchange(FelicaRequestResponse, true, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(rx) != 1 {
+		return 0, errors.New("FeliCa: malformed Request Response response")
+	}
+
+	return rx[0], nil
+}
+
+// Issue Request System Code. Returns the system codes of the card.
+func (f *FelicaCard) RequestSystemCode() ([]uint16, error) {
+	rx, err := f.exchange(FelicaRequestSystemCode, true, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rx) < 1 || len(rx) != 1+2*int(rx[0]) {
+		return nil, errors.New("FeliCa: malformed Request System Code response")
+	}
+
+	codes := make([]uint16, rx[0])
+	for i := range codes {
+		// system codes are big endian, unlike service codes
+		codes[i] = binary.BigEndian.Uint16(rx[1+2*i:])
+	}
+
+	return codes, nil
+}
+
+// Issue Search Service Code for the node with the given index. Returns
+// ok = false once index exceeds the last node.
+func (f *FelicaCard) SearchServiceCode(index uint16) (node FelicaNode, ok bool, err error) {
+	rx, err := f.exchange(FelicaSearchServiceCode, true, []byte{byte(index), byte(index >> 8)})
+	if err != nil {
+		return
+	}
+
+	switch len(rx) {
+	case 2:
+		node.Code = binary.LittleEndian.Uint16(rx)
+		node.End = node.Code
+		ok = node.Code != 0xffff
+	case 4:
+		node.Code = binary.LittleEndian.Uint16(rx)
+		node.End = binary.LittleEndian.Uint16(rx[2:])
+		node.IsArea = true
+		ok = true
+	default:
+		err = errors.New("FeliCa: malformed Search Service Code response")
+	}
+
+	return
+}
+
+// Enumerate all areas and services of the card using Search Service Code.
+func (f *FelicaCard) SearchServiceCodes() ([]FelicaNode, error) {
+	nodes := []FelicaNode{}
+	for i := 0; i <= 0xffff; i++ {
+		node, ok, err := f.SearchServiceCode(uint16(i))
+		if err != nil {
+			return nodes, err
+		}
+
+		if !ok {
+			break
+		}
+
+		nodes = append(nodes, node)
+	}
+
+	return nodes, nil
+}
+
+// Build the service code list and block list parameters common to Read and
+// Write Without Encryption.
+func felicaBlockParams(services []uint16, blocks []FelicaBlock) ([]byte, error) {
+	if len(services) == 0 || len(services) > 16 ||
+		len(blocks) == 0 || len(blocks) > FelicaMaxBlocks {
+		return nil, Error(EINVARG)
+	}
+
+	params := appendFelicaCodes([]byte{byte(len(services))}, services)
+	params = append(params, byte(len(blocks)))
+
+	for _, b := range blocks {
+		if b.Service >= len(services) {
+			return nil, Error(EINVARG)
+		}
+	}
+
+	return appendFelicaBlockList(params, blocks)
+}
+
+// Issue Read Without Encryption for the given blocks. Each block refers to
+// an entry of services through its Service field.
+func (f *FelicaCard) ReadWithoutEncryption(services []uint16, blocks []FelicaBlock) ([][16]byte, error) {
+	params, err := felicaBlockParams(services, blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	rx, err := f.exchange(FelicaReadWithoutEncryption, true, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(rx) < 2 {
+		return nil, errors.New("FeliCa: malformed Read Without Encryption response")
+	}
+
+	if err = felicaStatus(rx[0], rx[1]); err != nil {
+		return nil, err
+	}
+
+	rx = rx[2:]
+	if len(rx) < 1 || int(rx[0]) != len(blocks) || len(rx) != 1+16*len(blocks) {
+		return nil, errors.New("FeliCa: malformed Read Without Encryption response")
+	}
+
+	data := make([][16]byte, len(blocks))
+	for i := range data {
+		copy(data[i][:], rx[1+16*i:])
+	}
+
+	return data, nil
+}
+
+// Issue Write Without Encryption, writing data[i] to blocks[i].
+func (f *FelicaCard) WriteWithoutEncryption(services []uint16, blocks []FelicaBlock, data [][16]byte) error {
+	if len(blocks) != len(data) {
+		return Error(EINVARG)
+	}
+
+	params, err := felicaBlockParams(services, blocks)
+	if err != nil {
+		return err
+	}
+
+	for i := range data {
+		params = append(params, data[i][:]...)
+	}
+
+	rx, err := f.exchange(FelicaWriteWithoutEncryption, true, params)
+	if err != nil {
+		return err
+	}
+
+	if len(rx) != 2 {
+		return errors.New("FeliCa: malformed Write Without Encryption response")
+	}
+
+	return felicaStatus(rx[0], rx[1])
+}
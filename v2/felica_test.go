@@ -0,0 +1,224 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "testing"
+
+var felicaTestIDm = [8]byte{0x01, 0x2e, 0x4c, 0xd1, 0x02, 0x03, 0x04, 0x05}
+
+// Build a FeliCa frame with length byte, command or response code, the
+// given IDm (if any) and parameters.
+func felicaFrame(code byte, idm []byte, params ...byte) []byte {
+	f := append([]byte{0, code}, idm...)
+	f = append(f, params...)
+	f[0] = byte(len(f))
+	return f
+}
+
+func TestFelicaBlockList(t *testing.T) {
+	tests := []struct {
+		blocks []FelicaBlock
+		enc    []byte
+	}{
+		{[]FelicaBlock{{Number: 1}}, []byte{0x80, 0x01}},
+		{[]FelicaBlock{{Service: 2, AccessMode: 1, Number: 0xff}}, []byte{0x92, 0xff}},
+		{[]FelicaBlock{{Service: 1, Number: 0x100}}, []byte{0x01, 0x00, 0x01}},
+		{[]FelicaBlock{{Service: 0xf, Number: 0x1234}, {Number: 7}}, []byte{0x0f, 0x34, 0x12, 0x80, 0x07}},
+	}
+
+	for _, tt := range tests {
+		enc, err := appendFelicaBlockList(nil, tt.blocks)
+		if err != nil || !bytes.Equal(enc, tt.enc) {
+			t.Errorf("appendFelicaBlockList(%+v) = % x, %v, want % x", tt.blocks, enc, err, tt.enc)
+			continue
+		}
+
+		blocks, rest, err := parseFelicaBlockList(append(enc, 0xaa), len(tt.blocks))
+		if err != nil || len(blocks) != len(tt.blocks) || !bytes.Equal(rest, []byte{0xaa}) {
+			t.Errorf("parseFelicaBlockList(% x) = %+v, % x, %v", enc, blocks, rest, err)
+			continue
+		}
+
+		for i := range blocks {
+			if blocks[i] != tt.blocks[i] {
+				t.Errorf("parseFelicaBlockList(% x): block %d is %+v, want %+v", enc, i, blocks[i], tt.blocks[i])
+			}
+		}
+	}
+
+	for _, b := range []FelicaBlock{{Service: 16}, {Service: -1}, {AccessMode: 8}} {
+		if _, err := appendFelicaBlockList(nil, []FelicaBlock{b}); err != Error(EINVARG) {
+			t.Errorf("appendFelicaBlockList(%+v): got error %v, want %v", b, err, Error(EINVARG))
+		}
+	}
+
+	if _, _, err := parseFelicaBlockList([]byte{0x80, 0x01, 0x00, 0x01}, 2); err == nil {
+		t.Error("parseFelicaBlockList() of truncated 3 byte element succeeded")
+	}
+}
+
+func TestFelicaStatusError(t *testing.T) {
+	tests := []struct {
+		err FelicaStatusError
+		msg string
+	}{
+		{FelicaStatusError{0xff, 0xa5}, "FeliCa: access not allowed"},
+		{FelicaStatusError{0x02, 0xa8}, "FeliCa: illegal block number (flag1 0x02)"},
+		{FelicaStatusError{0xff, 0x55}, "FeliCa: status 0x55"},
+	}
+
+	for _, tt := range tests {
+		if msg := tt.err.Error(); msg != tt.msg {
+			t.Errorf("%#v.Error() = %q, want %q", tt.err, msg, tt.msg)
+		}
+	}
+
+	if err := felicaStatus(0, 0); err != nil {
+		t.Errorf("felicaStatus(0, 0) = %v, want nil", err)
+	}
+
+	if err := felicaStatus(0x01, 0xa3); err != (FelicaStatusError{0x01, 0xa3}) {
+		t.Errorf("felicaStatus(0x01, 0xa3) = %#v", err)
+	}
+}
+
+// Run commands against a scripted card, checking the frames sent and the
+// handling of the responses.
+func TestFelicaCard(t *testing.T) {
+	idm := felicaTestIDm[:]
+	other := []byte{0x01, 0x2e, 0x4c, 0xd1, 0x02, 0x03, 0x04, 0x06}
+	block := bytes.Repeat([]byte{0x5a}, 16)
+
+	// Read Without Encryption of blocks 0 and 0x100 of service 0x000b
+	readCmd := felicaFrame(FelicaReadWithoutEncryption, idm,
+		0x01, 0x0b, 0x00, 0x02, 0x80, 0x00, 0x00, 0x00, 0x01)
+	read := func(f *FelicaCard) error {
+		data, err := f.ReadWithoutEncryption([]uint16{0x000b}, []FelicaBlock{{Number: 0}, {Number: 0x100}})
+		if err == nil && (!bytes.Equal(data[0][:], block) || data[1] != [16]byte{}) {
+			t.Errorf("ReadWithoutEncryption() = % x", data)
+		}
+
+		return err
+	}
+
+	mode := func(f *FelicaCard) error {
+		_, err := f.RequestResponse()
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		call   func(f *FelicaCard) error
+		script []scriptedExchange
+		err    error // expected error, nil for success
+		fails  bool  // expect an error other than a FelicaStatusError
+	}{{
+		name: "polling",
+		call: func(f *FelicaCard) error {
+			p, err := f.Polling(0x12fc, FelicaRequestSysCode, 0)
+			if err == nil && (p.IDm != felicaTestIDm || !bytes.Equal(p.RequestData, []byte{0x12, 0xfc})) {
+				t.Errorf("Polling() = %+v", p)
+			}
+
+			return err
+		},
+		script: []scriptedExchange{{
+			tx: []byte{0x06, FelicaPolling, 0x12, 0xfc, 0x01, 0x00},
+			rx: felicaFrame(FelicaPolling+1, idm, 0, 0, 0, 0, 0, 0, 0, 0, 0x12, 0xfc),
+		}},
+	}, {
+		name: "read",
+		call: read,
+		script: []scriptedExchange{{
+			tx: readCmd,
+			rx: felicaFrame(FelicaReadWithoutEncryption+1, idm,
+				append(append([]byte{0x00, 0x00, 0x02}, block...), make([]byte, 16)...)...),
+		}},
+	}, {
+		name:   "status error",
+		call:   read,
+		script: []scriptedExchange{{tx: readCmd, rx: felicaFrame(FelicaReadWithoutEncryption+1, idm, 0x02, 0xa8)}},
+		err:    FelicaStatusError{0x02, 0xa8},
+	}, {
+		name:   "wrong length byte",
+		call:   mode,
+		script: []scriptedExchange{{tx: felicaFrame(FelicaRequestResponse, idm), rx: []byte{0x0c, 0x05}}},
+		fails:  true,
+	}, {
+		name:   "wrong response code",
+		call:   mode,
+		script: []scriptedExchange{{tx: felicaFrame(FelicaRequestResponse, idm), rx: felicaFrame(FelicaRequestService+1, idm, 0x00)}},
+		fails:  true,
+	}, {
+		name:   "wrong IDm",
+		call:   mode,
+		script: []scriptedExchange{{tx: felicaFrame(FelicaRequestResponse, idm), rx: felicaFrame(FelicaRequestResponse+1, other, 0x00)}},
+		fails:  true,
+	}, {
+		name:   "truncated IDm",
+		call:   mode,
+		script: []scriptedExchange{{tx: felicaFrame(FelicaRequestResponse, idm), rx: felicaFrame(FelicaRequestResponse+1, idm[:4])}},
+		fails:  true,
+	}, {
+		name:   "device error",
+		call:   mode,
+		script: []scriptedExchange{{tx: felicaFrame(FelicaRequestResponse, idm), err: Error(ETIMEOUT)}},
+		err:    Error(ETIMEOUT),
+	}}
+
+	for _, tt := range tests {
+		d := &scriptedInitiator{t: t, script: tt.script}
+		err := tt.call(NewFelicaCard(d, &FelicaTarget{ID: felicaTestIDm}))
+		switch {
+		case tt.fails:
+			if _, ok := err.(FelicaStatusError); err == nil || ok {
+				t.Errorf("%s: got error %v, want a protocol error", tt.name, err)
+			}
+		case err != tt.err:
+			t.Errorf("%s: got error %v, want %v", tt.name, err, tt.err)
+		}
+
+		d.done()
+	}
+}
+
+// Enumerate the nodes of a card up to the end marker 0xffff.
+func TestFelicaSearchServiceCodes(t *testing.T) {
+	idm := felicaTestIDm[:]
+	d := &scriptedInitiator{t: t, script: []scriptedExchange{
+		{tx: felicaFrame(FelicaSearchServiceCode, idm, 0x00, 0x00), rx: felicaFrame(FelicaSearchServiceCode+1, idm, 0x00, 0x00, 0xfe, 0xff)},
+		{tx: felicaFrame(FelicaSearchServiceCode, idm, 0x01, 0x00), rx: felicaFrame(FelicaSearchServiceCode+1, idm, 0x09, 0x10)},
+		{tx: felicaFrame(FelicaSearchServiceCode, idm, 0x02, 0x00), rx: felicaFrame(FelicaSearchServiceCode+1, idm, 0xff, 0xff)},
+	}}
+
+	nodes, err := NewFelicaCard(d, &FelicaTarget{ID: felicaTestIDm}).SearchServiceCodes()
+	if err != nil {
+		t.Fatal("SearchServiceCodes():", err)
+	}
+
+	want := []FelicaNode{{Code: 0x0000, End: 0xfffe, IsArea: true}, {Code: 0x1009, End: 0x1009}}
+	if len(nodes) != len(want) {
+		t.Fatalf("SearchServiceCodes() = %+v, want %+v", nodes, want)
+	}
+
+	for i := range want {
+		if nodes[i] != want[i] {
+			t.Errorf("node %d is %+v, want %+v", i, nodes[i], want[i])
+		}
+	}
+
+	d.done()
+}
@@ -0,0 +1,409 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/binary"
+import "sync"
+import "time"
+
+// System code of NFC Forum Type 3 Tags.
+const FelicaSystemNDEF = 0x12fc
+
+// Service codes of the NDEF data of NFC Forum Type 3 Tags. Both refer to
+// the same blocks.
+const (
+	FelicaServiceNDEFRW = 0x0009 // read/write access without key
+	FelicaServiceNDEFRO = 0x000b // read only access without key
+)
+
+// FelicaMemory is an in-memory block store for FelicaEmulator. Services
+// are identified by their service code: the upper ten bits form the service
+// number, the lower six bits the attribute. Services with the same number
+// share their blocks, so the same data can be made available read/write
+// (attribute 0x09) and read only (attribute 0x0b). Only services accessible
+// without a key (attribute bit 0 set) can be accessed by the emulator.
+// FelicaMemory is safe for concurrent use.
+type FelicaMemory struct {
+	mu     sync.Mutex
+	codes  map[uint16]bool       // registered service codes
+	blocks map[uint16][][16]byte // blocks by service number
+}
+
+// Make a new FelicaMemory without any services.
+func NewFelicaMemory() *FelicaMemory {
+	return &FelicaMemory{
+		codes:  make(map[uint16]bool),
+		blocks: make(map[uint16][][16]byte),
+	}
+}
+
+// Make a new FelicaMemory holding an NFC Forum Type 3 Tag with the NDEF
+// message msg and room for nmaxb blocks of NDEF data. Use it with the
+// system code FelicaSystemNDEF.
+func NewFelicaType3Memory(msg []byte, nmaxb int) *FelicaMemory {
+	if nblocks := (len(msg) + 15) / 16; nmaxb < nblocks {
+		nmaxb = nblocks
+	}
+
+	m := NewFelicaMemory()
+	m.AddService(FelicaServiceNDEFRW, 1+nmaxb)
+	m.AddService(FelicaServiceNDEFRO, 1+nmaxb)
+
+	data := m.blocks[FelicaServiceNDEFRW>>6]
+	for i := 0; i < len(msg); i += 16 {
+		copy(data[1+i/16][:], msg[i:])
+	}
+
+	// attribute information block
+	ai := &data[0]
+	ai[0] = 0x10 // mapping version 1.0
+	ai[1] = 4    // Nbr
+	ai[2] = 1    // Nbw
+	binary.BigEndian.PutUint16(ai[3:5], uint16(nmaxb))
+	ai[10] = 0x01 // RWFlag: read/write
+	ai[11] = byte(len(msg) >> 16)
+	ai[12] = byte(len(msg) >> 8)
+	ai[13] = byte(len(msg))
+
+	sum := 0
+	for _, b := range ai[:14] {
+		sum += int(b)
+	}
+
+	binary.BigEndian.PutUint16(ai[14:16], uint16(sum))
+
+	return m
+}
+
+// Add service code with the given number of blocks. If a service with the
+// same service number already exists, the code refers to the existing
+// blocks and the number of blocks is ignored.
+func (m *FelicaMemory) AddService(code uint16, blocks int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.codes[code] = true
+	if _, ok := m.blocks[code>>6]; !ok {
+		m.blocks[code>>6] = make([][16]byte, blocks)
+	}
+}
+
+// Return block n of service code. Unlike READ WITHOUT ENCRYPTION, this
+// also works for services requiring a key.
+func (m *FelicaMemory) ReadBlock(code uint16, n int) ([16]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flag2 := m.check(code, n)
+	if flag2 != 0 {
+		return [16]byte{}, FelicaStatusError{0xff, flag2}
+	}
+
+	return m.blocks[code>>6][n], nil
+}
+
+// Set block n of service code to data. Unlike WRITE WITHOUT ENCRYPTION,
+// this also works for read only services and services requiring a key.
+func (m *FelicaMemory) WriteBlock(code uint16, n int, data [16]byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	flag2 := m.check(code, n)
+	if flag2 != 0 {
+		return FelicaStatusError{0xff, flag2}
+	}
+
+	m.blocks[code>>6][n] = data
+	return nil
+}
+
+// Check if block n of service code exists and return the status flag2
+// describing the error or 0. m.mu must be held.
+func (m *FelicaMemory) check(code uint16, n int) byte {
+	switch {
+	case !m.codes[code]:
+		return 0xa6 // illegal service code list
+	case n < 0 || n >= len(m.blocks[code>>6]):
+		return 0xa8 // illegal block number
+	default:
+		return 0
+	}
+}
+
+// Check if service code can be accessed without encryption and return the
+// status flag2 describing the error or 0.
+func felicaAccess(code uint16, write bool) byte {
+	if code&0x01 == 0 || write && code&0x02 != 0 {
+		return 0xa5 // access not allowed
+	}
+
+	return 0
+}
+
+// Return the key version of node code for Request Service: 0x0000 for
+// the root area and existing services, 0xffff for all other nodes.
+func (m *FelicaMemory) keyVersion(code uint16) uint16 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if code == 0x0000 || m.codes[code] {
+		return 0x0000
+	}
+
+	return 0xffff
+}
+
+// FelicaEmulator emulates a FeliCa card holding the blocks of a
+// FelicaMemory. The card answers Polling, Request Service, Request Response
+// and Read and Write Without Encryption. Its IDm, PMm and system code are
+// taken from the ID, Pad and SysCode fields of Target.
+type FelicaEmulator struct {
+	Device  TargetDevice  // device used to emulate the card
+	Target  FelicaTarget  // the card emulated
+	Memory  *FelicaMemory // the blocks of the card
+	Timeout time.Duration // see Device.TargetReceiveBytesTimeout()
+}
+
+// Make a new FelicaEmulator using d and m with a default IDm and PMm and
+// the system code FelicaSystemNDEF. The timeout is NoTimeout, so the
+// emulator waits for readers indefinitely.
+func NewFelicaEmulator(d TargetDevice, m *FelicaMemory) *FelicaEmulator {
+	return &FelicaEmulator{
+		Device: d,
+		Memory: m,
+		Target: FelicaTarget{
+			Len:     18,
+			ResCode: FelicaPolling + 1,
+			ID:      [8]byte{0x02, 0xfe, 0x00, 0xb0, 0x0b, 0x00, 0x00, 0x01},
+			Pad:     [8]byte{0x00, 0xf1, 0x00, 0x00, 0x00, 0x01, 0x43, 0x00},
+			SysCode: [2]byte{FelicaSystemNDEF >> 8, FelicaSystemNDEF & 0xff},
+			Baud:    Nbr212,
+		},
+	}
+}
+
+// Emulate the card for one session: wait for a reader with TargetInit() and
+// answer its commands until it releases the card, in which case nil is
+// returned. Call Run() in a loop to serve one reader after another.
+func (e *FelicaEmulator) Run() error {
+	timeout, err := timeoutMillis(e.Timeout)
+	if err != nil {
+		return err
+	}
+
+	rx := make([]byte, felicaMaxFrame)
+	n, _, err := e.Device.TargetInit(&e.Target, rx, timeout)
+	for err == nil {
+		if tx := e.Process(rx[:n]); tx != nil {
+			if _, err = e.Device.TargetSendBytes(tx, timeout); err != nil {
+				break
+			}
+		}
+
+		n, err = e.Device.TargetReceiveBytes(rx, timeout)
+	}
+
+	if err == Error(ETGRELEASED) {
+		err = nil
+	}
+
+	return err
+}
+
+// Process a command frame with leading length byte and return the response
+// frame or nil if the card does not answer, e.g. because the command is
+// addressed to another card.
+func (e *FelicaEmulator) Process(cmd []byte) []byte {
+	if len(cmd) < 2 || int(cmd[0]) != len(cmd) {
+		return nil
+	}
+
+	code, params := cmd[1], cmd[2:]
+	var res []byte
+	if code == FelicaPolling {
+		res = e.polling(params)
+	} else {
+		if len(params) < 8 || string(params[:8]) != string(e.Target.ID[:]) {
+			return nil
+		}
+
+		params = params[8:]
+		switch code {
+		case FelicaRequestService:
+			res = e.requestService(params)
+		case FelicaRequestResponse:
+			res = []byte{0x00}
+		case FelicaReadWithoutEncryption:
+			res = e.read(params)
+		case FelicaWriteWithoutEncryption:
+			res = e.write(params)
+		}
+
+		if res != nil {
+			res = append(append([]byte(nil), e.Target.ID[:]...), res...)
+		}
+	}
+
+	if res == nil {
+		return nil
+	}
+
+	return append([]byte{byte(2 + len(res)), code + 1}, res...)
+}
+
+// Handle Polling.
+func (e *FelicaEmulator) polling(params []byte) []byte {
+	if len(params) != 4 {
+		return nil
+	}
+
+	// each byte of the system code may be a wildcard
+	for i := 0; i < 2; i++ {
+		if params[i] != 0xff && params[i] != e.Target.SysCode[i] {
+			return nil
+		}
+	}
+
+	res := append(append([]byte(nil), e.Target.ID[:]...), e.Target.Pad[:]...)
+	switch params[2] {
+	case FelicaRequestSysCode:
+		res = append(res, e.Target.SysCode[:]...)
+	case FelicaRequestCommunication:
+		res = append(res, 0x00, 0x83) // 212 and 424 kbps, automatic detection
+	}
+
+	return res
+}
+
+// Handle Request Service.
+func (e *FelicaEmulator) requestService(params []byte) []byte {
+	if len(params) < 1 || params[0] < 1 || params[0] > 32 || len(params) != 1+2*int(params[0]) {
+		return nil
+	}
+
+	res := []byte{params[0]}
+	for i := 0; i < int(params[0]); i++ {
+		v := e.Memory.keyVersion(binary.LittleEndian.Uint16(params[1+2*i:]))
+		res = append(res, byte(v), byte(v>>8))
+	}
+
+	return res
+}
+
+// Parse the service code list and block list of Read and Write Without
+// Encryption. Returns the services and blocks, the remaining parameters
+// and the status flags in case of error.
+func felicaParseBlockParams(params []byte) (services []uint16, blocks []FelicaBlock, rest []byte, flag1, flag2 byte) {
+	if len(params) < 1 || params[0] < 1 || params[0] > 16 {
+		return nil, nil, nil, 0xff, 0xa1 // illegal number of services
+	}
+
+	n := int(params[0])
+	if len(params) < 2+2*n {
+		return nil, nil, nil, 0xff, 0xa2 // illegal command packet
+	}
+
+	services = make([]uint16, n)
+	for i := range services {
+		services[i] = binary.LittleEndian.Uint16(params[1+2*i:])
+	}
+
+	params = params[1+2*n:]
+	if params[0] < 1 || int(params[0]) > FelicaMaxBlocks {
+		return nil, nil, nil, 0xff, 0xa2
+	}
+
+	blocks, rest, err := parseFelicaBlockList(params[1:], int(params[0]))
+	if err != nil {
+		return nil, nil, nil, 0xff, 0xa2
+	}
+
+	for i, b := range blocks {
+		if b.Service >= len(services) {
+			return nil, nil, nil, byte(i + 1), 0xa3 // illegal block list
+		}
+
+		if b.AccessMode != 0 {
+			return nil, nil, nil, byte(i + 1), 0xa7 // illegal access mode
+		}
+	}
+
+	return services, blocks, rest, 0, 0
+}
+
+// Handle Read Without Encryption.
+func (e *FelicaEmulator) read(params []byte) []byte {
+	services, blocks, rest, flag1, flag2 := felicaParseBlockParams(params)
+	if flag2 == 0 && len(rest) != 0 {
+		flag1, flag2 = 0xff, 0xa2
+	}
+
+	if flag2 != 0 {
+		return []byte{flag1, flag2}
+	}
+
+	m := e.Memory
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	res := []byte{0x00, 0x00, byte(len(blocks))}
+	for i, b := range blocks {
+		code := services[b.Service]
+		if flag2 = felicaAccess(code, false); flag2 != 0 {
+			return []byte{byte(i + 1), flag2}
+		}
+
+		if flag2 = m.check(code, int(b.Number)); flag2 != 0 {
+			return []byte{byte(i + 1), flag2}
+		}
+
+		res = append(res, m.blocks[code>>6][b.Number][:]...)
+	}
+
+	return res
+}
+
+// Handle Write Without Encryption. Either all blocks are written or none.
+func (e *FelicaEmulator) write(params []byte) []byte {
+	services, blocks, rest, flag1, flag2 := felicaParseBlockParams(params)
+	if flag2 == 0 && len(rest) != 16*len(blocks) {
+		flag1, flag2 = 0xff, 0xa2
+	}
+
+	if flag2 != 0 {
+		return []byte{flag1, flag2}
+	}
+
+	m := e.Memory
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, b := range blocks {
+		code := services[b.Service]
+		if flag2 = felicaAccess(code, true); flag2 != 0 {
+			return []byte{byte(i + 1), flag2}
+		}
+
+		if flag2 = m.check(code, int(b.Number)); flag2 != 0 {
+			return []byte{byte(i + 1), flag2}
+		}
+	}
+
+	for i, b := range blocks {
+		copy(m.blocks[services[b.Service]>>6][b.Number][:], rest[16*i:])
+	}
+
+	return []byte{0x00, 0x00}
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "testing"
+
+// An Initiator passing frames directly to a FelicaEmulator.
+type felicaLoopback struct {
+	e *FelicaEmulator
+}
+
+func (l felicaLoopback) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (int, error) {
+	res := l.e.Process(tx)
+	if res == nil {
+		return 0, Error(ETIMEOUT)
+	}
+
+	return copy(rx, res), nil
+}
+
+func (l felicaLoopback) InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxPar []byte) (int, error) {
+	return 0, Error(EDEVNOTSUPP)
+}
+
+// Read and write an emulated Type 3 Tag through FelicaCard.
+func TestFelicaEmulator(t *testing.T) {
+	msg := bytes.Repeat([]byte("NDEF"), 5)
+	e := NewFelicaEmulator(nil, NewFelicaType3Memory(msg, 4))
+	card := NewFelicaCard(felicaLoopback{e}, &e.Target)
+
+	p, err := card.Polling(FelicaSystemNDEF, FelicaRequestSysCode, 0)
+	if err != nil {
+		t.Fatal("Polling():", err)
+	}
+
+	if p.IDm != e.Target.ID || p.PMm != e.Target.Pad || !bytes.Equal(p.RequestData, []byte{0x12, 0xfc}) {
+		t.Errorf("unexpected polling response %+v", p)
+	}
+
+	if _, err = card.Polling(0x88b4, FelicaRequestNone, 0); err != Error(ETIMEOUT) {
+		t.Errorf("Polling() for other system: got error %v, want %v", err, Error(ETIMEOUT))
+	}
+
+	versions, err := card.RequestService([]uint16{0x0000, FelicaServiceNDEFRO, 0x1009})
+	if err != nil {
+		t.Fatal("RequestService():", err)
+	}
+
+	if want := []uint16{0x0000, 0x0000, 0xffff}; !equalUint16s(versions, want) {
+		t.Errorf("RequestService() = %04x, want %04x", versions, want)
+	}
+
+	ro := []uint16{FelicaServiceNDEFRO}
+	rw := []uint16{FelicaServiceNDEFRW}
+	data, err := card.ReadWithoutEncryption(ro, []FelicaBlock{{Number: 0}, {Number: 1}, {Number: 2}})
+	if err != nil {
+		t.Fatal("ReadWithoutEncryption():", err)
+	}
+
+	if data[0][0] != 0x10 || data[0][4] != 4 || data[0][13] != byte(len(msg)) {
+		t.Errorf("unexpected attribute information block % x", data[0])
+	}
+
+	if got := append(data[1][:], data[2][:4]...); !bytes.Equal(got, msg) {
+		t.Errorf("read message % x, want % x", got, msg)
+	}
+
+	block := [16]byte{'h', 'e', 'l', 'l', 'o'}
+	err = card.WriteWithoutEncryption(ro, []FelicaBlock{{Number: 1}}, [][16]byte{block})
+	if serr, ok := err.(FelicaStatusError); !ok || serr.Flag2 != 0xa5 {
+		t.Errorf("write to read only service: got error %v, want access not allowed", err)
+	}
+
+	// access is checked before the block number
+	err = card.WriteWithoutEncryption(ro, []FelicaBlock{{Number: 5}}, [][16]byte{block})
+	if serr, ok := err.(FelicaStatusError); !ok || serr.Flag2 != 0xa5 {
+		t.Errorf("write beyond end of read only service: got error %v, want access not allowed", err)
+	}
+
+	_, err = card.ReadWithoutEncryption([]uint16{0x1008}, []FelicaBlock{{Number: 0}})
+	if serr, ok := err.(FelicaStatusError); !ok || serr.Flag2 != 0xa5 {
+		t.Errorf("read of missing encrypted service: got error %v, want access not allowed", err)
+	}
+
+	// block number 5 is out of range, so nothing must be written
+	err = card.WriteWithoutEncryption(rw, []FelicaBlock{{Number: 1}, {Number: 5}}, [][16]byte{block, block})
+	if serr, ok := err.(FelicaStatusError); !ok || serr.Flag1 != 2 || serr.Flag2 != 0xa8 {
+		t.Errorf("write beyond end: got error %v, want illegal block number", err)
+	}
+
+	if err = card.WriteWithoutEncryption(rw, []FelicaBlock{{Number: 0x100}}, [][16]byte{block}); err == nil {
+		t.Errorf("write to 3 byte block number beyond end succeeded")
+	}
+
+	if err = card.WriteWithoutEncryption(rw, []FelicaBlock{{Number: 4}}, [][16]byte{block}); err != nil {
+		t.Fatal("WriteWithoutEncryption():", err)
+	}
+
+	if b, _ := e.Memory.ReadBlock(FelicaServiceNDEFRO, 4); b != block {
+		t.Errorf("block 4 is % x, want % x", b, block)
+	}
+}
+
+func equalUint16s(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,324 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+// The code in this file implements the MAC and authentication scheme of
+// FeliCa Lite-S as described in the FeliCa Lite-S User's Manual. FeliCa
+// Lite-S stores 8 byte quantities in the reverse byte order of what DES
+// expects, so all keys, challenges and data are swapped in groups of 8
+// bytes before they go into the cipher. All values passed to and returned
+// from the functions in this file are in the byte order used on the card.
+
+package nfc
+
+import "crypto/cipher"
+import "crypto/des"
+import "crypto/rand"
+import "crypto/subtle"
+import "errors"
+
+// FeliCa Lite-S memory blocks.
+const (
+	FelicaLiteSPad0    = 0x00 // first of 14 blocks of user data S_PAD0 to S_PAD13
+	FelicaLiteREG      = 0x0e // register block
+	FelicaLiteRC       = 0x80 // random challenge, write only
+	FelicaLiteMAC      = 0x81 // MAC of FeliCa Lite, read only
+	FelicaLiteID       = 0x82 // card identification
+	FelicaLiteDID      = 0x83 // IDm and PMm
+	FelicaLiteSERC     = 0x84 // service code
+	FelicaLiteSYSC     = 0x85 // system code
+	FelicaLiteCKV      = 0x86 // card key version
+	FelicaLiteCK       = 0x87 // card key, write only
+	FelicaLiteMC       = 0x88 // memory configuration
+	FelicaLiteWCNT     = 0x90 // write count
+	FelicaLiteMACA     = 0x91 // MAC_A
+	FelicaLiteSTATE    = 0x92 // authentication state
+	FelicaLiteCRCCheck = 0xa0 // CRC check
+)
+
+// Service codes of FeliCa Lite-S.
+const (
+	FelicaLiteServiceRW = 0x0009 // read/write access
+	FelicaLiteServiceRO = 0x000b // read only access
+)
+
+// Maximum number of data blocks that can be read with MAC_A at once.
+const FelicaLiteMaxMACBlocks = 3
+
+// Returned if a MAC computed by the card does not match the expected one.
+// This indicates a card with a different card key, e.g. a clone.
+var ErrFelicaMAC = errors.New("FeliCa: MAC verification failed")
+
+// Reverse each group of 8 bytes in b. The length of b must be a multiple
+// of 8.
+func felicaSwap8(b []byte) {
+	for i := 0; i+8 <= len(b); i += 8 {
+		for j := 0; j < 4; j++ {
+			b[i+j], b[i+7-j] = b[i+7-j], b[i+j]
+		}
+	}
+}
+
+// Make a two key triple DES cipher from key, which must be in DES byte
+// order. If flip is set, the halves of the key are exchanged.
+func felicaLiteCipher(key [16]byte, flip bool) cipher.Block {
+	var k [24]byte
+	if flip {
+		copy(k[0:8], key[8:16])
+		copy(k[8:16], key[0:8])
+	} else {
+		copy(k[0:16], key[:])
+	}
+
+	copy(k[16:24], k[0:8])
+
+	block, err := des.NewTripleDESCipher(k[:])
+	if err != nil {
+		// cannot happen, the key has the right length
+		panic(err)
+	}
+
+	return block
+}
+
+// Generate the session key SK from the card key ck and the random
+// challenge rc written to block FelicaLiteRC.  SK is the triple DES CBC
+// encryption of RC under CK with a zero initialisation vector.
+func FelicaLiteSessionKey(ck, rc [16]byte) [16]byte {
+	felicaSwap8(ck[:])
+	felicaSwap8(rc[:])
+
+	var sk [16]byte
+	var iv [8]byte
+	cbc := cipher.NewCBCEncrypter(felicaLiteCipher(ck, false), iv[:])
+	cbc.CryptBlocks(sk[:], rc[:])
+	felicaSwap8(sk[:])
+
+	return sk
+}
+
+// Compute a MAC over data, whose length must be a multiple of 8, using
+// session key sk and the first half of rc as initialisation vector. The
+// MAC is the last block of the triple DES CBC encryption of data.
+func felicaLiteMAC(sk, rc [16]byte, flip bool, data []byte) (mac [8]byte) {
+	felicaSwap8(sk[:])
+	felicaSwap8(rc[:])
+
+	buf := make([]byte, len(data))
+	copy(buf, data)
+	felicaSwap8(buf)
+
+	cbc := cipher.NewCBCEncrypter(felicaLiteCipher(sk, flip), rc[0:8])
+	cbc.CryptBlocks(buf, buf)
+
+	copy(mac[:], buf[len(buf)-8:])
+	felicaSwap8(mac[:])
+
+	return
+}
+
+// Compute MAC_A for reading blocks with contents data, where data holds
+// the 16 bytes of each block in blocks. The block number of MAC_A itself is
+// appended to blocks implicitly. At most FelicaLiteMaxMACBlocks blocks can
+// be covered by one MAC_A.
+func FelicaLiteReadMAC(sk, rc [16]byte, blocks []uint16, data []byte) ([8]byte, error) {
+	if len(blocks) == 0 || len(blocks) > FelicaLiteMaxMACBlocks || len(data) != 16*len(blocks) {
+		return [8]byte{}, Error(EINVARG)
+	}
+
+	// block number information: numbers of all blocks read including
+	// MAC_A, two bytes each, padded with 0xff
+	msg := make([]byte, 0, 8+len(data))
+	for _, b := range blocks {
+		msg = append(msg, byte(b), byte(b>>8))
+	}
+
+	msg = append(msg, FelicaLiteMACA, 0x00)
+	for len(msg) < 8 {
+		msg = append(msg, 0xff)
+	}
+
+	msg = append(msg, data...)
+
+	return felicaLiteMAC(sk, rc, false, msg), nil
+}
+
+// Compute MAC_A for writing data to block with write count wcnt. The MAC
+// for writing is computed with the halves of the session key exchanged.
+func FelicaLiteWriteMAC(sk, rc [16]byte, wcnt uint32, block uint16, data [16]byte) [8]byte {
+	msg := []byte{
+		byte(wcnt), byte(wcnt >> 8), byte(wcnt >> 16), 0x00,
+		byte(block), byte(block >> 8), FelicaLiteMACA, 0x00,
+	}
+
+	msg = append(msg, data[:]...)
+
+	return felicaLiteMAC(sk, rc, true, msg)
+}
+
+// An authenticated session with a FeliCa Lite-S card, see
+// FelicaCard.LiteAuthenticate().
+type FelicaLiteSession struct {
+	Card *FelicaCard
+	sk   [16]byte
+	rc   [16]byte
+}
+
+// Read a single block of a FeliCa Lite-S card without MAC.
+func (f *FelicaCard) liteRead(block uint16) ([16]byte, error) {
+	data, err := f.ReadWithoutEncryption(
+		[]uint16{FelicaLiteServiceRO},
+		[]FelicaBlock{{Number: block}})
+	if err != nil {
+		return [16]byte{}, err
+	}
+
+	return data[0], nil
+}
+
+// Write a single block of a FeliCa Lite-S card without MAC.
+func (f *FelicaCard) liteWrite(block uint16, data [16]byte) error {
+	return f.WriteWithoutEncryption(
+		[]uint16{FelicaLiteServiceRW},
+		[]FelicaBlock{{Number: block}},
+		[][16]byte{data})
+}
+
+// Perform internal authentication of a FeliCa Lite-S card with card key
+// ck: write a random challenge to block FelicaLiteRC, derive the session
+// key and read the ID and CKV blocks with MAC_A. If the MAC does not match,
+// the card does not know ck and ErrFelicaMAC is returned. On success, the
+// returned session can be used to read and write blocks with MAC_A.
+func (f *FelicaCard) LiteAuthenticate(ck [16]byte) (*FelicaLiteSession, error) {
+	var rc [16]byte
+	if _, err := rand.Read(rc[:]); err != nil {
+		return nil, err
+	}
+
+	if err := f.liteWrite(FelicaLiteRC, rc); err != nil {
+		return nil, err
+	}
+
+	s := &FelicaLiteSession{Card: f, sk: FelicaLiteSessionKey(ck, rc), rc: rc}
+	if _, err := s.ReadWithMAC(FelicaLiteID, FelicaLiteCKV); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// Read up to FelicaLiteMaxMACBlocks blocks together with MAC_A and verify
+// the MAC. Returns ErrFelicaMAC if verification fails.
+func (s *FelicaLiteSession) ReadWithMAC(blocks ...uint16) ([][16]byte, error) {
+	if len(blocks) == 0 || len(blocks) > FelicaLiteMaxMACBlocks {
+		return nil, Error(EINVARG)
+	}
+
+	list := make([]FelicaBlock, len(blocks)+1)
+	for i, b := range blocks {
+		list[i].Number = b
+	}
+
+	list[len(blocks)].Number = FelicaLiteMACA
+
+	data, err := s.Card.ReadWithoutEncryption([]uint16{FelicaLiteServiceRO}, list)
+	if err != nil {
+		return nil, err
+	}
+
+	msg := make([]byte, 0, 16*len(blocks))
+	for _, d := range data[:len(blocks)] {
+		msg = append(msg, d[:]...)
+	}
+
+	mac, err := FelicaLiteReadMAC(s.sk, s.rc, blocks, msg)
+	if err != nil {
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare(mac[:], data[len(blocks)][0:8]) != 1 {
+		return nil, ErrFelicaMAC
+	}
+
+	return data[:len(blocks)], nil
+}
+
+// Write data to block together with MAC_A. The card only accepts the write
+// if the MAC is valid, i.e. if the session key is correct. The current
+// write count is read from block FelicaLiteWCNT first.
+func (s *FelicaLiteSession) WriteWithMAC(block uint16, data [16]byte) error {
+	wblock, err := s.Card.liteRead(FelicaLiteWCNT)
+	if err != nil {
+		return err
+	}
+
+	wcnt := uint32(wblock[0]) | uint32(wblock[1])<<8 | uint32(wblock[2])<<16
+	mac := FelicaLiteWriteMAC(s.sk, s.rc, wcnt, block, data)
+
+	var maca [16]byte
+	copy(maca[0:8], mac[:])
+	copy(maca[8:11], wblock[0:3])
+
+	return s.Card.WriteWithoutEncryption(
+		[]uint16{FelicaLiteServiceRW},
+		[]FelicaBlock{{Number: block}, {Number: FelicaLiteMACA}},
+		[][16]byte{data, maca})
+}
+
+// Perform external authentication: set the EXT_AUTH flag in block
+// FelicaLiteSTATE by writing it with MAC_A, then read it back with MAC_A
+// to confirm. Once externally authenticated, the card permits writes to
+// blocks configured to require it.
+func (s *FelicaLiteSession) ExternalAuthenticate() error {
+	var state [16]byte
+	state[0] = 0x01
+	if err := s.WriteWithMAC(FelicaLiteSTATE, state); err != nil {
+		return err
+	}
+
+	data, err := s.ReadWithMAC(FelicaLiteSTATE)
+	if err != nil {
+		return err
+	}
+
+	if data[0][0] != 0x01 {
+		return errors.New("FeliCa: external authentication failed")
+	}
+
+	return nil
+}
+
+// Provision card key ck and card key version ckv on a FeliCa Lite-S card
+// using plain writes. This only works while the memory configuration
+// permits writing CK and CKV without MAC, typically during issuance. Use
+// FelicaLiteSession.WriteWithMAC() to change the keys of a card that
+// requires MAC_A for these blocks.
+func (f *FelicaCard) LiteProvisionCardKey(ck [16]byte, ckv uint16) error {
+	var v [16]byte
+	v[0] = byte(ckv)
+	v[1] = byte(ckv >> 8)
+
+	if err := f.liteWrite(FelicaLiteCKV, v); err != nil {
+		return err
+	}
+
+	return f.liteWrite(FelicaLiteCK, ck)
+}
+
+// Check whether a FeliCa Lite-S card has card key ck by performing
+// internal authentication. Returns nil if it does, ErrFelicaMAC if it does
+// not, and some other error if communication fails.
+func (f *FelicaCard) LiteVerifyCardKey(ck [16]byte) error {
+	_, err := f.LiteAuthenticate(ck)
+	return err
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "testing"
+
+// The test vectors in this file were computed with the two key triple DES
+// CBC of OpenSSL (des-ede-cbc) following the procedure of the FeliCa Lite-S
+// User's Manual, independently of the code under test.
+var (
+	felicaLiteTestCK = [16]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}
+	felicaLiteTestRC = [16]byte{0x10, 0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18, 0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f}
+	felicaLiteTestSK = [16]byte{0x17, 0xa1, 0x88, 0x77, 0x06, 0xa1, 0xbd, 0x40, 0x38, 0xc6, 0x4b, 0x8e, 0x67, 0x9d, 0x65, 0x7b}
+)
+
+func TestFelicaLiteSessionKey(t *testing.T) {
+	if sk := FelicaLiteSessionKey(felicaLiteTestCK, felicaLiteTestRC); sk != felicaLiteTestSK {
+		t.Errorf("FelicaLiteSessionKey() = % x, want % x", sk, felicaLiteTestSK)
+	}
+}
+
+func TestFelicaLiteMAC(t *testing.T) {
+	// reading ID and CKV as done by LiteAuthenticate()
+	data := make([]byte, 32)
+	for i := 0; i < 16; i++ {
+		data[i] = byte(0x20 + i)
+	}
+
+	data[16] = 0x01
+
+	mac, err := FelicaLiteReadMAC(felicaLiteTestSK, felicaLiteTestRC, []uint16{FelicaLiteID, FelicaLiteCKV}, data)
+	if want := [8]byte{0x1b, 0x00, 0xb7, 0x74, 0x66, 0xe1, 0xa3, 0xa3}; err != nil || mac != want {
+		t.Errorf("FelicaLiteReadMAC() = % x, %v, want % x", mac, err, want)
+	}
+
+	if _, err = FelicaLiteReadMAC(felicaLiteTestSK, felicaLiteTestRC, []uint16{0, 1, 2, 3}, make([]byte, 64)); err != Error(EINVARG) {
+		t.Errorf("FelicaLiteReadMAC() of 4 blocks: got error %v, want %v", err, Error(EINVARG))
+	}
+
+	// setting EXT_AUTH at write count 0x030405
+	mac = FelicaLiteWriteMAC(felicaLiteTestSK, felicaLiteTestRC, 0x030405, FelicaLiteSTATE, [16]byte{0x01})
+	if want := [8]byte{0x27, 0x9c, 0x96, 0xef, 0xb5, 0x69, 0xc7, 0x62}; mac != want {
+		t.Errorf("FelicaLiteWriteMAC() = % x, want % x", mac, want)
+	}
+}
+
+// A simulated FeliCa Lite-S card answering Read and Write Without
+// Encryption, computing MAC_A and checking it for writes.
+type felicaLiteCard struct {
+	ck     [16]byte
+	sk, rc [16]byte
+	wcnt   uint32
+	blocks map[uint16][16]byte
+}
+
+func (c *felicaLiteCard) transceive(tx []byte) ([]byte, error) {
+	if len(tx) < 11 || int(tx[0]) != len(tx) || tx[10] != 1 {
+		return nil, Error(EIO)
+	}
+
+	idm, params := tx[2:10], tx[13:]
+	blocks, data, err := parseFelicaBlockList(params[1:], int(params[0]))
+	if err != nil {
+		return nil, err
+	}
+
+	switch tx[1] {
+	case FelicaReadWithoutEncryption:
+		res := []byte{0x00, 0x00, byte(len(blocks))}
+		var numbers []uint16
+		var msg []byte
+		for _, b := range blocks {
+			var block [16]byte
+			switch b.Number {
+			case FelicaLiteMACA:
+				mac, _ := FelicaLiteReadMAC(c.sk, c.rc, numbers, msg)
+				copy(block[:], mac[:])
+			case FelicaLiteWCNT:
+				block = [16]byte{byte(c.wcnt), byte(c.wcnt >> 8), byte(c.wcnt >> 16)}
+			default:
+				block = c.blocks[b.Number]
+			}
+
+			numbers = append(numbers, b.Number)
+			msg = append(msg, block[:]...)
+			res = append(res, block[:]...)
+		}
+
+		return felicaFrame(FelicaReadWithoutEncryption+1, idm, res...), nil
+
+	case FelicaWriteWithoutEncryption:
+		var block [16]byte
+		copy(block[:], data)
+		if len(blocks) == 2 && blocks[1].Number == FelicaLiteMACA {
+			mac := FelicaLiteWriteMAC(c.sk, c.rc, c.wcnt, blocks[0].Number, block)
+			if !bytes.Equal(data[16:24], mac[:]) {
+				return felicaFrame(FelicaWriteWithoutEncryption+1, idm, 0x02, 0xa9), nil
+			}
+		}
+
+		if blocks[0].Number == FelicaLiteRC {
+			c.rc = block
+			c.sk = FelicaLiteSessionKey(c.ck, block)
+		}
+
+		c.blocks[blocks[0].Number] = block
+		c.wcnt++
+
+		return felicaFrame(FelicaWriteWithoutEncryption+1, idm, 0x00, 0x00), nil
+	}
+
+	return nil, Error(EIO)
+}
+
+func TestFelicaLiteSession(t *testing.T) {
+	card := &felicaLiteCard{ck: felicaLiteTestCK, wcnt: 0x1234, blocks: map[uint16][16]byte{
+		FelicaLiteID:  {0x20, 0x21, 0x22},
+		FelicaLiteCKV: {0x01},
+	}}
+	f := NewFelicaCard(initiatorFunc(card.transceive), &FelicaTarget{ID: felicaTestIDm})
+
+	if _, err := f.LiteAuthenticate([16]byte{0xff}); err != ErrFelicaMAC {
+		t.Errorf("LiteAuthenticate() with wrong key: got error %v, want %v", err, ErrFelicaMAC)
+	}
+
+	s, err := f.LiteAuthenticate(felicaLiteTestCK)
+	if err != nil {
+		t.Fatal("LiteAuthenticate():", err)
+	}
+
+	data := [16]byte{'h', 'e', 'l', 'l', 'o'}
+	if err = s.WriteWithMAC(FelicaLiteSPad0+1, data); err != nil {
+		t.Fatal("WriteWithMAC():", err)
+	}
+
+	if card.blocks[FelicaLiteSPad0+1] != data {
+		t.Errorf("card holds % x, want % x", card.blocks[FelicaLiteSPad0+1], data)
+	}
+
+	read, err := s.ReadWithMAC(FelicaLiteID, FelicaLiteSPad0+1)
+	if err != nil {
+		t.Fatal("ReadWithMAC():", err)
+	}
+
+	if read[0] != card.blocks[FelicaLiteID] || read[1] != data {
+		t.Errorf("ReadWithMAC() = % x", read)
+	}
+
+	// the card refuses writes with a MAC made with the wrong session key
+	s.sk[0] ^= 0xff
+	err = s.WriteWithMAC(FelicaLiteSPad0+2, data)
+	if serr, ok := err.(FelicaStatusError); !ok || serr.Flag2 != 0xa9 {
+		t.Errorf("WriteWithMAC() with wrong session key: got error %v, want data write failure", err)
+	}
+
+	if _, err = s.ReadWithMAC(FelicaLiteID); err != ErrFelicaMAC {
+		t.Errorf("ReadWithMAC() with wrong session key: got error %v, want %v", err, ErrFelicaMAC)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "errors"
+import "math/bits"
+
+// Frame is a raw ISO14443a frame for the bit-level functions
+// Device.InitiatorTransceiveBits(), Device.TargetSendBits() and
+// Device.TargetTransceiveBits(). It keeps the data bytes, a parity bit for
+// each byte and the length of the frame in bits (not counting parity bits)
+// consistent. Parity bits are computed automatically (odd parity), but
+// can be overridden with SetParity(), e.g. for the encrypted parity bits of
+// MIFARE Classic (Crypto1). A frame whose length is not a multiple of 8
+// ends in a partial byte without parity bit, such as the 7 bit short frames
+// REQA and WUPA.
+//
+// For the parity bits to be sent as given, HandleParity must be disabled
+// with Device.SetPropertyBool(); likewise, HandleCRC must be disabled when
+// appending the CRC with AppendCRCA() or AppendCRCB().
+type Frame struct {
+	data   []byte
+	parity []byte
+	nbits  uint
+}
+
+// Compute the odd parity bit of b.
+func oddParity(b byte) byte {
+	return byte(^bits.OnesCount8(b) & 1)
+}
+
+// Make a new frame holding data with odd parity.
+func NewFrame(data ...byte) *Frame {
+	f := &Frame{}
+	f.Append(data...)
+	return f
+}
+
+// Make a new 7 bit short frame such as REQA (0x26) or WUPA (0x52). The most
+// significant bit of b is ignored.
+func NewShortFrame(b byte) *Frame {
+	return &Frame{data: []byte{b & 0x7f}, parity: []byte{0}, nbits: 7}
+}
+
+// Make a frame from the results of Device.InitiatorTransceiveBits() or
+// Device.TargetTransceiveBits(): the data rx, the parity bits rxPar and the
+// length n in bits. The slices are copied. The parity bits are kept as
+// received, see ParityOK().
+func FrameFromBits(rx, rxPar []byte, n uint) (*Frame, error) {
+	nbytes := (n + 7) / 8
+	if uint(len(rx)) < nbytes || uint(len(rxPar)) < nbytes {
+		return nil, errors.New("slice shorter than specified bit count")
+	}
+
+	f := &Frame{
+		data:   append([]byte(nil), rx[:nbytes]...),
+		parity: append([]byte(nil), rxPar[:nbytes]...),
+		nbits:  n,
+	}
+
+	if n%8 != 0 {
+		// mask out garbage beyond the last bit
+		f.data[nbytes-1] &= 1<<(n%8) - 1
+		f.parity[nbytes-1] = 0
+	}
+
+	return f, nil
+}
+
+// Append data bytes with odd parity. This fails with EINVARG if the frame
+// ends in a partial byte.
+func (f *Frame) Append(data ...byte) error {
+	if f.nbits%8 != 0 {
+		return Error(EINVARG)
+	}
+
+	for _, b := range data {
+		f.data = append(f.data, b)
+		f.parity = append(f.parity, oddParity(b))
+	}
+
+	f.nbits += 8 * uint(len(data))
+	return nil
+}
+
+// Append the ISO14443a CRC of the frame. This fails with EINVARG if the
+// frame ends in a partial byte.
+func (f *Frame) AppendCRCA() error {
+	crc := ISO14443aCRC(f.data)
+	return f.Append(crc[:]...)
+}
+
+// Append the ISO14443b CRC of the frame. This fails with EINVARG if the
+// frame ends in a partial byte.
+func (f *Frame) AppendCRCB() error {
+	crc := ISO14443bCRC(f.data)
+	return f.Append(crc[:]...)
+}
+
+// Set the parity bit of byte i to p (0 or 1) instead of the odd parity
+// computed by Append(). Bytes past the end of the frame are ignored.
+func (f *Frame) SetParity(i int, p byte) {
+	if i >= 0 && i < len(f.data) && uint(i+1)*8 <= f.nbits {
+		f.parity[i] = p & 1
+	}
+}
+
+// Return the data bytes of the frame. If the frame ends in a partial byte,
+// only the low bits of the last byte are used.
+func (f *Frame) Bytes() []byte {
+	return f.data
+}
+
+// Return the parity bits of the frame, one per data byte.
+func (f *Frame) Parity() []byte {
+	return f.parity
+}
+
+// Return the length of the frame in bits, not counting parity bits.
+func (f *Frame) Bits() uint {
+	return f.nbits
+}
+
+// Return data, parity bits and bit length of the frame as expected by
+// Device.InitiatorTransceiveBits() and Device.TargetSendBits(). The
+// returned slices must not be modified.
+func (f *Frame) Raw() (data, parity []byte, n uint) {
+	return f.data, f.parity, f.nbits
+}
+
+// Check if each complete byte of the frame has odd parity.
+func (f *Frame) ParityOK() bool {
+	for i := uint(0); i < f.nbits/8; i++ {
+		if f.parity[i] != oddParity(f.data[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Check if the frame ends in a valid ISO14443a CRC.
+func (f *Frame) CRCAOK() bool {
+	n := len(f.data)
+	if f.nbits%8 != 0 || n < 2 {
+		return false
+	}
+
+	crc := ISO14443aCRC(f.data[:n-2])
+	return crc[0] == f.data[n-2] && crc[1] == f.data[n-1]
+}
+
+// Check if the frame ends in a valid ISO14443b CRC.
+func (f *Frame) CRCBOK() bool {
+	n := len(f.data)
+	if f.nbits%8 != 0 || n < 2 {
+		return false
+	}
+
+	crc := ISO14443bCRC(f.data[:n-2])
+	return crc[0] == f.data[n-2] && crc[1] == f.data[n-1]
+}
+
+// Send tx with d.InitiatorTransceiveBits() and return the response frame,
+// which may be up to rxLen bytes long.
+func TransceiveFrame(d Initiator, tx *Frame, rxLen int) (*Frame, error) {
+	if len(tx.data) == 0 || rxLen < 1 {
+		return nil, Error(EINVARG)
+	}
+
+	rx := make([]byte, rxLen)
+	rxPar := make([]byte, rxLen)
+	n, err := d.InitiatorTransceiveBits(tx.data, tx.parity, tx.nbits, rx, rxPar)
+	if err != nil {
+		return nil, err
+	}
+
+	return FrameFromBits(rx, rxPar, uint(n))
+}
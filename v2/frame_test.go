@@ -0,0 +1,67 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "testing"
+
+func TestFrame(t *testing.T) {
+	reqa := NewShortFrame(0x26)
+	if data, par, n := reqa.Raw(); n != 7 || !bytes.Equal(data, []byte{0x26}) || len(par) != 1 {
+		t.Errorf("NewShortFrame(0x26).Raw() = % x, % x, %d", data, par, n)
+	}
+
+	if err := reqa.Append(0x00); err != Error(EINVARG) {
+		t.Errorf("Append() to short frame: got error %v, want %v", err, Error(EINVARG))
+	}
+
+	f := NewFrame(0x50, 0x00)
+	if err := f.AppendCRCA(); err != nil {
+		t.Fatal("AppendCRCA():", err)
+	}
+
+	if !bytes.Equal(f.Bytes(), []byte{0x50, 0x00, 0x57, 0xcd}) || f.Bits() != 32 {
+		t.Errorf("HLTA frame is % x (%d bits)", f.Bytes(), f.Bits())
+	}
+
+	// 0x50 and 0x00 have an even number of ones, 0x57 and 0xcd odd
+	if !bytes.Equal(f.Parity(), []byte{1, 1, 0, 0}) || !f.ParityOK() || !f.CRCAOK() {
+		t.Errorf("HLTA frame has parity % x", f.Parity())
+	}
+
+	f.SetParity(1, 0)
+	if f.ParityOK() || f.Parity()[1] != 0 {
+		t.Error("SetParity() did not override parity bit")
+	}
+
+	b := NewFrame(0x05, 0x00, 0x08)
+	if b.AppendCRCB(); !b.CRCBOK() || b.CRCAOK() {
+		t.Errorf("REQB frame % x fails CRC check", b.Bytes())
+	}
+
+	// 4 bit ACK with garbage in the upper bits
+	ack, err := FrameFromBits([]byte{0xfa, 0x00}, []byte{1, 1}, 4)
+	if err != nil {
+		t.Fatal("FrameFromBits():", err)
+	}
+
+	if !bytes.Equal(ack.Bytes(), []byte{0x0a}) || !bytes.Equal(ack.Parity(), []byte{0}) || ack.Bits() != 4 {
+		t.Errorf("FrameFromBits() = % x, % x, %d", ack.Bytes(), ack.Parity(), ack.Bits())
+	}
+
+	if _, err = FrameFromBits([]byte{0x00}, []byte{0}, 9); err == nil {
+		t.Error("FrameFromBits() with short slice succeeded")
+	}
+}
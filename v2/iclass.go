@@ -0,0 +1,274 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "crypto/subtle"
+import "errors"
+import "fmt"
+import "time"
+
+// HID iClass (Picopass) commands. IClassIdentify and IClassRead share the
+// same command code and are distinguished by the presence of a block
+// number.
+const (
+	IClassActAll     = 0x0a
+	IClassIdentify   = 0x0c
+	IClassRead       = 0x0c
+	IClassSelect     = 0x81
+	IClassReadCheckD = 0x88 // READCHECK with debit key Kd
+	IClassReadCheckC = 0x18 // READCHECK with credit key Kc
+	IClassCheck      = 0x05
+	IClassRead4      = 0x06
+	IClassUpdate     = 0x87
+)
+
+// Blocks of the iClass memory map with special semantics.
+const (
+	IClassBlockCSN    = 0 // card serial number
+	IClassBlockConfig = 1 // configuration block
+	IClassBlockEPurse = 2 // e-purse, used as challenge for READCHECK
+	IClassBlockKd     = 3 // debit key, write only
+	IClassBlockKc     = 4 // credit key, write only
+	IClassBlockAIA    = 5 // application issuer area
+)
+
+// Returned if the MAC returned by the card in response to CHECK does not
+// match the expected one.
+var ErrIClassMAC = errors.New("iClass: MAC verification failed")
+
+// IClass is a driver for HID iClass tags (ISO14443biClassTarget). Unlike the
+// other drivers in this package, IClass computes and checks the CRC of the
+// frames itself with IClassCRC() as not all iClass frames carry one, so
+// HandleCRC must be disabled with Device.SetPropertyBool() before issuing
+// commands.
+type IClass struct {
+	Device  Initiator     // device used to communicate with the tag
+	CSN     [8]byte       // card serial number of the selected tag
+	Timeout time.Duration // see Device.InitiatorTransceiveBytesTimeout()
+
+	divKey [8]byte // diversified key from the last Authenticate()
+	authed bool
+}
+
+// Make a new IClass driver communicating through d with the tag t that has
+// been selected with Device.InitiatorSelectPassiveTarget() using modulation
+// ISO14443biClass. Pass nil for t if you want to select the tag yourself
+// using ActAll(), Identify() and Select().
+func NewIClass(d Initiator, t *ISO14443biClassTarget) *IClass {
+	c := &IClass{Device: d, Timeout: DefaultTimeout}
+	if t != nil {
+		c.CSN = t.UID
+	}
+
+	return c
+}
+
+// Send tx to the tag and return the response, which must be exactly rxLen
+// bytes long. If crc is set, a CRC is appended to rxLen and checked.
+func (c *IClass) transceive(tx []byte, rxLen int, crc bool) ([]byte, error) {
+	if crc {
+		rxLen += 2
+	}
+
+	rx := make([]byte, rxLen)
+	n, err := transceiveBytesTimeout(c.Device, tx, rx, c.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if n != rxLen {
+		return nil, fmt.Errorf("iClass: command %#02x: expected %d byte response, got %d", tx[0], rxLen, n)
+	}
+
+	if crc {
+		sum := IClassCRC(rx[:n-2])
+		if rx[n-2] != sum[0] || rx[n-1] != sum[1] {
+			return nil, fmt.Errorf("iClass: command %#02x: CRC error in response", tx[0])
+		}
+
+		rx = rx[:n-2]
+	}
+
+	return rx, nil
+}
+
+// Make a frame of command cmd for block with a CRC over the block number.
+func iClassBlockCmd(cmd, block byte) []byte {
+	crc := IClassCRC([]byte{block})
+	return []byte{cmd, block, crc[0], crc[1]}
+}
+
+// Issue ACTALL. All tags in the field in ready state enter active state.
+// ACTALL is answered with a start of frame only, which most devices report
+// as a timeout, so a timeout is not treated as an error.
+func (c *IClass) ActAll() error {
+	var rx [1]byte
+	_, err := transceiveBytesTimeout(c.Device, []byte{IClassActAll}, rx[:], c.Timeout)
+	if err == Error(ETIMEOUT) {
+		return nil
+	}
+
+	return err
+}
+
+// Issue IDENTIFY. The tag answers with its anticollision serial number
+// (ASNB) which is used to select it with Select().
+func (c *IClass) Identify() (asnb [8]byte, err error) {
+	rx, err := c.transceive([]byte{IClassIdentify}, len(asnb), true)
+	if err != nil {
+		return
+	}
+
+	copy(asnb[:], rx)
+	return
+}
+
+// Issue SELECT for the tag with anticollision serial number asnb. The tag
+// answers with its CSN which is stored in c.CSN and returned.
+func (c *IClass) Select(asnb [8]byte) (csn [8]byte, err error) {
+	tx := append([]byte{IClassSelect}, asnb[:]...)
+	rx, err := c.transceive(tx, len(csn), true)
+	if err != nil {
+		return
+	}
+
+	copy(csn[:], rx)
+	c.CSN = csn
+	c.authed = false
+	return
+}
+
+// Issue READCHECK for block, which is normally IClassBlockEPurse. The tag
+// prepares authentication with the debit key Kd or, if credit is set, with
+// the credit key Kc and answers with the card challenge, i.e. the contents
+// of block.
+func (c *IClass) ReadCheck(block byte, credit bool) (cc [8]byte, err error) {
+	cmd := byte(IClassReadCheckD)
+	if credit {
+		cmd = IClassReadCheckC
+	}
+
+	rx, err := c.transceive([]byte{cmd, block}, len(cc), false)
+	if err != nil {
+		return
+	}
+
+	copy(cc[:], rx)
+	return
+}
+
+// Issue CHECK with reader nonce nr and reader MAC mac. The tag answers with
+// its own MAC if mac is correct.
+func (c *IClass) Check(nr, mac [4]byte) (tagMAC [4]byte, err error) {
+	tx := []byte{IClassCheck}
+	tx = append(tx, nr[:]...)
+	tx = append(tx, mac[:]...)
+
+	rx, err := c.transceive(tx, len(tagMAC), false)
+	if err != nil {
+		return
+	}
+
+	copy(tagMAC[:], rx)
+	return
+}
+
+// Issue READ for block. Blocks 0, 1, 2 and 5 can be read without
+// authentication, all other blocks require Authenticate() first.
+func (c *IClass) Read(block byte) (data [8]byte, err error) {
+	rx, err := c.transceive(iClassBlockCmd(IClassRead, block), len(data), true)
+	if err != nil {
+		return
+	}
+
+	copy(data[:], rx)
+	return
+}
+
+// Issue READ4 for the four consecutive blocks starting at block.
+func (c *IClass) Read4(block byte) (data [32]byte, err error) {
+	rx, err := c.transceive(iClassBlockCmd(IClassRead4, block), len(data), true)
+	if err != nil {
+		return
+	}
+
+	copy(data[:], rx)
+	return
+}
+
+// Perform mutual authentication with key, which is the master key if the
+// card uses standard security or the custom key if elite is set. The key is
+// diversified with the CSN of the tag, so c.CSN must be set. If credit is
+// set, authentication is performed against the credit key Kc, otherwise
+// against the debit key Kd. Returns ErrIClassMAC if the tag's MAC is wrong.
+// The tag does not answer CHECK at all if the reader MAC is wrong, which is
+// reported as an ETIMEOUT error.
+func (c *IClass) Authenticate(key [8]byte, elite, credit bool) error {
+	var divKey [8]byte
+	if elite {
+		divKey = IClassDiversifyEliteKey(c.CSN, key)
+	} else {
+		divKey = IClassDiversifyKey(c.CSN, key)
+	}
+
+	c.authed = false
+
+	cc, err := c.ReadCheck(IClassBlockEPurse, credit)
+	if err != nil {
+		return err
+	}
+
+	// the reader nonce may be all zeroes, the card challenge provides
+	// freshness
+	var nr [4]byte
+	tagMAC, err := c.Check(nr, IClassReaderMAC(divKey, cc, nr))
+	if err != nil {
+		return err
+	}
+
+	expected := IClassTagMAC(divKey, cc, nr)
+	if subtle.ConstantTimeCompare(tagMAC[:], expected[:]) != 1 {
+		return ErrIClassMAC
+	}
+
+	c.divKey = divKey
+	c.authed = true
+	return nil
+}
+
+// Issue UPDATE to write data to block. This requires a prior successful
+// Authenticate() as the write is protected by a MAC computed with the
+// diversified key. The tag answers with the new contents of block, which
+// are returned. Note that key blocks read back as all ones.
+func (c *IClass) Update(block byte, data [8]byte) (newData [8]byte, err error) {
+	if !c.authed {
+		err = errors.New("iClass: UPDATE requires authentication")
+		return
+	}
+
+	mac := IClassUpdateMAC(c.divKey, block, data)
+
+	tx := []byte{IClassUpdate, block}
+	tx = append(tx, data[:]...)
+	tx = append(tx, mac[:]...)
+
+	rx, err := c.transceive(tx, len(newData), true)
+	if err != nil {
+		return
+	}
+
+	copy(newData[:], rx)
+	return
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "encoding/binary"
+import "testing"
+
+// Check the iClass MAC against a known trace.
+func TestIClassReaderMAC(t *testing.T) {
+	divKey := [8]byte{0xe0, 0x33, 0xca, 0x41, 0x9a, 0xee, 0x43, 0xf9}
+	cc := [8]byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	want := [4]byte{0x1d, 0x49, 0xc9, 0xda}
+
+	if mac := IClassReaderMAC(divKey, cc, [4]byte{}); mac != want {
+		t.Errorf("IClassReaderMAC() = % x, want % x", mac, want)
+	}
+}
+
+// Check hash0 against the test vectors of the iClass paper.
+func TestIClassHash0(t *testing.T) {
+	vectors := []struct{ in, out uint64 }{
+		{0x0102030405060708, 0x0bdd6512073c460a},
+		{0x1020304050607080, 0x0208211405f3381f},
+		{0x1122334455667788, 0x2bee256d40ac1f3a},
+		{0xabcdabcdabcdabcd, 0xa91c9ec66f7da592},
+	}
+
+	for _, v := range vectors {
+		k := iClassHash0(v.in)
+		if got := binary.BigEndian.Uint64(k[:]); got != v.out {
+			t.Errorf("iClassHash0(%016x) = %016x, want %016x", v.in, got, v.out)
+		}
+	}
+}
+
+// Check the beginning of the key table generated by hash2.
+func TestIClassHash2(t *testing.T) {
+	key := [8]byte{0x5b, 0x7c, 0x62, 0xc4, 0x91, 0xc1, 0x1b, 0x39}
+	want := []byte{
+		0xf1, 0x35, 0x59, 0xa1, 0x0d, 0x5a, 0x26, 0x7f,
+		0x18, 0x60, 0x0b, 0x96, 0x8a, 0xc0, 0x25, 0xc1,
+		0xbf, 0xa1, 0x3b, 0xb0, 0xff, 0x85, 0x28, 0x75,
+		0xf2, 0x1f, 0xc6, 0x8f, 0x0e, 0x74, 0x8f, 0x21,
+	}
+
+	table := iClassHash2(key)
+	if !bytes.Equal(table[:len(want)], want) {
+		t.Errorf("iClassHash2() = % x..., want % x...", table[:len(want)], want)
+	}
+}
+
+// READ frames as sent by other iClass readers, with the CRC over the block
+// number.
+func TestIClassCRC(t *testing.T) {
+	frames := map[byte][]byte{
+		1: {0x0c, 0x01, 0xfa, 0x22},
+		2: {0x0c, 0x02, 0x61, 0x10},
+		5: {0x0c, 0x05, 0xde, 0x64},
+	}
+
+	for block, want := range frames {
+		if got := iClassBlockCmd(IClassRead, block); !bytes.Equal(got, want) {
+			t.Errorf("iClassBlockCmd(IClassRead, %d) = % x, want % x", block, got, want)
+		}
+	}
+}
+
+// Append the iClass CRC of b to a copy of b.
+func iClassWithCRC(b ...byte) []byte {
+	crc := IClassCRC(b)
+	return append(append([]byte(nil), b...), crc[0], crc[1])
+}
+
+// Select a tag, check the CRC handling and read a block.
+func TestIClassSelect(t *testing.T) {
+	asnb := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88}
+	csn := []byte{0x01, 0x02, 0x03, 0x04, 0xf7, 0xff, 0x12, 0xe0}
+	config := []byte{0x12, 0xff, 0xff, 0xff, 0x7f, 0x1f, 0xff, 0x3c}
+
+	badCRC := iClassWithCRC(asnb...)
+	badCRC[len(badCRC)-1] ^= 0xff
+
+	d := &scriptedInitiator{t: t, script: []scriptedExchange{
+		{tx: []byte{IClassActAll}, err: Error(ETIMEOUT)},
+		{tx: []byte{IClassActAll}, err: Error(EIO)},
+		{tx: []byte{IClassIdentify}, rx: badCRC},
+		{tx: []byte{IClassIdentify}, rx: asnb},
+		{tx: []byte{IClassIdentify}, rx: iClassWithCRC(asnb...)},
+		{tx: append([]byte{IClassSelect}, asnb...), rx: iClassWithCRC(csn...)},
+		{tx: []byte{0x0c, 0x01, 0xfa, 0x22}, rx: iClassWithCRC(config...)},
+	}}
+	c := NewIClass(d, nil)
+
+	if err := c.ActAll(); err != nil {
+		t.Error("ActAll() answered with SOF only:", err)
+	}
+
+	if err := c.ActAll(); err != Error(EIO) {
+		t.Errorf("ActAll(): got error %v, want %v", err, Error(EIO))
+	}
+
+	if _, err := c.Identify(); err == nil {
+		t.Error("Identify() with CRC error succeeded")
+	}
+
+	if _, err := c.Identify(); err == nil {
+		t.Error("Identify() with missing CRC succeeded")
+	}
+
+	got, err := c.Identify()
+	if err != nil || !bytes.Equal(got[:], asnb) {
+		t.Fatalf("Identify() = % x, %v", got, err)
+	}
+
+	if got, err = c.Select(got); err != nil || !bytes.Equal(got[:], csn) || !bytes.Equal(c.CSN[:], csn) {
+		t.Fatalf("Select() = % x, %v", got, err)
+	}
+
+	if got, err = c.Read(IClassBlockConfig); err != nil || !bytes.Equal(got[:], config) {
+		t.Errorf("Read() = % x, %v", got, err)
+	}
+
+	d.done()
+}
+
+// Authenticate with the debit key, checking the MAC of the tag, and write
+// a block, which requires successful authentication.
+func TestIClassAuthenticate(t *testing.T) {
+	csn := [8]byte{0x01, 0x02, 0x03, 0x04, 0xf7, 0xff, 0x12, 0xe0}
+	key := [8]byte{0xaf, 0xa7, 0x85, 0xa7, 0xda, 0xb3, 0x33, 0x78}
+	cc := [8]byte{0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	data := [8]byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08}
+
+	divKey := IClassDiversifyKey(csn, key)
+	var nr [4]byte
+	readerMAC := IClassReaderMAC(divKey, cc, nr)
+	tagMAC := IClassTagMAC(divKey, cc, nr)
+	check := append(append([]byte{IClassCheck}, nr[:]...), readerMAC[:]...)
+	updateMAC := IClassUpdateMAC(divKey, 6, data)
+	update := append(append([]byte{IClassUpdate, 6}, data[:]...), updateMAC[:]...)
+	wrongMAC := tagMAC
+	wrongMAC[0] ^= 0xff
+
+	d := &scriptedInitiator{t: t, script: []scriptedExchange{
+		{tx: []byte{IClassReadCheckD, IClassBlockEPurse}, rx: cc[:]},
+		{tx: check, rx: wrongMAC[:]},
+		{tx: []byte{IClassReadCheckD, IClassBlockEPurse}, rx: cc[:]},
+		{tx: check, rx: tagMAC[:]},
+		{tx: update, rx: iClassWithCRC(data[:]...)},
+	}}
+	c := NewIClass(d, &ISO14443biClassTarget{UID: csn})
+
+	if _, err := c.Update(6, data); err == nil {
+		t.Error("Update() without authentication succeeded")
+	}
+
+	if err := c.Authenticate(key, false, false); err != ErrIClassMAC {
+		t.Errorf("Authenticate() with wrong tag MAC: got error %v, want %v", err, ErrIClassMAC)
+	}
+
+	if _, err := c.Update(6, data); err == nil {
+		t.Error("Update() after failed authentication succeeded")
+	}
+
+	if err := c.Authenticate(key, false, false); err != nil {
+		t.Fatal("Authenticate():", err)
+	}
+
+	if got, err := c.Update(6, data); err != nil || got != data {
+		t.Errorf("Update() = % x, %v", got, err)
+	}
+
+	d.done()
+}
@@ -0,0 +1,364 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+// The code in this file implements the iClass cipher and key
+// diversification as described in "Dismantling iClass and iClass Elite"
+// by Garcia, de Koning Gans, Verdult and Meriac (ESORICS 2012). Bit and
+// register numbering follows the paper: bit 0 is the most significant bit.
+
+package nfc
+
+import "crypto/des"
+import "encoding/binary"
+
+// Internal state of the iClass cipher: left, right, top and bottom
+// register.
+type iClassState struct {
+	l, r, b byte
+	t       uint16
+}
+
+// The feedback function of the top register,
+// T(x) = x0 ^ x1 ^ x5 ^ x7 ^ x10 ^ x11 ^ x14 ^ x15.
+func (s *iClassState) feedbackT() byte {
+	t := s.t & 0xc533
+	t ^= t >> 8
+	t ^= t >> 4
+	t ^= t >> 2
+	t ^= t >> 1
+	return byte(t & 1)
+}
+
+// The feedback function of the bottom register, B(x) = x1 ^ x2 ^ x3 ^ x7.
+func (s *iClassState) feedbackB() byte {
+	b := s.b & 0x71
+	b ^= b >> 4
+	b ^= b >> 2
+	b ^= b >> 1
+	return b & 1
+}
+
+// The selection function select(x, y, r) of the paper. Returns a key
+// index between 0 and 7.
+func iClassSelect(x, y, r byte) byte {
+	bit := func(i uint) byte { return r >> (7 - i) & 1 }
+
+	z0 := bit(0)&bit(2) ^ bit(1)&^bit(3) ^ (bit(2) | bit(4))
+	z1 := (bit(0) | bit(2)) ^ (bit(5) | bit(7)) ^ bit(1) ^ bit(6) ^ x ^ y
+	z2 := bit(3)&^bit(5) ^ bit(4)&bit(6) ^ bit(7) ^ x
+
+	return (z0&1)<<2 | (z1&1)<<1 | z2&1
+}
+
+// Make the initial cipher state for key k.
+func iClassInit(k *[8]byte) iClassState {
+	return iClassState{
+		l: (k[0] ^ 0x4c) + 0xec,
+		r: (k[0] ^ 0x4c) + 0x21,
+		b: 0x4c,
+		t: 0xe012,
+	}
+}
+
+// Advance the cipher state by one step with input bit y.
+func (s *iClassState) successor(k *[8]byte, y byte) {
+	r0 := s.r >> 7 & 1
+	r4 := s.r >> 3 & 1
+	r7 := s.r & 1
+	tt := s.feedbackT()
+
+	s.t = s.t>>1 | uint16(tt^r0^r4)<<15
+	s.b = s.b>>1 | (s.feedbackB()^r7)<<7
+
+	z := k[iClassSelect(tt, y, s.r)] ^ s.b
+	s.l, s.r = z+s.l+s.r, z+s.l
+}
+
+// Feed input into the cipher. Bytes are processed in order, each least
+// significant bit first.
+func (s *iClassState) feed(k *[8]byte, input []byte) {
+	for _, c := range input {
+		for i := uint(0); i < 8; i++ {
+			s.successor(k, c>>i&1)
+		}
+	}
+}
+
+// Produce 32 bits of output while feeding zeroes. Each output bit is bit
+// r5 of the right register; bits are packed least significant bit first.
+func (s *iClassState) output(k *[8]byte) (out [4]byte) {
+	for i := uint(0); i < 32; i++ {
+		out[i/8] |= (s.r >> 2 & 1) << (i % 8)
+		s.successor(k, 0)
+	}
+
+	return
+}
+
+// Compute the MAC the reader sends with CHECK: the cipher keyed with the
+// diversified key divKey is fed the card challenge cc and the reader nonce
+// nr.
+func IClassReaderMAC(divKey [8]byte, cc [8]byte, nr [4]byte) [4]byte {
+	s := iClassInit(&divKey)
+	s.feed(&divKey, cc[:])
+	s.feed(&divKey, nr[:])
+
+	return s.output(&divKey)
+}
+
+// Compute the MAC the card answers CHECK with. It is the cipher output
+// following the reader MAC.
+func IClassTagMAC(divKey [8]byte, cc [8]byte, nr [4]byte) [4]byte {
+	s := iClassInit(&divKey)
+	s.feed(&divKey, cc[:])
+	s.feed(&divKey, nr[:])
+	s.feed(&divKey, []byte{0, 0, 0, 0})
+
+	return s.output(&divKey)
+}
+
+// Compute the MAC for an UPDATE of block with data. It is computed over the
+// block number followed by the data.
+func IClassUpdateMAC(divKey [8]byte, block byte, data [8]byte) [4]byte {
+	s := iClassInit(&divKey)
+	s.feed(&divKey, []byte{block})
+	s.feed(&divKey, data[:])
+
+	return s.output(&divKey)
+}
+
+// The 35 seven bit values of Hamming weight 4 in ascending order.
+var iClassPi = [35]byte{
+	0x0f, 0x17, 0x1b, 0x1d, 0x1e, 0x27, 0x2b, 0x2d, 0x2e, 0x33, 0x35, 0x36,
+	0x39, 0x3a, 0x3c, 0x47, 0x4b, 0x4d, 0x4e, 0x53, 0x55, 0x56, 0x59, 0x5a,
+	0x5c, 0x63, 0x65, 0x66, 0x69, 0x6a, 0x6c, 0x71, 0x72, 0x74, 0x78,
+}
+
+// Get the n-th six bit value z[n] from the lower 48 bits of c.
+func sixBits(c uint64, n int) byte {
+	return byte(c >> uint(42-6*n) & 0x3f)
+}
+
+// Set the n-th six bit value z[n] in the lower 48 bits of c.
+func setSixBits(c uint64, n int, z byte) uint64 {
+	shift := uint(42 - 6*n)
+	return c&^(0x3f<<shift) | uint64(z&0x3f)<<shift
+}
+
+// The function ck of the paper, operating on z[0] to z[3].
+func iClassCk(i, j int, z uint64) uint64 {
+	for {
+		if j == -1 {
+			if i == 1 {
+				return z
+			}
+
+			i, j = i-1, i-2
+			continue
+		}
+
+		if sixBits(z, i) == sixBits(z, j) {
+			z = setSixBits(z, i, byte(j))
+		}
+
+		j--
+	}
+}
+
+// The function hash0 of the paper. It turns the DES encrypted CSN c into
+// the diversified key.
+func iClassHash0(c uint64) (k [8]byte) {
+	// swap z[0] ... z[7] to z[7] ... z[0]
+	var swapped uint64
+	for n := 0; n < 8; n++ {
+		swapped = setSixBits(swapped, 7-n, sixBits(c, n))
+	}
+
+	x := byte(c >> 56)
+	y := byte(c >> 48)
+	c = swapped
+
+	var zp uint64
+	for n := 0; n < 4; n++ {
+		zp = setSixBits(zp, n, sixBits(c, n)%byte(63-n)+byte(n))
+		zp = setSixBits(zp, n+4, sixBits(c, n+4)%byte(64-n)+byte(n))
+	}
+
+	// check(z') = ck(3, 2, z'[0..3]) ck(3, 2, z'[4..7])
+	zc := iClassCk(3, 2, zp)&0xffffff000000 | iClassCk(3, 2, zp<<24)&0xffffff000000>>24
+
+	// p is pi[x mod 35], complemented for odd x. Permute z^ according
+	// to p, taking bits of p from the least significant one.
+	p := iClassPi[x%35]
+	if x&1 != 0 {
+		p = ^p
+	}
+
+	var zt uint64
+	l, r := 0, 4
+	for i := 0; i < 8; i++ {
+		if p>>uint(i)&1 != 0 {
+			zt = setSixBits(zt, i, sixBits(zc, l)+1)
+			l++
+		} else {
+			zt = setSixBits(zt, i, sixBits(zc, r))
+			r++
+		}
+	}
+
+	for i := 0; i < 8; i++ {
+		zi := sixBits(zt, i) << 1
+		pi := p >> uint(i) & 1
+
+		if y>>uint(i)&1 != 0 {
+			k[i] = 0x80 | ^zi&0x7e | pi
+			k[i]++
+		} else {
+			k[i] = zi&0x7e | ^pi&1
+		}
+	}
+
+	return
+}
+
+// Diversify key with the card serial number csn: the diversified key is
+// hash0 of the DES encryption of csn under key. This is the key
+// diversification used by standard security iClass cards.
+func IClassDiversifyKey(csn, key [8]byte) [8]byte {
+	block, err := des.NewCipher(key[:])
+	if err != nil {
+		// cannot happen, the key has the right length
+		panic(err)
+	}
+
+	var c [8]byte
+	block.Encrypt(c[:], csn[:])
+
+	return iClassHash0(binary.BigEndian.Uint64(c[:]))
+}
+
+// Rotate each byte of key left by n bits.
+func iClassRk(key [8]byte, n uint) [8]byte {
+	for i := range key {
+		key[i] = key[i]<<(n%8) | key[i]>>(8-n%8)
+	}
+
+	return key
+}
+
+// Permute a key from iClass format into DES format.
+func iClassPermuteKeyRev(key [8]byte) (dest [8]byte) {
+	for i := uint(0); i < 8; i++ {
+		for j := uint(0); j < 8; j++ {
+			dest[7-i] |= (key[j] >> (7 - i) & 1) << (7 - j)
+		}
+	}
+
+	return
+}
+
+// The function hash1 of the paper. It computes eight indices into the
+// key table from the CSN.
+func iClassHash1(csn [8]byte) (k [8]byte) {
+	rl := func(a byte) byte { return a<<1 | a>>7 }
+	rr := func(a byte) byte { return a<<7 | a>>1 }
+	swap := func(a byte) byte { return a>>4 | a<<4 }
+
+	for _, c := range csn {
+		k[0] ^= c
+		k[1] += c
+	}
+
+	k[2] = rr(swap(csn[2] + k[1]))
+	k[3] = rl(swap(csn[3] + k[0]))
+	k[4] = -rr(csn[4] + k[2])
+	k[5] = -rl(csn[5] + k[3])
+	k[6] = rr(csn[6] + (k[4] ^ 0x3c))
+	k[7] = rl(csn[7] + (k[5] ^ 0xc3))
+
+	for i := range k {
+		k[i] &= 0x7f
+	}
+
+	return
+}
+
+// The function hash2 of the paper. It computes the 128 byte key table of
+// iClass Elite from the custom key. All keys are in iClass format and are
+// permuted into DES format before use.
+func iClassHash2(key [8]byte) (table [128]byte) {
+	desEnc := func(k, in [8]byte) (out [8]byte) {
+		k = iClassPermuteKeyRev(k)
+		block, _ := des.NewCipher(k[:])
+		block.Encrypt(out[:], in[:])
+		return
+	}
+
+	desDec := func(k, in [8]byte) (out [8]byte) {
+		k = iClassPermuteKeyRev(k)
+		block, _ := des.NewCipher(k[:])
+		block.Decrypt(out[:], in[:])
+		return
+	}
+
+	var notKey [8]byte
+	for i := range key {
+		notKey[i] = ^key[i]
+	}
+
+	var y, z [8][8]byte
+	z[0] = desEnc(key, notKey)
+	y[0] = desDec(z[0], notKey)
+	for i := 1; i < 8; i++ {
+		rk := iClassRk(key, uint(i))
+		z[i] = desDec(rk, z[i-1])
+		y[i] = desEnc(rk, y[i-1])
+	}
+
+	for i := 0; i < 8; i++ {
+		copy(table[16*i:], y[i][:])
+		copy(table[16*i+8:], z[i][:])
+	}
+
+	return
+}
+
+// Diversify key with the card serial number csn for iClass Elite (high
+// security) cards. The key is selected from the key table derived from the
+// custom key with hash2 and then diversified like a standard key.
+func IClassDiversifyEliteKey(csn, key [8]byte) [8]byte {
+	table := iClassHash2(key)
+	index := iClassHash1(csn)
+
+	var sel [8]byte
+	for i := range sel {
+		sel[i] = table[index[i]]
+	}
+
+	return IClassDiversifyKey(csn, iClassPermuteKeyRev(sel))
+}
+
+// Calculate the CRC used by iClass. It is the ISO 14443 CRC with an initial
+// value of 0xe012 and no final inversion.
+func IClassCRC(data []byte) [2]byte {
+	crc := uint32(0xe012)
+	for _, bt := range data {
+		bt ^= uint8(crc & 0xff)
+		bt ^= bt << 4
+		bt32 := uint32(bt)
+		crc = (crc >> 8) ^ (bt32 << 8) ^ (bt32 << 3) ^ (bt32 >> 4)
+	}
+
+	return [2]byte{byte(crc & 0xff), byte((crc >> 8) & 0xff)}
+}
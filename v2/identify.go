@@ -0,0 +1,318 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "fmt"
+
+// Card types as determined by IdentifyTarget() and Identify().
+type CardType int
+
+// Known card types.
+const (
+	CardUnknown CardType = iota
+	CardMifareMini
+	CardMifareClassic1K
+	CardMifareClassic4K
+	CardMifareUltralight
+	CardMifareUltralightC
+	CardMifareUltralightEV1
+	CardNTAG21x
+	CardMifareDESFire
+	CardMifareDESFireEV1
+	CardMifareDESFireEV2
+	CardMifareDESFireEV3
+	CardMifarePlus
+	CardJCOP
+	CardTopaz
+	CardFelica
+	CardFelicaLite
+	CardFelicaLiteS
+	CardISO14443b
+	CardInnovatron
+	CardSRx
+	CardASKCTS
+	CardPicoPass
+)
+
+var cardTypeNames = map[CardType]string{
+	CardUnknown:             "unknown card",
+	CardMifareMini:          "MIFARE Mini",
+	CardMifareClassic1K:     "MIFARE Classic 1K",
+	CardMifareClassic4K:     "MIFARE Classic 4K",
+	CardMifareUltralight:    "MIFARE Ultralight",
+	CardMifareUltralightC:   "MIFARE Ultralight C",
+	CardMifareUltralightEV1: "MIFARE Ultralight EV1",
+	CardNTAG21x:             "NTAG21x",
+	CardMifareDESFire:       "MIFARE DESFire",
+	CardMifareDESFireEV1:    "MIFARE DESFire EV1",
+	CardMifareDESFireEV2:    "MIFARE DESFire EV2",
+	CardMifareDESFireEV3:    "MIFARE DESFire EV3",
+	CardMifarePlus:          "MIFARE Plus",
+	CardJCOP:                "NXP SmartMX / JCOP",
+	CardTopaz:               "Innovision Topaz",
+	CardFelica:              "FeliCa",
+	CardFelicaLite:          "FeliCa Lite",
+	CardFelicaLiteS:         "FeliCa Lite-S",
+	CardISO14443b:           "ISO14443B card",
+	CardInnovatron:          "Innovatron ISO14443B' card",
+	CardSRx:                 "ST SRx",
+	CardASKCTS:              "ASK CTS",
+	CardPicoPass:            "HID iClass / PicoPass",
+}
+
+// Print the name of a card type, e.g. "MIFARE Classic 1K".
+func (c CardType) String() string {
+	name, ok := cardTypeNames[c]
+	if !ok {
+		return "unknown card"
+	}
+
+	return name
+}
+
+// CardInfo describes a card as identified by IdentifyTarget() and
+// Identify(). Fields that do not apply to the card are left at their zero
+// value unless noted otherwise.
+type CardInfo struct {
+	Type          CardType
+	SecurityLevel int       // MIFARE Plus security level 0 to 3, -1 if not known
+	NTAG          NTAGModel // NTAG21x model, NTAGUnknown if not known
+	SRx           SRxModel  // SRx model, SRxUnknown if not known
+	ISODEP        bool      // card supports ISO14443-4
+	Historical    []byte    // historical bytes from the ATS of ISO14443A cards
+	Version       []byte    // GET_VERSION response data, nil if not probed
+	FelicaROM     byte      // ROM type from the FeliCa PMm
+	FelicaIC      byte      // IC type from the FeliCa PMm
+}
+
+// Print a short description of the card, e.g. "NTAG215" or "MIFARE Plus
+// (security level 1)".
+func (c *CardInfo) String() string {
+	switch {
+	case c.Type == CardNTAG21x && c.NTAG != NTAGUnknown:
+		return c.NTAG.String()
+	case c.Type == CardSRx && c.SRx != SRxUnknown:
+		return "ST " + c.SRx.String()
+	case c.Type == CardMifarePlus && c.SecurityLevel >= 0:
+		return fmt.Sprintf("MIFARE Plus (security level %d)", c.SecurityLevel)
+	case c.Type == CardUnknown && c.ISODEP:
+		return "unknown ISO14443-4 card"
+	default:
+		return c.Type.String()
+	}
+}
+
+// FeliCa IC types found in the second byte of the PMm.
+const (
+	felicaICLite  = 0xf0
+	felicaICLiteS = 0xf1
+)
+
+// Identify the card t from its target data alone following the decision tree
+// of NXP AN10833 (MIFARE type identification procedure) and the ATS
+// historical bytes. Without probing, some cards cannot be told apart: MIFARE
+// Ultralight EV1 and NTAG21x are identified as MIFARE Ultralight, MIFARE
+// Plus in security level 1 as MIFARE Classic and MIFARE DESFire EV1 and
+// later as MIFARE DESFire. Use Identify() to tell them apart.
+func IdentifyTarget(t Target) CardInfo {
+	info := CardInfo{SecurityLevel: -1, NTAG: NTAGUnknown, SRx: SRxUnknown}
+
+	switch tt := t.(type) {
+	case *ISO14443aTarget:
+		identifyISO14443a(&info, tt)
+	case *FelicaTarget:
+		info.Type = CardFelica
+		info.FelicaROM = tt.Pad[0]
+		info.FelicaIC = tt.Pad[1]
+		switch tt.Pad[1] {
+		case felicaICLite:
+			info.Type = CardFelicaLite
+		case felicaICLiteS:
+			info.Type = CardFelicaLiteS
+		}
+	case *ISO14443bTarget:
+		info.Type = CardISO14443b
+		info.ISODEP = tt.ProtocolInfo[1]&0x01 != 0
+	case *ISO14443biTarget:
+		info.Type = CardInnovatron
+		info.ISODEP = true
+	case *ISO14443b2srTarget:
+		info.Type = CardSRx
+		info.SRx = tt.Model()
+	case *ISO14443b2ctTarget:
+		info.Type = CardASKCTS
+	case *ISO14443biClassTarget:
+		info.Type = CardPicoPass
+	case *JewelTarget:
+		info.Type = CardTopaz
+	}
+
+	return info
+}
+
+// Identify an ISO14443A card from ATQA, SAK and ATS.
+func identifyISO14443a(info *CardInfo, t *ISO14443aTarget) {
+	atqa := uint16(t.Atqa[0])<<8 | uint16(t.Atqa[1])
+	info.ISODEP = t.Sak&0x20 != 0
+	if t.AtsLen > 0 {
+		info.Historical = ISO14443aLocateHistoricalBytes(t.Ats[:clampLen(t.AtsLen, len(t.Ats))])
+	}
+
+	switch {
+	case bytes.Contains(info.Historical, []byte("JCOP")):
+		info.Type = CardJCOP
+	case t.Sak == 0x09:
+		info.Type = CardMifareMini
+	case t.Sak == 0x08 || t.Sak == 0x88:
+		info.Type = CardMifareClassic1K
+	case t.Sak == 0x18:
+		info.Type = CardMifareClassic4K
+	case t.Sak == 0x10 || t.Sak == 0x11:
+		info.Type = CardMifarePlus
+		info.SecurityLevel = 2
+	case t.Sak == 0x00 && atqa == 0x0044:
+		info.Type = CardMifareUltralight
+	case t.Sak == 0x28 || t.Sak == 0x38 || t.Sak == 0x20 && atqa&0xf0ff == 0x0048:
+		// SmartMX with MIFARE Classic emulation or 7 byte UID
+		info.Type = CardJCOP
+	case t.Sak == 0x20 && bytes.HasPrefix(info.Historical, []byte{0xc1, 0x05}):
+		// NXP type information as sent by MIFARE Plus
+		info.Type = CardMifarePlus
+		info.SecurityLevel = 3
+	case t.Sak == 0x20 && atqa == 0x0344:
+		info.Type = CardMifareDESFire
+	}
+}
+
+// Identify the card t like IdentifyTarget() does, then probe it with
+// GET_VERSION through d to tell apart the cards IdentifyTarget() cannot.
+// t must be the currently selected target of d. If a MIFARE Ultralight does
+// not answer GET_VERSION, it is reselected and probed for MIFARE Ultralight
+// C with the first step of AUTHENTICATE; it is reselected again afterwards.
+//
+// MIFARE Plus in security level 0 cannot be told apart from security level 3
+// without writing to the card and is reported as security level 3. MIFARE
+// Plus in security level 1 is reported as MIFARE Classic. The error is
+// non-nil only if communication with the device failed in a way that leaves
+// the card in an unknown state; info is valid in any case.
+func Identify(d Device, t Target) (info CardInfo, err error) {
+	info = IdentifyTarget(t)
+
+	a, ok := t.(*ISO14443aTarget)
+	if !ok {
+		return
+	}
+
+	switch info.Type {
+	case CardMifareUltralight:
+		err = identifyUltralight(d, a, &info)
+	case CardMifareDESFire, CardMifarePlus, CardUnknown:
+		if info.ISODEP {
+			identifyDESFire(d, &info)
+		}
+	}
+
+	return
+}
+
+// Tell apart MIFARE Ultralight, Ultralight C, Ultralight EV1 and NTAG21x.
+func identifyUltralight(d Device, t *ISO14443aTarget, info *CardInfo) error {
+	rx := make([]byte, 8)
+	n, err := d.InitiatorTransceiveBytes([]byte{NTAGGetVersion}, rx, -1)
+	if err == nil && n == len(rx) && rx[1] == 0x04 {
+		info.Version = rx
+		switch rx[2] {
+		case 0x03:
+			info.Type = CardMifareUltralightEV1
+		case 0x04:
+			info.Type = CardNTAG21x
+			if m := NTAGModel(rx[6]); m.Pages() != 0 {
+				info.NTAG = m
+			}
+		}
+
+		return nil
+	}
+
+	// the card went to HALT after the failed command
+	m := Modulation{ISO14443a, t.Baud}
+	uid := t.UID[:clampLen(t.UIDLen, len(t.UID))]
+	if _, err = d.InitiatorSelectPassiveTarget(m, uid); err != nil {
+		return err
+	}
+
+	// Ultralight C answers with 0xaf and the encrypted challenge
+	rx = make([]byte, 16)
+	n, err = d.InitiatorTransceiveBytes([]byte{0x1a, 0x00}, rx, -1)
+	if err != nil || n < 1 || rx[0] != 0xaf {
+		// the card is a plain Ultralight and has gone to HALT again
+		_, err = d.InitiatorSelectPassiveTarget(m, uid)
+		return err
+	}
+
+	info.Type = CardMifareUltralightC
+	_, err = d.InitiatorSelectPassiveTarget(m, uid)
+	return err
+}
+
+// Tell apart MIFARE DESFire versions and MIFARE Plus with the native
+// GET_VERSION command. Failure is not an error as the card may simply be
+// some other ISO14443-4 card.
+func identifyDESFire(d Device, info *CardInfo) {
+	var version []byte
+
+	// the response comes in up to three frames, each but the last
+	// with status 0xaf (additional frame)
+	rx := make([]byte, 16)
+	cmd := byte(NTAGGetVersion)
+	for i := 0; i < 3; i++ {
+		n, err := d.InitiatorTransceiveBytes([]byte{cmd}, rx, -1)
+		if err != nil || n < 1 || rx[0] != 0x00 && rx[0] != 0xaf {
+			break
+		}
+
+		version = append(version, rx[1:n]...)
+		if rx[0] == 0x00 {
+			break
+		}
+
+		cmd = 0xaf
+	}
+
+	if len(version) < 7 || version[0] != 0x04 {
+		return
+	}
+
+	info.Version = version
+	switch version[1] {
+	case 0x01:
+		switch version[3] {
+		case 0x00:
+			info.Type = CardMifareDESFire
+		case 0x01:
+			info.Type = CardMifareDESFireEV1
+		case 0x12:
+			info.Type = CardMifareDESFireEV2
+		case 0x33:
+			info.Type = CardMifareDESFireEV3
+		default:
+			info.Type = CardMifareDESFire
+		}
+	case 0x02:
+		info.Type = CardMifarePlus
+		info.SecurityLevel = 3
+	}
+}
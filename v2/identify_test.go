@@ -0,0 +1,129 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "encoding/json"
+import "testing"
+
+// Make an ISO14443A target from ATQA, SAK and ATS.
+func identifyTestTarget(atqa uint16, sak byte, ats []byte) *ISO14443aTarget {
+	t := &ISO14443aTarget{Atqa: [2]byte{byte(atqa >> 8), byte(atqa)}, Sak: sak, UIDLen: 7, UID: [10]byte{0x04, 1, 2, 3, 4, 5, 6}, Baud: Nbr106}
+	t.AtsLen = copy(t.Ats[:], ats)
+	return t
+}
+
+func TestIdentifyTarget(t *testing.T) {
+	tests := []struct {
+		t    Target
+		want string
+	}{
+		{identifyTestTarget(0x0004, 0x08, nil), "MIFARE Classic 1K"},
+		{identifyTestTarget(0x0002, 0x18, nil), "MIFARE Classic 4K"},
+		{identifyTestTarget(0x0004, 0x09, nil), "MIFARE Mini"},
+		{identifyTestTarget(0x0044, 0x00, nil), "MIFARE Ultralight"},
+		{identifyTestTarget(0x0044, 0x11, nil), "MIFARE Plus (security level 2)"},
+		{identifyTestTarget(0x0344, 0x20, []byte{0x75, 0x77, 0x81, 0x02, 0x80}), "MIFARE DESFire"},
+		{identifyTestTarget(0x0304, 0x28, []byte{0x78, 0x77, 0x94, 0x02, 'J', 'C', 'O', 'P', '4', '1'}), "NXP SmartMX / JCOP"},
+		{identifyTestTarget(0x0004, 0x20, []byte{0x78, 0x77, 0x94, 0x02, 0x80, 0x31}), "unknown ISO14443-4 card"},
+		{identifyTestTarget(0x0044, 0x20, []byte{0x75, 0x77, 0x80, 0x02, 0xc1, 0x05, 0x2f, 0x2f, 0x01, 0xbc, 0xd6}), "MIFARE Plus (security level 3)"},
+		{&FelicaTarget{Pad: [8]byte{0x10, 0xf1}}, "FeliCa Lite-S"},
+		{&FelicaTarget{Pad: [8]byte{0x05, 0x01}}, "FeliCa"},
+		{&ISO14443bTarget{ProtocolInfo: [3]byte{0x00, 0x81, 0x71}}, "ISO14443B card"},
+		{&ISO14443b2srTarget{UID: [8]byte{0, 0, 0, 0, 0, 0x0f << 2, 0x02, 0xd0}}, "ST SRI2K"},
+		{&JewelTarget{}, "Innovision Topaz"},
+	}
+
+	for _, tt := range tests {
+		info := IdentifyTarget(tt.t)
+		if got := info.String(); got != tt.want {
+			t.Errorf("IdentifyTarget(%T) = %q, want %q", tt.t, got, tt.want)
+		}
+	}
+
+	if info := IdentifyTarget(&ISO14443bTarget{ProtocolInfo: [3]byte{0x00, 0x81, 0x71}}); !info.ISODEP {
+		t.Error("ISO14443B target does not support ISO14443-4")
+	}
+}
+
+// Make a replayed Device performing calls.
+func replayCalls(t *testing.T, calls []deviceCall) Device {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	calls = append([]deviceCall{{Op: "Open"}}, calls...)
+	for i := range calls {
+		if err := enc.Encode(&calls[i]); err != nil {
+			t.Fatal("Encode():", err)
+		}
+	}
+
+	d, err := Replay(&buf)
+	if err != nil {
+		t.Fatal("Replay():", err)
+	}
+
+	return d
+}
+
+func TestIdentify(t *testing.T) {
+	ul := identifyTestTarget(0x0044, 0x00, nil)
+	sel := deviceCall{Op: "InitiatorSelectPassiveTarget", callArgs: callArgs{Modulations: []Modulation{{ISO14443a, Nbr106}}, InitData: ul.UID[:7]}, callResult: callResult{Result: &jsonTarget{ul}}}
+	getVersion := callArgs{Tx: []byte{NTAGGetVersion}, RxLen: 8, Timeout: -1}
+	version := NTAG215.Version()
+
+	tests := []struct {
+		t     Target
+		calls []deviceCall
+		want  string
+	}{
+		{ul, []deviceCall{
+			{Op: "InitiatorTransceiveBytes", callArgs: getVersion, callResult: callResult{N: 8, Rx: version[:]}},
+		}, "NTAG215"},
+		{ul, []deviceCall{
+			{Op: "InitiatorTransceiveBytes", callArgs: getVersion, callResult: callResult{N: ETIMEOUT, Err: ETIMEOUT}},
+			sel,
+			{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{0x1a, 0x00}, RxLen: 16, Timeout: -1}, callResult: callResult{N: 9, Rx: []byte{0xaf, 1, 2, 3, 4, 5, 6, 7, 8}}},
+			sel,
+		}, "MIFARE Ultralight C"},
+		{ul, []deviceCall{
+			{Op: "InitiatorTransceiveBytes", callArgs: getVersion, callResult: callResult{N: ETIMEOUT, Err: ETIMEOUT}},
+			sel,
+			{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{0x1a, 0x00}, RxLen: 16, Timeout: -1}, callResult: callResult{N: ETIMEOUT, Err: ETIMEOUT}},
+			sel,
+		}, "MIFARE Ultralight"},
+		{identifyTestTarget(0x0344, 0x20, []byte{0x75, 0x77, 0x81, 0x02, 0x80}), []deviceCall{
+			{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{NTAGGetVersion}, RxLen: 16, Timeout: -1}, callResult: callResult{N: 8, Rx: []byte{0xaf, 0x04, 0x01, 0x01, 0x12, 0x00, 0x1a, 0x05}}},
+			{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{0xaf}, RxLen: 16, Timeout: -1}, callResult: callResult{N: 8, Rx: []byte{0xaf, 0x04, 0x01, 0x01, 0x02, 0x01, 0x1a, 0x05}}},
+			{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{0xaf}, RxLen: 16, Timeout: -1}, callResult: callResult{N: 15, Rx: make([]byte, 15)}},
+		}, "MIFARE DESFire EV2"},
+		{identifyTestTarget(0x0004, 0x20, []byte{0x78, 0x77, 0x94, 0x02, 0x80, 0x31}), []deviceCall{
+			{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{NTAGGetVersion}, RxLen: 16, Timeout: -1}, callResult: callResult{N: 2, Rx: []byte{0x6e, 0x00}}},
+		}, "unknown ISO14443-4 card"},
+	}
+
+	for _, tt := range tests {
+		d := replayCalls(t, append(tt.calls, deviceCall{Op: "Close"}))
+		info, err := Identify(d, tt.t)
+		if err != nil {
+			t.Errorf("Identify(): %v", err)
+		} else if got := info.String(); got != tt.want {
+			t.Errorf("Identify() = %q, want %q", got, tt.want)
+		}
+
+		if err = d.Close(); err != nil {
+			t.Errorf("%s: Close(): %v", tt.want, err)
+		}
+	}
+}
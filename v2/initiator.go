@@ -54,9 +54,23 @@ struct target_listing list_targets_wrapper(nfc_device *device, const nfc_modulat
 
 	return tar;
 }
+
+// nfc_initiator_select_dep_target takes a pointer to an nfc_dep_info, but we
+// can only marshall entire targets.  Pass the dep_info of initiator, which may
+// be NULL.
+int select_dep_target_wrapper(nfc_device *device, nfc_dep_mode ndm, nfc_baud_rate nbr,
+    const nfc_target *initiator, nfc_target *pnt, int timeout) {
+	const nfc_dep_info *ndi = NULL;
+
+	if (initiator != NULL)
+		ndi = &initiator->nti.ndi;
+
+	return nfc_initiator_select_dep_target(device, ndm, nbr, ndi, pnt, timeout);
+}
 */
 import "C"
 import "errors"
+import "time"
 import "unsafe"
 
 // Send data to target then retrieve data from target. n contains received bytes
@@ -87,6 +101,23 @@ import "unsafe"
 // raised or function is completed). If timeout equals to -1, the default
 // timeout will be used.
 func (d Device) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (n int, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: nilIfEmpty(tx), RxLen: len(rx), Timeout: timeout}}
+		err = d.b.call(&c, func(d Device) {
+			n, err := d.InitiatorTransceiveBytes(tx, rx, timeout)
+			c.setBytes(rx, n)
+			c.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, err
+		}
+
+		return c.getData(rx, nil), c.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, errors.New("device closed")
 	}
@@ -94,6 +125,7 @@ func (d Device) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (n int, err
 	txptr := (*C.uint8_t)(&tx[0])
 	rxptr := (*C.uint8_t)(&rx[0])
 
+	start := time.Now()
 	n = int(C.nfc_initiator_transceive_bytes(
 		*d.d,
 		txptr, C.size_t(len(tx)),
@@ -105,6 +137,7 @@ func (d Device) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (n int, err
 		err = Error(n)
 	}
 
+	d.trace("InitiatorTransceiveBytes", start, traceBytes(tx, len(tx)), traceBytes(rx, n), 0, err)
 	return
 }
 
@@ -145,6 +178,23 @@ func (d Device) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (n int, err
 // violate the ISO14443-A standard by sending incorrect parity and CRC bytes.
 // Using this feature you are able to simulate these frames.
 func (d Device) InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxPar []byte) (n int, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorTransceiveBits", callArgs: callArgs{Tx: nilIfEmpty(tx), TxPar: nilIfEmpty(txPar), TxBits: txLength, RxLen: len(rx)}}
+		err = d.b.call(&c, func(d Device) {
+			n, err := d.InitiatorTransceiveBits(tx, txPar, txLength, rx, rxPar)
+			c.setBits(rx, rxPar, n)
+			c.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, err
+		}
+
+		return c.getData(rx, rxPar), c.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, errors.New("device closed")
 	}
@@ -162,6 +212,7 @@ func (d Device) InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxP
 	rxptr := (*C.uint8_t)(&rx[0])
 	rxparptr := (*C.uint8_t)(&rxPar[0])
 
+	start := time.Now()
 	n = int(C.nfc_initiator_transceive_bits(
 		*d.d,
 		txptr, C.size_t(txLength), txparptr,
@@ -172,6 +223,7 @@ func (d Device) InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxP
 		err = Error(n)
 	}
 
+	d.trace("InitiatorTransceiveBits", start, traceBits(tx, txPar, int(txLength)), traceBits(rx, rxPar, n), 0, err)
 	return
 }
 
@@ -200,29 +252,48 @@ func (d Device) InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxP
 // Warning: The configuration option EASY_FRAMING must be set to false; the
 // configuration option HANDLE_PARITY must be set to true (default value).
 func (d Device) InitiatorTransceiveBytesTimed(tx, rx []byte, cycles uint32) (n int, c uint32, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		dc := deviceCall{Op: "InitiatorTransceiveBytesTimed", callArgs: callArgs{Tx: nilIfEmpty(tx), RxLen: len(rx), Cycles: cycles}}
+		err = d.b.call(&dc, func(d Device) {
+			n, c, err := d.InitiatorTransceiveBytesTimed(tx, rx, cycles)
+			dc.setBytes(rx, n)
+			dc.C = c
+			dc.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, 0, err
+		}
+
+		return dc.getData(rx, nil), dc.C, dc.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, 0, errors.New("device closed")
 	}
 
-	var cptr *C.uint32_t
-	*cptr = C.uint32_t(cycles)
+	cptr := C.uint32_t(cycles)
 
 	txptr := (*C.uint8_t)(&tx[0])
 	rxptr := (*C.uint8_t)(&rx[0])
 
+	start := time.Now()
 	n = int(C.nfc_initiator_transceive_bytes_timed(
 		*d.d,
 		txptr, C.size_t(len(tx)),
 		rxptr, C.size_t(len(rx)),
-		cptr,
+		&cptr,
 	))
 
 	if n < 0 {
 		err = Error(n)
 	}
 
-	c = uint32(*cptr)
+	c = uint32(cptr)
 
+	d.trace("InitiatorTransceiveBytesTimed", start, traceBytes(tx, len(tx)), traceBytes(rx, n), c, err)
 	return
 }
 
@@ -253,6 +324,24 @@ func (d Device) InitiatorTransceiveBytesTimed(tx, rx []byte, cycles uint32) (n i
 // configuration option HANDLE_CRC must be set to false; the configuration
 // option HANDLE_PARITY must be set to true (the default value).
 func (d Device) InitiatorTransceiveBitsTimed(tx, txPar []byte, txLength uint, rx, rxPar []byte, cycles uint32) (n int, c uint32, err error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		dc := deviceCall{Op: "InitiatorTransceiveBitsTimed", callArgs: callArgs{Tx: nilIfEmpty(tx), TxPar: nilIfEmpty(txPar), TxBits: txLength, RxLen: len(rx), Cycles: cycles}}
+		err = d.b.call(&dc, func(d Device) {
+			n, c, err := d.InitiatorTransceiveBitsTimed(tx, txPar, txLength, rx, rxPar, cycles)
+			dc.setBits(rx, rxPar, n)
+			dc.C = c
+			dc.setErr(err)
+		})
+		if err != nil {
+			return ESOFT, 0, err
+		}
+
+		return dc.getData(rx, rxPar), dc.C, dc.err()
+	}
+
 	if *d.d == nil {
 		return ESOFT, 0, errors.New("device closed")
 	}
@@ -265,27 +354,28 @@ func (d Device) InitiatorTransceiveBitsTimed(tx, txPar []byte, txLength uint, rx
 		return ESOFT, 0, errors.New("slice shorter than specified bit count")
 	}
 
-	var cptr *C.uint32_t
-	*cptr = C.uint32_t(cycles)
+	cptr := C.uint32_t(cycles)
 
 	txptr := (*C.uint8_t)(&tx[0])
 	txparptr := (*C.uint8_t)(&txPar[0])
 	rxptr := (*C.uint8_t)(&rx[0])
 	rxparptr := (*C.uint8_t)(&rxPar[0])
 
+	start := time.Now()
 	n = int(C.nfc_initiator_transceive_bits_timed(
 		*d.d,
 		txptr, C.size_t(txLength), txparptr,
 		rxptr, C.size_t(len(rx)), rxparptr,
-		cptr,
+		&cptr,
 	))
 
-	c = uint32(*cptr)
+	c = uint32(cptr)
 
 	if n < 0 {
 		err = Error(n)
 	}
 
+	d.trace("InitiatorTransceiveBitsTimed", start, traceBits(tx, txPar, int(txLength)), traceBits(rx, rxPar, n), c, err)
 	return
 }
 
@@ -294,6 +384,18 @@ func (d Device) InitiatorTransceiveBitsTimed(tx, txPar []byte, txLength uint, rx
 // one or more commands will be sent to the target. The t argument can be nil,
 // in this case presence will be tested for the last selected tag.
 func (d Device) InitiatorTargetIsPresent(t Target) error {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorTargetIsPresent", callArgs: callArgs{Target: newJSONTarget(t)}}
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.InitiatorTargetIsPresent(t)) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -330,6 +432,18 @@ func (d Device) InitiatorTargetIsPresent(t Target) error {
 //   - Let the device try forever to find a target (NP_INFINITE_SELECT = true)
 //   - RF field is shortly dropped (if it was enabled) then activated again
 func (d Device) InitiatorInit() error {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorInit"}
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.InitiatorInit()) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -346,6 +460,18 @@ func (d Device) InitiatorInit() error {
 // (reader). After initialization it can be used to communicate with the secure
 // element. The RF field is deactivated in order to save power.
 func (d Device) InitiatorInitSecureElement() error {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorInitSecureElement"}
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.InitiatorInitSecureElement()) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -362,6 +488,25 @@ func (d Device) InitiatorInitSecureElement() error {
 //
 // if nil, default values adequate for the chosen modulation will be used.
 func (d Device) InitiatorSelectPassiveTarget(m Modulation, initData []byte) (Target, error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorSelectPassiveTarget", callArgs: callArgs{Modulations: []Modulation{m}, InitData: nilIfEmpty(initData)}}
+		err := d.b.call(&c, func(d Device) {
+			t, err := d.InitiatorSelectPassiveTarget(m, initData)
+			c.Result = newJSONTarget(t)
+			c.setErr(err)
+		})
+		if err != nil {
+			return nil, err
+		} else if err = c.err(); err != nil {
+			return nil, err
+		}
+
+		return c.target(), nil
+	}
+
 	if *d.d == nil {
 		return nil, errors.New("device closed")
 	}
@@ -393,6 +538,28 @@ func (d Device) InitiatorSelectPassiveTarget(m Modulation, initData []byte) (Tar
 // of tag it is dealing with, therefore the initial modulation and speed (106,
 // 212 or 424 kbps) should be supplied.
 func (d Device) InitiatorListPassiveTargets(m Modulation) ([]Target, error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorListPassiveTargets", callArgs: callArgs{Modulations: []Modulation{m}}}
+		err := d.b.call(&c, func(d Device) {
+			targets, err := d.InitiatorListPassiveTargets(m)
+			for _, t := range targets {
+				c.Results = append(c.Results, jsonTarget{t})
+			}
+
+			c.setErr(err)
+		})
+		if err != nil {
+			return nil, err
+		} else if err = c.err(); err != nil {
+			return nil, err
+		}
+
+		return c.targets(), nil
+	}
+
 	if *d.d == nil {
 		return nil, errors.New("device closed")
 	}
@@ -425,6 +592,18 @@ func (d Device) InitiatorListPassiveTargets(m Modulation) ([]Target, error) {
 // it for the available features and support, deselect it and skip to the next
 // tag until the correct tag is found.
 func (d Device) InitiatorDeselectTarget() error {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorDeselectTarget"}
+		if err := d.b.call(&c, func(d Device) { c.setErr(d.InitiatorDeselectTarget()) }); err != nil {
+			return err
+		}
+
+		return c.err()
+	}
+
 	if *d.d == nil {
 		return errors.New("device closed")
 	}
@@ -436,3 +615,77 @@ func (d Device) InitiatorDeselectTarget() error {
 
 	return nil
 }
+
+// Select a target and request active or passive mode for D.E.P. (Data Exchange
+// Protocol). mode is either Active or Passive, baud is the baud rate to use.
+// The initiator parameters of the ATR_REQ (NFCID3, general bytes) are taken
+// from initiator if it is not nil; libnfc picks defaults otherwise. The
+// returned target contains the parameters of the ATR_RES sent by the target.
+//
+// If timeout equals to 0, the function blocks indefinitely (until an error is
+// raised or function is completed). If timeout equals to -1, the default
+// timeout will be used.
+func (d Device) InitiatorSelectDEPTarget(mode, baud int, initiator *DEPTarget, timeout int) (*DEPTarget, error) {
+	d.state.op.Lock()
+	defer d.state.op.Unlock()
+
+	if d.b != nil {
+		c := deviceCall{Op: "InitiatorSelectDEPTarget", callArgs: callArgs{Mode: mode, Baud: baud, Timeout: timeout}}
+		if initiator != nil {
+			c.Target = &jsonTarget{initiator}
+		}
+
+		err := d.b.call(&c, func(d Device) {
+			dt, err := d.InitiatorSelectDEPTarget(mode, baud, initiator, timeout)
+			if dt != nil {
+				c.Result = &jsonTarget{dt}
+			}
+
+			c.setErr(err)
+		})
+		if err != nil {
+			return nil, err
+		} else if err = c.err(); err != nil {
+			return nil, err
+		}
+
+		dt, ok := c.target().(*DEPTarget)
+		if !ok {
+			return nil, errors.New("replay: recorded target is not a DEPTarget")
+		}
+
+		return dt, nil
+	}
+
+	if *d.d == nil {
+		return nil, errors.New("device closed")
+	}
+
+	var ini *C.nfc_target
+	if initiator != nil {
+		ini = (*C.nfc_target)(unsafe.Pointer(initiator.Marshall()))
+		defer C.free(unsafe.Pointer(ini))
+	}
+
+	var pnt C.nfc_target
+	n := C.select_dep_target_wrapper(
+		*d.d, C.nfc_dep_mode(mode), C.nfc_baud_rate(baud),
+		ini, &pnt, C.int(timeout))
+	if n < 0 {
+		return nil, Error(n)
+	} else if n == 0 {
+		return nil, Error(ETIMEOUT)
+	}
+
+	dt := unmarshallDEPTarget(&pnt)
+	return &dt, nil
+}
+
+// Initiator is the subset of the methods of Device needed to talk to a
+// selected target.  The tag drivers in this package are written against this
+// interface instead of Device so they can be layered on top of other
+// transports.  Device implements Initiator.
+type Initiator interface {
+	InitiatorTransceiveBytes(tx, rx []byte, timeout int) (n int, err error)
+	InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxPar []byte) (n int, err error)
+}
@@ -0,0 +1,733 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+// The code in this file implements the NFC Forum Logical Link Control
+// Protocol (LLCP) 1.1 on top of an NFC-DEP session. See llcpconn.go for the
+// connection types.
+
+package nfc
+
+// the package has its own type called context
+import gocontext "context"
+import "bytes"
+import "errors"
+import "fmt"
+import "io"
+import "net"
+import "os"
+import "sync"
+import "time"
+
+// LLCP magic number found at the beginning of the general bytes of the
+// ATR_REQ and ATR_RES of LLCP capable devices.
+var llcpMagic = []byte{0x46, 0x66, 0x6d}
+
+// LLCP PDU types.
+const (
+	LLCPSymm    = 0x0 // symmetry
+	LLCPPax     = 0x1 // parameter exchange
+	LLCPAgf     = 0x2 // aggregated frame
+	LLCPUI      = 0x3 // unnumbered information
+	LLCPConnect = 0x4
+	LLCPDisc    = 0x5 // disconnect
+	LLCPCC      = 0x6 // connection complete
+	LLCPDM      = 0x7 // disconnected mode
+	LLCPFrmr    = 0x8 // frame reject
+	LLCPSnl     = 0x9 // service name lookup
+	LLCPI       = 0xc // information
+	LLCPRR      = 0xd // receive ready
+	LLCPRNR     = 0xe // receive not ready
+)
+
+// LLCP parameter types.
+const (
+	LLCPParamVersion = 0x01
+	LLCPParamMIUX    = 0x02
+	LLCPParamWKS     = 0x03
+	LLCPParamLTO     = 0x04
+	LLCPParamRW      = 0x05
+	LLCPParamSN      = 0x06
+	LLCPParamOpt     = 0x07
+	LLCPParamSDReq   = 0x08
+	LLCPParamSDRes   = 0x09
+)
+
+// Well-known service access points.
+const (
+	LLCPSAPLinkManagement = 0x00
+	LLCPSAPSDP            = 0x01 // service discovery protocol
+	LLCPSAPSNEP           = 0x04 // simple NDEF exchange protocol
+)
+
+// Reasons sent in a DM PDU.
+const (
+	LLCPDMDisconnected = 0x00 // DISC received
+	LLCPDMNotActive    = 0x01 // no active connection for connection-oriented PDU
+	LLCPDMNoService    = 0x02 // no service bound to target SAP
+	LLCPDMRejected     = 0x03 // CONNECT rejected by service layer
+)
+
+// The LLCP version implemented by this package, 1.1.
+const LLCPVersion = 0x11
+
+// Default values of LLCP parameters.
+const (
+	llcpDefaultMIU = 128 // maximum information unit
+	llcpDefaultLTO = 100 // link timeout in ms
+	llcpDefaultRW  = 1   // receive window
+)
+
+// How long the initiator waits before answering a SYMM with a SYMM. This
+// keeps an idle link from spinning.
+const llcpSymmDelay = 10 * time.Millisecond
+
+// Parameters of an LLCP link as exchanged in the general bytes of ATR_REQ
+// and ATR_RES.
+type LLCPParams struct {
+	Version byte   // major version in the upper, minor version in the lower nibble
+	MIUX    uint16 // maximum information unit extension, MIU = 128 + MIUX
+	WKS     uint16 // bit map of well-known services offered
+	LTO     int    // link timeout in ms, 0 for the default of 100 ms
+	Opt     byte   // option parameter (link service class)
+}
+
+// Encode p as general bytes including the LLCP magic number. A zero
+// Version is replaced by LLCPVersion.
+func (p LLCPParams) GeneralBytes() []byte {
+	version := p.Version
+	if version == 0 {
+		version = LLCPVersion
+	}
+
+	gb := append([]byte(nil), llcpMagic...)
+	gb = appendTLV(gb, LLCPParamVersion, version)
+	if p.MIUX != 0 {
+		gb = appendTLV(gb, LLCPParamMIUX, byte(p.MIUX>>8&0x07), byte(p.MIUX))
+	}
+
+	gb = appendTLV(gb, LLCPParamWKS, byte(p.WKS>>8), byte(p.WKS))
+	if p.LTO != 0 {
+		gb = appendTLV(gb, LLCPParamLTO, byte(p.LTO/10))
+	}
+
+	if p.Opt != 0 {
+		gb = appendTLV(gb, LLCPParamOpt, p.Opt)
+	}
+
+	return gb
+}
+
+// Maximum information unit, i.e. the largest information field the owner of
+// p accepts.
+func (p LLCPParams) MIU() int {
+	return llcpDefaultMIU + int(p.MIUX&0x7ff)
+}
+
+// Parse the LLCP parameters from general bytes gb. An error is returned if
+// gb does not start with the LLCP magic number.
+func ParseLLCPParams(gb []byte) (p LLCPParams, err error) {
+	if !bytes.HasPrefix(gb, llcpMagic) {
+		err = errors.New("LLCP: general bytes lack magic number")
+		return
+	}
+
+	tlvs, err := parseTLVs(gb[len(llcpMagic):])
+	if err != nil {
+		return
+	}
+
+	p.LTO = llcpDefaultLTO
+	for _, t := range tlvs {
+		switch {
+		case t.typ == LLCPParamVersion && len(t.val) == 1:
+			p.Version = t.val[0]
+		case t.typ == LLCPParamMIUX && len(t.val) == 2:
+			p.MIUX = uint16(t.val[0]&0x07)<<8 | uint16(t.val[1])
+		case t.typ == LLCPParamWKS && len(t.val) == 2:
+			p.WKS = uint16(t.val[0])<<8 | uint16(t.val[1])
+		case t.typ == LLCPParamLTO && len(t.val) == 1:
+			p.LTO = 10 * int(t.val[0])
+		case t.typ == LLCPParamOpt && len(t.val) == 1:
+			p.Opt = t.val[0]
+		}
+	}
+
+	if p.Version>>4 != LLCPVersion>>4 {
+		err = fmt.Errorf("LLCP: unsupported version %d.%d", p.Version>>4, p.Version&0xf)
+	}
+
+	return
+}
+
+// A type-length-value parameter.
+type llcpTLV struct {
+	typ byte
+	val []byte
+}
+
+// Append a TLV with type typ and value val to b.
+func appendTLV(b []byte, typ byte, val ...byte) []byte {
+	b = append(b, typ, byte(len(val)))
+	return append(b, val...)
+}
+
+// Split b into TLVs.
+func parseTLVs(b []byte) ([]llcpTLV, error) {
+	var tlvs []llcpTLV
+	for len(b) > 0 {
+		if len(b) < 2 || len(b) < 2+int(b[1]) {
+			return nil, errors.New("LLCP: truncated parameter")
+		}
+
+		tlvs = append(tlvs, llcpTLV{b[0], b[2 : 2+int(b[1])]})
+		b = b[2+int(b[1]):]
+	}
+
+	return tlvs, nil
+}
+
+// An LLCP PDU.
+type llcpPDU struct {
+	dsap, ptype, ssap byte
+	seq               byte // N(S) << 4 | N(R), for I, RR and RNR only
+	info              []byte
+}
+
+// Whether PDUs of type ptype carry a sequence field.
+func llcpHasSeq(ptype byte) bool {
+	return ptype == LLCPI || ptype == LLCPRR || ptype == LLCPRNR
+}
+
+// Encode p.
+func (p *llcpPDU) marshal() []byte {
+	b := make([]byte, 2, 3+len(p.info))
+	b[0] = p.dsap<<2 | p.ptype>>2
+	b[1] = p.ptype<<6 | p.ssap&0x3f
+	if llcpHasSeq(p.ptype) {
+		b = append(b, p.seq)
+	}
+
+	return append(b, p.info...)
+}
+
+// Decode a PDU from b.
+func parseLLCPPDU(b []byte) (p llcpPDU, err error) {
+	if len(b) < 2 {
+		err = errors.New("LLCP: truncated PDU")
+		return
+	}
+
+	p.dsap = b[0] >> 2
+	p.ptype = (b[0]&0x03)<<2 | b[1]>>6
+	p.ssap = b[1] & 0x3f
+	b = b[2:]
+	if llcpHasSeq(p.ptype) {
+		if len(b) < 1 {
+			err = errors.New("LLCP: truncated PDU")
+			return
+		}
+
+		p.seq = b[0]
+		b = b[1:]
+	}
+
+	p.info = b
+	return
+}
+
+// LLCPLink is an LLCP link running on top of a DEPSession. Once created,
+// the link exchanges PDUs with the peer in the background until either side
+// closes it. Use Dial() and Listen() for connection-oriented and
+// ListenPacket() for connectionless communication.
+type LLCPLink struct {
+	Local  LLCPParams // parameters of the local side
+	Remote LLCPParams // parameters of the peer
+
+	dep       *DEPSession
+	initiator bool
+
+	mu        sync.Mutex
+	cond      *sync.Cond // signalled whenever the state below changes
+	queue     [][]byte   // PDUs waiting to be sent
+	wake      chan struct{}
+	err       error // set once the link is down
+	closing   bool
+	done      chan struct{}
+	listeners map[byte]*LLCPListener
+	services  map[string]byte          // service names bound to SAPs
+	conns     map[[2]byte]*LLCPConn    // by local and remote SAP
+	pending   map[byte]*LLCPConn       // outgoing CONNECTs by local SAP
+	packets   map[byte]*LLCPPacketConn // connectionless endpoints
+	lookups   map[byte]chan byte       // outstanding SNL requests by TID
+	tid       byte
+}
+
+// Establish an LLCP link on top of DEP session s with local parameters p.
+// The parameters of the peer are taken from the general bytes of
+// s.Remote. On the target, first must be the message returned by
+// DEPListen(); on the initiator it must be nil. The general bytes sent
+// during activation must have been p.GeneralBytes().
+func NewLLCPLink(s *DEPSession, first []byte, p LLCPParams) (*LLCPLink, error) {
+	remote, err := ParseLLCPParams(s.Remote.GeneralBytes())
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Version == 0 {
+		p.Version = LLCPVersion
+	}
+
+	if p.LTO == 0 {
+		p.LTO = llcpDefaultLTO
+	}
+
+	l := &LLCPLink{
+		Local:     p,
+		Remote:    remote,
+		dep:       s,
+		initiator: s.initiator,
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+		listeners: make(map[byte]*LLCPListener),
+		services:  make(map[string]byte),
+		conns:     make(map[[2]byte]*LLCPConn),
+		pending:   make(map[byte]*LLCPConn),
+		packets:   make(map[byte]*LLCPPacketConn),
+		lookups:   make(map[byte]chan byte),
+	}
+
+	l.cond = sync.NewCond(&l.mu)
+	l.services[LLCPServiceSDP] = LLCPSAPSDP
+
+	go l.run(first)
+
+	return l, nil
+}
+
+// Activate an LLCP link as initiator with DEPConnect(). See DEPConnect() for
+// the meaning of the other parameters.
+func ConnectLLCP(d DEPInitiator, mode, baud int, p LLCPParams, timeout time.Duration) (*LLCPLink, error) {
+	s, err := DEPConnect(d, mode, baud, p.GeneralBytes(), timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLLCPLink(s, nil, p)
+}
+
+// Wait for an initiator to activate an LLCP link with d configured as DEP
+// target t. The general bytes of t are replaced with the encoding of p. See
+// DEPListen() for the meaning of the other parameters.
+func ListenLLCP(d TargetDevice, t DEPTarget, p LLCPParams, timeout time.Duration) (*LLCPLink, error) {
+	if err := t.SetGeneralBytes(p.GeneralBytes()); err != nil {
+		return nil, err
+	}
+
+	s, first, err := DEPListen(d, &t, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLLCPLink(s, first, p)
+}
+
+// Queue PDU p for transmission. Must be called with l.mu held.
+func (l *LLCPLink) send(p llcpPDU) {
+	l.queue = append(l.queue, p.marshal())
+	select {
+	case l.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Wait on l.cond until woken up or deadline passes. Must be called with
+// l.mu held.
+func (l *LLCPLink) wait(deadline time.Time) error {
+	if !deadline.IsZero() {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return os.ErrDeadlineExceeded
+		}
+
+		t := time.AfterFunc(d, func() {
+			l.mu.Lock()
+			l.cond.Broadcast()
+			l.mu.Unlock()
+		})
+
+		defer t.Stop()
+	}
+
+	l.cond.Wait()
+	return nil
+}
+
+// Return the next PDU to send, or SYMM if there is none. If idle is set, the
+// link waits a bit for a PDU to be queued before falling back to SYMM. The
+// second return value indicates that the PDU terminates the link.
+func (l *LLCPLink) next(idle bool) ([]byte, bool) {
+	if idle {
+		l.mu.Lock()
+		empty := len(l.queue) == 0
+		l.mu.Unlock()
+
+		if empty {
+			t := time.NewTimer(llcpSymmDelay)
+			select {
+			case <-l.wake:
+			case <-t.C:
+			}
+
+			t.Stop()
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.queue) == 0 {
+		symm := llcpPDU{ptype: LLCPSymm}
+		return symm.marshal(), false
+	}
+
+	pdu := l.queue[0]
+	l.queue = l.queue[1:]
+
+	return pdu, l.closing && len(l.queue) == 0
+}
+
+// Run the link: exchange PDUs with the peer until the link is closed or the
+// DEP session fails.
+func (l *LLCPLink) run(first []byte) {
+	var err error
+	rx := first
+	stop := false
+
+	if l.initiator {
+		var tx []byte
+		tx, stop = l.next(false)
+		rx, err = l.dep.Exchange(tx)
+	}
+
+	for err == nil && !stop {
+		// the peer deactivated the link; the initiator releases the
+		// DEP session while the target keeps answering until it does
+		idle, down := l.dispatch(rx)
+		if down && l.initiator {
+			break
+		}
+
+		var tx []byte
+		tx, stop = l.next(idle && l.initiator)
+		rx, err = l.dep.Exchange(tx)
+	}
+
+	if l.initiator {
+		l.dep.Close()
+	}
+
+	if err == nil || err == Error(ETGRELEASED) {
+		err = net.ErrClosed
+	}
+
+	l.mu.Lock()
+	l.err = err
+	for _, c := range l.lookups {
+		close(c)
+	}
+
+	l.lookups = nil
+	l.cond.Broadcast()
+	l.mu.Unlock()
+
+	close(l.done)
+}
+
+// Process received PDU b. idle reports whether b was a SYMM, down whether
+// the peer deactivated the link.
+func (l *LLCPLink) dispatch(b []byte) (idle, down bool) {
+	p, err := parseLLCPPDU(b)
+	if err != nil {
+		return false, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	defer l.cond.Broadcast()
+
+	switch p.ptype {
+	case LLCPSymm:
+		return true, false
+
+	case LLCPAgf:
+		for info := p.info; len(info) >= 2; {
+			n := int(info[0])<<8 | int(info[1])
+			if len(info) < 2+n {
+				break
+			}
+
+			if sub, err := parseLLCPPDU(info[2 : 2+n]); err == nil {
+				if l.handle(sub) {
+					down = true
+				}
+			}
+
+			info = info[2+n:]
+		}
+
+		return false, down
+
+	default:
+		return false, l.handle(p)
+	}
+}
+
+// Handle a single PDU p. Returns true if the peer deactivated the link.
+// Must be called with l.mu held.
+func (l *LLCPLink) handle(p llcpPDU) bool {
+	switch p.ptype {
+	case LLCPUI:
+		if pc := l.packets[p.dsap]; pc != nil {
+			pc.deliver(p.ssap, p.info)
+		}
+
+	case LLCPConnect:
+		l.handleConnect(p)
+
+	case LLCPCC:
+		c := l.pending[p.dsap]
+		if c == nil {
+			// the connection was given up while waiting for CC
+			l.send(llcpPDU{dsap: p.ssap, ptype: LLCPDisc, ssap: p.dsap})
+			break
+		}
+
+		delete(l.pending, p.dsap)
+		c.remote = p.ssap
+		c.established(p.info)
+		l.conns[[2]byte{c.local, c.remote}] = c
+
+	case LLCPDM:
+		if c := l.pending[p.dsap]; c != nil {
+			delete(l.pending, p.dsap)
+			reason := byte(0)
+			if len(p.info) > 0 {
+				reason = p.info[0]
+			}
+
+			c.fail(fmt.Errorf("LLCP: connection refused (reason %#02x)", reason))
+		} else if c := l.conns[[2]byte{p.dsap, p.ssap}]; c != nil {
+			delete(l.conns, [2]byte{p.dsap, p.ssap})
+			c.fail(io.EOF)
+		}
+
+	case LLCPDisc:
+		if p.dsap == LLCPSAPLinkManagement && p.ssap == LLCPSAPLinkManagement {
+			return true
+		}
+
+		if c := l.conns[[2]byte{p.dsap, p.ssap}]; c != nil {
+			delete(l.conns, [2]byte{p.dsap, p.ssap})
+			c.fail(io.EOF)
+			l.send(llcpPDU{dsap: p.ssap, ptype: LLCPDM, ssap: p.dsap, info: []byte{LLCPDMDisconnected}})
+		} else {
+			l.send(llcpPDU{dsap: p.ssap, ptype: LLCPDM, ssap: p.dsap, info: []byte{LLCPDMNotActive}})
+		}
+
+	case LLCPI, LLCPRR, LLCPRNR:
+		c := l.conns[[2]byte{p.dsap, p.ssap}]
+		if c == nil {
+			l.send(llcpPDU{dsap: p.ssap, ptype: LLCPDM, ssap: p.dsap, info: []byte{LLCPDMNotActive}})
+			break
+		}
+
+		c.receive(p)
+
+	case LLCPSnl:
+		l.handleSNL(p)
+	}
+
+	return false
+}
+
+// Handle a CONNECT PDU. Must be called with l.mu held.
+func (l *LLCPLink) handleConnect(p llcpPDU) {
+	sap := p.dsap
+	tlvs, _ := parseTLVs(p.info)
+	if sap == LLCPSAPSDP {
+		sap = 0
+		for _, t := range tlvs {
+			if t.typ == LLCPParamSN {
+				sap = l.services[string(t.val)]
+			}
+		}
+	}
+
+	ln := l.listeners[sap]
+	if ln == nil || ln.closed {
+		l.send(llcpPDU{dsap: p.ssap, ptype: LLCPDM, ssap: p.dsap, info: []byte{LLCPDMNoService}})
+		return
+	}
+
+	c := newLLCPConn(l, sap, p.ssap, ln.service)
+	c.established(p.info)
+	l.conns[[2]byte{sap, p.ssap}] = c
+	ln.backlog = append(ln.backlog, c)
+
+	l.send(llcpPDU{dsap: p.ssap, ptype: LLCPCC, ssap: sap, info: l.connParams()})
+}
+
+// The parameters sent in CONNECT and CC.
+func (l *LLCPLink) connParams() []byte {
+	var b []byte
+	if l.Local.MIUX != 0 {
+		b = appendTLV(b, LLCPParamMIUX, byte(l.Local.MIUX>>8&0x07), byte(l.Local.MIUX))
+	}
+
+	return appendTLV(b, LLCPParamRW, llcpDefaultRW)
+}
+
+// Handle an SNL PDU: answer service discovery requests and deliver the
+// responses to our own requests. Must be called with l.mu held.
+func (l *LLCPLink) handleSNL(p llcpPDU) {
+	tlvs, err := parseTLVs(p.info)
+	if err != nil {
+		return
+	}
+
+	var res []byte
+	for _, t := range tlvs {
+		switch {
+		case t.typ == LLCPParamSDReq && len(t.val) >= 1:
+			res = appendTLV(res, LLCPParamSDRes, t.val[0], l.services[string(t.val[1:])])
+		case t.typ == LLCPParamSDRes && len(t.val) == 2:
+			if c := l.lookups[t.val[0]]; c != nil {
+				delete(l.lookups, t.val[0])
+				c <- t.val[1] & 0x3f
+			}
+		}
+	}
+
+	if res != nil {
+		l.send(llcpPDU{dsap: LLCPSAPSDP, ptype: LLCPSnl, ssap: LLCPSAPSDP, info: res})
+	}
+}
+
+// Allocate a free SAP in the range [lo, hi). Must be called with l.mu held.
+func (l *LLCPLink) allocSAP(lo, hi byte) (byte, error) {
+	for sap := lo; sap < hi; sap++ {
+		if l.listeners[sap] != nil || l.pending[sap] != nil || l.packets[sap] != nil {
+			continue
+		}
+
+		used := false
+		for k := range l.conns {
+			if k[0] == sap {
+				used = true
+				break
+			}
+		}
+
+		if !used {
+			return sap, nil
+		}
+	}
+
+	return 0, errors.New("LLCP: no free SAP")
+}
+
+// Resolve service name to a SAP on the peer using service discovery.
+// Returns an error if the peer does not offer the service. Resolve blocks
+// until the peer answers or the link goes down; use ResolveContext() to
+// give up earlier.
+func (l *LLCPLink) Resolve(service string) (byte, error) {
+	return l.ResolveContext(gocontext.Background(), service)
+}
+
+// Like Resolve(), but give up and return ctx.Err() once ctx is done.
+func (l *LLCPLink) ResolveContext(ctx gocontext.Context, service string) (byte, error) {
+	// the SDREQ value holds the tid and the name in at most 255 bytes
+	if len(service) > 254 {
+		return 0, Error(EINVARG)
+	}
+
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	l.mu.Lock()
+	if l.err != nil {
+		l.mu.Unlock()
+		return 0, l.err
+	}
+
+	c := make(chan byte, 1)
+	tid := l.tid
+	l.tid++
+	l.lookups[tid] = c
+
+	val := append([]byte{tid}, service...)
+	l.send(llcpPDU{dsap: LLCPSAPSDP, ptype: LLCPSnl, ssap: LLCPSAPSDP, info: appendTLV(nil, LLCPParamSDReq, val...)})
+	l.mu.Unlock()
+
+	var sap byte
+	var ok bool
+	select {
+	case sap, ok = <-c:
+	case <-ctx.Done():
+		l.mu.Lock()
+		if l.lookups[tid] == c {
+			delete(l.lookups, tid)
+		}
+		l.mu.Unlock()
+
+		return 0, ctx.Err()
+	}
+
+	if !ok {
+		return 0, net.ErrClosed
+	}
+
+	if sap == 0 {
+		return 0, fmt.Errorf("LLCP: service %q not found", service)
+	}
+
+	return sap, nil
+}
+
+// Deactivate the link. Open connections are closed.
+func (l *LLCPLink) Close() error {
+	l.mu.Lock()
+	if l.err == nil && !l.closing {
+		l.closing = true
+		l.send(llcpPDU{ptype: LLCPDisc})
+	}
+	l.mu.Unlock()
+
+	<-l.done
+	return nil
+}
+
+// Return a channel that is closed once the link is down.
+func (l *LLCPLink) Done() <-chan struct{} {
+	return l.done
+}
+
+// Return the error that brought the link down, or nil if it is still up.
+func (l *LLCPLink) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.err
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import gocontext "context"
+import "bytes"
+import "io"
+import "testing"
+import "time"
+
+// A depLink passing each frame received to tap.
+type depTap struct {
+	depLink
+	tap func(frame []byte)
+}
+
+func (d depTap) transceive(tx []byte) ([]byte, error) {
+	rx, err := d.depLink.transceive(tx)
+	if err == nil {
+		d.tap(rx)
+	}
+
+	return rx, err
+}
+
+// Make a pair of LLCP links connected back to back over simulated DEP
+// sessions.
+func llcpPipe(t *testing.T) (ini, tgt *LLCPLink) {
+	return llcpPipeTap(t, nil)
+}
+
+// Like llcpPipe(), but pass each DEP frame received by the initiator to
+// tap unless tap is nil.
+func llcpPipeTap(t *testing.T, tap func(frame []byte)) (ini, tgt *LLCPLink) {
+	p := LLCPParams{MIUX: 100}
+	gb := p.GeneralBytes()
+
+	dini, dtgt := depPipe(0x30)
+	dini.Remote.SetGeneralBytes(gb)
+	dtgt.Remote.SetGeneralBytes(gb)
+	if tap != nil {
+		dini.link = depTap{dini.link, tap}
+	}
+
+	ini, err := NewLLCPLink(dini, nil, p)
+	if err != nil {
+		t.Fatal("NewLLCPLink(initiator):", err)
+	}
+
+	frame, err := dtgt.link.transceive(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := dtgt.receive(frame)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tgt, err = NewLLCPLink(dtgt, first, p)
+	if err != nil {
+		t.Fatal("NewLLCPLink(target):", err)
+	}
+
+	return
+}
+
+// Check that the LLCP parameters survive encoding.
+func TestLLCPParams(t *testing.T) {
+	p := LLCPParams{Version: 0x11, MIUX: 0x123, WKS: 0x0013, LTO: 150, Opt: 0x03}
+	q, err := ParseLLCPParams(p.GeneralBytes())
+	if err != nil {
+		t.Fatal("ParseLLCPParams():", err)
+	}
+
+	if p != q {
+		t.Errorf("ParseLLCPParams() = %+v, want %+v", q, p)
+	}
+}
+
+// Transfer data over a connection established by service name in both
+// directions, then close it.
+func TestLLCPConn(t *testing.T) {
+	ini, tgt := llcpPipe(t)
+	defer ini.Close()
+
+	ln, err := tgt.Listen("urn:nfc:xsn:example.org:echo")
+	if err != nil {
+		t.Fatal("Listen():", err)
+	}
+
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error("Accept():", err)
+			return
+		}
+
+		io.Copy(c, c)
+		c.Close()
+	}()
+
+	c, err := ini.Dial("urn:nfc:xsn:example.org:echo")
+	if err != nil {
+		t.Fatal("Dial():", err)
+	}
+
+	msg := bytes.Repeat([]byte("0123456789"), 100)
+	if _, err = c.Write(msg); err != nil {
+		t.Fatal("Write():", err)
+	}
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, len(msg))
+	if _, err = io.ReadFull(c, buf); err != nil {
+		t.Fatal("ReadFull():", err)
+	}
+
+	if !bytes.Equal(buf, msg) {
+		t.Errorf("echo mismatch: got %q", buf)
+	}
+
+	c.Close()
+
+	if _, err = ini.Dial("urn:nfc:xsn:example.org:nonexistent"); err == nil {
+		t.Error("Dial() to nonexistent service succeeded")
+	}
+}
+
+// Exchange datagrams and resolve a service name.
+func TestLLCPPacket(t *testing.T) {
+	ini, tgt := llcpPipe(t)
+
+	pc, err := tgt.ListenPacket("urn:nfc:xsn:example.org:dgram")
+	if err != nil {
+		t.Fatal("ListenPacket():", err)
+	}
+
+	sap, err := ini.Resolve("urn:nfc:xsn:example.org:dgram")
+	if err != nil {
+		t.Fatal("Resolve():", err)
+	}
+
+	if sap != pc.LocalAddr().(*LLCPAddr).SAP {
+		t.Errorf("Resolve() = %d, want %v", sap, pc.LocalAddr())
+	}
+
+	// the name and the tid must fit into the 255 bytes of an SDREQ
+	if _, err = ini.Resolve(string(make([]byte, 255))); err != Error(EINVARG) {
+		t.Errorf("Resolve() with 255 byte name: got error %v, want %v", err, Error(EINVARG))
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if _, err = ini.ResolveContext(ctx, "urn:nfc:xsn:example.org:dgram"); err != gocontext.Canceled {
+		t.Errorf("ResolveContext() with cancelled context: got error %v, want %v", err, gocontext.Canceled)
+	}
+
+	ipc, err := ini.ListenPacket("")
+	if err != nil {
+		t.Fatal("ListenPacket():", err)
+	}
+
+	if _, err = ipc.WriteTo([]byte("hello"), &LLCPAddr{SAP: sap}); err != nil {
+		t.Fatal("WriteTo():", err)
+	}
+
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, 16)
+	n, addr, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatal("ReadFrom():", err)
+	}
+
+	if string(buf[:n]) != "hello" || addr.(*LLCPAddr).SAP != ipc.LocalAddr().(*LLCPAddr).SAP {
+		t.Errorf("ReadFrom() = %q from %v", buf[:n], addr)
+	}
+
+	tgt.Close()
+	<-ini.Done()
+}
+
+// An I PDU with the wrong N(S) must be answered with FRMR carrying the S
+// flag and end the connection.
+func TestLLCPSequenceError(t *testing.T) {
+	frmr := make(chan llcpPDU, 1)
+	ini, tgt := llcpPipeTap(t, func(frame []byte) {
+		// DEP_RES header: CMD0, CMD1, PFB
+		if len(frame) < 3 {
+			return
+		}
+
+		if p, err := parseLLCPPDU(frame[3:]); err == nil && p.ptype == LLCPFrmr {
+			select {
+			case frmr <- p:
+			default:
+			}
+		}
+	})
+	defer ini.Close()
+
+	ln, err := tgt.Listen("urn:nfc:xsn:example.org:seq")
+	if err != nil {
+		t.Fatal("Listen():", err)
+	}
+
+	accepted := make(chan *LLCPConn, 1)
+	go func() {
+		c, err := ln.Accept()
+		if err != nil {
+			t.Error("Accept():", err)
+			close(accepted)
+			return
+		}
+
+		accepted <- c.(*LLCPConn)
+	}()
+
+	c, err := ini.Dial("urn:nfc:xsn:example.org:seq")
+	if err != nil {
+		t.Fatal("Dial():", err)
+	}
+
+	tc := <-accepted
+	if tc == nil {
+		return
+	}
+
+	// the target expects N(S) = 0
+	ini.mu.Lock()
+	ini.send(llcpPDU{dsap: c.remote, ptype: LLCPI, ssap: c.local, seq: 3 << 4, info: []byte("x")})
+	ini.mu.Unlock()
+
+	select {
+	case p := <-frmr:
+		want := []byte{0x10 | LLCPI, 3 << 4, 0x00, 0x00}
+		if p.dsap != c.local || p.ssap != c.remote || !bytes.Equal(p.info, want) {
+			t.Errorf("got FRMR %d -> %d % x, want %d -> %d % x", p.ssap, p.dsap, p.info, c.remote, c.local, want)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no FRMR received")
+	}
+
+	tc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err = tc.Read(make([]byte, 1)); err == nil {
+		t.Error("Read() after sequence error succeeded")
+	}
+}
+
+// DialContext() gives up once its context is done. The peer's CC arriving
+// later is answered with DISC, which closes the connection on its side.
+func TestLLCPDialContext(t *testing.T) {
+	ini, tgt := llcpPipe(t)
+	defer ini.Close()
+
+	ln, err := tgt.Listen("urn:nfc:xsn:example.org:slow")
+	if err != nil {
+		t.Fatal("Listen():", err)
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if _, err = ini.DialContext(ctx, "urn:nfc:xsn:example.org:slow"); err != gocontext.Canceled {
+		t.Errorf("DialContext() with cancelled context: got error %v, want %v", err, gocontext.Canceled)
+	}
+
+	// keep the target from answering CONNECT
+	tgt.mu.Lock()
+	ctx, cancel = gocontext.WithTimeout(gocontext.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = ini.DialContext(ctx, "urn:nfc:xsn:example.org:slow")
+	tgt.mu.Unlock()
+	if err != gocontext.DeadlineExceeded {
+		t.Fatalf("DialContext() to silent peer: got error %v, want %v", err, gocontext.DeadlineExceeded)
+	}
+
+	c, err := ln.Accept()
+	if err != nil {
+		t.Fatal("Accept():", err)
+	}
+
+	c.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err = c.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read() on abandoned connection: got error %v, want %v", err, io.EOF)
+	}
+}
@@ -0,0 +1,619 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+// the package has its own type called context
+import gocontext "context"
+import "errors"
+import "fmt"
+import "io"
+import "net"
+import "time"
+
+// Well-known service names.
+const (
+	LLCPServiceSDP  = "urn:nfc:sn:sdp"
+	LLCPServiceSNEP = "urn:nfc:sn:snep"
+)
+
+// LLCPAddr is the address of an LLCP endpoint, a service access point.
+// LLCPAddr implements net.Addr.
+type LLCPAddr struct {
+	SAP     byte   // service access point
+	Service string // service name if known
+}
+
+// Always "llcp".
+func (a *LLCPAddr) Network() string {
+	return "llcp"
+}
+
+// Print the address as "SAP" or "SAP(service)".
+func (a *LLCPAddr) String() string {
+	if a.Service == "" {
+		return fmt.Sprint(a.SAP)
+	}
+
+	return fmt.Sprintf("%d(%s)", a.SAP, a.Service)
+}
+
+// LLCPConn is a connection-oriented LLCP data link connection. LLCPConn
+// implements net.Conn.
+type LLCPConn struct {
+	link          *LLCPLink
+	local, remote byte
+	service       string
+	connected     bool
+	err           error // set once the connection is closed
+	miu           int   // MIU of the peer
+	rw            int   // receive window of the peer
+	vs, vsa, vr   byte  // send, send acknowledged and receive state variables
+	busy          bool  // peer sent RNR
+	rbuf          []byte
+
+	readDeadline, writeDeadline time.Time
+}
+
+// Make a new connection on l. Must be called with l.mu held.
+func newLLCPConn(l *LLCPLink, local, remote byte, service string) *LLCPConn {
+	return &LLCPConn{
+		link:    l,
+		local:   local,
+		remote:  remote,
+		service: service,
+		miu:     llcpDefaultMIU,
+		rw:      llcpDefaultRW,
+	}
+}
+
+// Mark c as connected, taking the peer's parameters from the CONNECT or CC
+// PDU information field info. Must be called with l.mu held.
+func (c *LLCPConn) established(info []byte) {
+	tlvs, _ := parseTLVs(info)
+	for _, t := range tlvs {
+		switch {
+		case t.typ == LLCPParamMIUX && len(t.val) == 2:
+			c.miu = llcpDefaultMIU + (int(t.val[0]&0x07)<<8 | int(t.val[1]))
+		case t.typ == LLCPParamRW && len(t.val) == 1:
+			c.rw = int(t.val[0] & 0x0f)
+		}
+	}
+
+	c.connected = true
+}
+
+// Terminate c with err unless it already is. Must be called with l.mu held.
+func (c *LLCPConn) fail(err error) {
+	if c.err == nil {
+		c.err = err
+	}
+}
+
+// Process an I, RR or RNR PDU. Must be called with l.mu held.
+func (c *LLCPConn) receive(p llcpPDU) {
+	if p.ptype == LLCPI {
+		if p.seq>>4 != c.vr {
+			// sequence error: reject the frame with the S flag (invalid
+			// N(S)) and drop the connection
+			c.link.send(llcpPDU{dsap: c.remote, ptype: LLCPFrmr, ssap: c.local,
+				info: []byte{0x10 | p.ptype, p.seq, c.vs<<4 | c.vr, c.vsa<<4 | c.vr}})
+			delete(c.link.conns, [2]byte{c.local, c.remote})
+			c.fail(errors.New("LLCP: sequence error"))
+			return
+		}
+
+		c.vr = (c.vr + 1) & 0xf
+		c.rbuf = append(c.rbuf, p.info...)
+		c.link.send(llcpPDU{dsap: c.remote, ptype: LLCPRR, ssap: c.local, seq: c.vr})
+	}
+
+	c.vsa = p.seq & 0xf
+	c.busy = p.ptype == LLCPRNR
+}
+
+// Read data from the connection. Returns io.EOF once the peer has closed the
+// connection and all data has been read.
+func (c *LLCPConn) Read(b []byte) (int, error) {
+	l := c.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(c.rbuf) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+
+		if l.err != nil {
+			return 0, l.err
+		}
+
+		if err := l.wait(c.readDeadline); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(b, c.rbuf)
+	c.rbuf = c.rbuf[n:]
+
+	return n, nil
+}
+
+// Write data to the connection. Data is split into I PDUs no larger than the
+// MIU of the peer. Write blocks while the receive window of the peer is
+// full.
+func (c *LLCPConn) Write(b []byte) (int, error) {
+	l := c.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	n := 0
+	for n < len(b) {
+		for c.busy || int((c.vs-c.vsa)&0xf) >= c.rw {
+			if c.err != nil {
+				return n, c.err
+			}
+
+			if l.err != nil {
+				return n, l.err
+			}
+
+			if err := l.wait(c.writeDeadline); err != nil {
+				return n, err
+			}
+		}
+
+		if c.err != nil {
+			return n, c.err
+		}
+
+		if l.err != nil {
+			return n, l.err
+		}
+
+		chunk := b[n:]
+		if len(chunk) > c.miu {
+			chunk = chunk[:c.miu]
+		}
+
+		l.send(llcpPDU{
+			dsap:  c.remote,
+			ptype: LLCPI,
+			ssap:  c.local,
+			seq:   c.vs<<4 | c.vr,
+			info:  append([]byte(nil), chunk...),
+		})
+
+		c.vs = (c.vs + 1) & 0xf
+		n += len(chunk)
+	}
+
+	return n, nil
+}
+
+// Close the connection by sending DISC.
+func (c *LLCPConn) Close() error {
+	l := c.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if c.err != nil && c.err != io.EOF {
+		return c.err
+	}
+
+	if c.err == nil && l.err == nil {
+		l.send(llcpPDU{dsap: c.remote, ptype: LLCPDisc, ssap: c.local})
+	}
+
+	delete(l.conns, [2]byte{c.local, c.remote})
+	c.err = net.ErrClosed
+	l.cond.Broadcast()
+
+	return nil
+}
+
+// Return the local address of the connection.
+func (c *LLCPConn) LocalAddr() net.Addr {
+	return &LLCPAddr{SAP: c.local}
+}
+
+// Return the remote address of the connection.
+func (c *LLCPConn) RemoteAddr() net.Addr {
+	return &LLCPAddr{SAP: c.remote, Service: c.service}
+}
+
+// Set the read and write deadlines of the connection.
+func (c *LLCPConn) SetDeadline(t time.Time) error {
+	c.SetReadDeadline(t)
+	return c.SetWriteDeadline(t)
+}
+
+// Set the read deadline of the connection.
+func (c *LLCPConn) SetReadDeadline(t time.Time) error {
+	c.link.mu.Lock()
+	c.readDeadline = t
+	c.link.cond.Broadcast()
+	c.link.mu.Unlock()
+
+	return nil
+}
+
+// Set the write deadline of the connection.
+func (c *LLCPConn) SetWriteDeadline(t time.Time) error {
+	c.link.mu.Lock()
+	c.writeDeadline = t
+	c.link.cond.Broadcast()
+	c.link.mu.Unlock()
+
+	return nil
+}
+
+// Connect to the service with the given name on the peer. The name is
+// resolved by the peer's service discovery component. Dial blocks until the
+// peer accepts or refuses the connection or the link goes down; use
+// DialContext() to give up earlier.
+func (l *LLCPLink) Dial(service string) (*LLCPConn, error) {
+	return l.DialContext(gocontext.Background(), service)
+}
+
+// Like Dial(), but give up and return ctx.Err() once ctx is done.
+func (l *LLCPLink) DialContext(ctx gocontext.Context, service string) (*LLCPConn, error) {
+	if service == "" || len(service) > 255 {
+		return nil, Error(EINVARG)
+	}
+
+	return l.dial(ctx, LLCPSAPSDP, service)
+}
+
+// Connect to the service bound to sap on the peer.
+func (l *LLCPLink) DialSAP(sap byte) (*LLCPConn, error) {
+	return l.DialSAPContext(gocontext.Background(), sap)
+}
+
+// Like DialSAP(), but give up and return ctx.Err() once ctx is done.
+func (l *LLCPLink) DialSAPContext(ctx gocontext.Context, sap byte) (*LLCPConn, error) {
+	if sap < 2 || sap > 63 {
+		return nil, Error(EINVARG)
+	}
+
+	return l.dial(ctx, sap, "")
+}
+
+func (l *LLCPLink) dial(ctx gocontext.Context, dsap byte, service string) (*LLCPConn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	local, err := l.allocSAP(32, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	c := newLLCPConn(l, local, dsap, service)
+	l.pending[local] = c
+
+	info := l.connParams()
+	if service != "" {
+		info = appendTLV(info, LLCPParamSN, []byte(service)...)
+	}
+
+	l.send(llcpPDU{dsap: dsap, ptype: LLCPConnect, ssap: local, info: info})
+
+	// wake up the loop below once ctx is done
+	if done := ctx.Done(); done != nil {
+		stop := make(chan struct{})
+		defer close(stop)
+
+		go func() {
+			select {
+			case <-done:
+				l.mu.Lock()
+				l.cond.Broadcast()
+				l.mu.Unlock()
+			case <-stop:
+			}
+		}()
+	}
+
+	for !c.connected {
+		if c.err != nil {
+			return nil, c.err
+		}
+
+		if l.err != nil {
+			delete(l.pending, local)
+			return nil, l.err
+		}
+
+		// a CC arriving later is answered with DISC
+		if err := ctx.Err(); err != nil {
+			delete(l.pending, local)
+			return nil, err
+		}
+
+		l.cond.Wait()
+	}
+
+	return c, nil
+}
+
+// LLCPListener accepts incoming connections for a service. LLCPListener
+// implements net.Listener.
+type LLCPListener struct {
+	link    *LLCPLink
+	sap     byte
+	service string
+	backlog []*LLCPConn
+	closed  bool
+}
+
+// Choose the SAP for service. Well-known services are bound to their
+// well-known SAP, other named services to a SAP between 16 and 31 and
+// unnamed ones to a SAP between 32 and 63. Must be called with l.mu held.
+func (l *LLCPLink) bindSAP(service string) (byte, error) {
+	switch service {
+	case "":
+		return l.allocSAP(32, 64)
+	case LLCPServiceSNEP:
+		if l.listeners[LLCPSAPSNEP] != nil || l.packets[LLCPSAPSNEP] != nil {
+			return 0, errors.New("LLCP: service already bound")
+		}
+
+		return LLCPSAPSNEP, nil
+	default:
+		if _, ok := l.services[service]; ok {
+			return 0, errors.New("LLCP: service already bound")
+		}
+
+		return l.allocSAP(16, 32)
+	}
+}
+
+// Listen for incoming connections to service. If service is the empty
+// string, the listener can only be reached by its SAP, see
+// LLCPListener.Addr().
+func (l *LLCPLink) Listen(service string) (*LLCPListener, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	sap, err := l.bindSAP(service)
+	if err != nil {
+		return nil, err
+	}
+
+	ln := &LLCPListener{link: l, sap: sap, service: service}
+	l.listeners[sap] = ln
+	if service != "" {
+		l.services[service] = sap
+	}
+
+	return ln, nil
+}
+
+// Wait for and return the next connection to the listener.
+func (ln *LLCPListener) Accept() (net.Conn, error) {
+	l := ln.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(ln.backlog) == 0 {
+		if ln.closed {
+			return nil, net.ErrClosed
+		}
+
+		if l.err != nil {
+			return nil, l.err
+		}
+
+		l.cond.Wait()
+	}
+
+	c := ln.backlog[0]
+	ln.backlog = ln.backlog[1:]
+
+	return c, nil
+}
+
+// Stop listening. Connections that have not been accepted yet are closed.
+func (ln *LLCPListener) Close() error {
+	l := ln.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ln.closed {
+		return net.ErrClosed
+	}
+
+	ln.closed = true
+	delete(l.listeners, ln.sap)
+	if ln.service != "" {
+		delete(l.services, ln.service)
+	}
+
+	for _, c := range ln.backlog {
+		l.send(llcpPDU{dsap: c.remote, ptype: LLCPDisc, ssap: c.local})
+		delete(l.conns, [2]byte{c.local, c.remote})
+		c.err = net.ErrClosed
+	}
+
+	ln.backlog = nil
+	l.cond.Broadcast()
+
+	return nil
+}
+
+// Return the address the listener is bound to.
+func (ln *LLCPListener) Addr() net.Addr {
+	return &LLCPAddr{SAP: ln.sap, Service: ln.service}
+}
+
+// A datagram received on an LLCPPacketConn.
+type llcpPacket struct {
+	ssap byte
+	data []byte
+}
+
+// LLCPPacketConn is an endpoint for connectionless LLCP communication with
+// UI PDUs. LLCPPacketConn implements net.PacketConn.
+type LLCPPacketConn struct {
+	link    *LLCPLink
+	sap     byte
+	service string
+	queue   []llcpPacket
+	closed  bool
+
+	readDeadline time.Time
+}
+
+// Listen for connectionless traffic to service. See Listen() for how the SAP
+// is chosen.
+func (l *LLCPLink) ListenPacket(service string) (*LLCPPacketConn, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.err != nil {
+		return nil, l.err
+	}
+
+	sap, err := l.bindSAP(service)
+	if err != nil {
+		return nil, err
+	}
+
+	pc := &LLCPPacketConn{link: l, sap: sap, service: service}
+	l.packets[sap] = pc
+	if service != "" {
+		l.services[service] = sap
+	}
+
+	return pc, nil
+}
+
+// Queue a received datagram. Must be called with l.mu held.
+func (pc *LLCPPacketConn) deliver(ssap byte, data []byte) {
+	pc.queue = append(pc.queue, llcpPacket{ssap, append([]byte(nil), data...)})
+}
+
+// Read a datagram. If b is too short, the rest of the datagram is
+// discarded.
+func (pc *LLCPPacketConn) ReadFrom(b []byte) (int, net.Addr, error) {
+	l := pc.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for len(pc.queue) == 0 {
+		if pc.closed {
+			return 0, nil, net.ErrClosed
+		}
+
+		if l.err != nil {
+			return 0, nil, l.err
+		}
+
+		if err := l.wait(pc.readDeadline); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	p := pc.queue[0]
+	pc.queue = pc.queue[1:]
+
+	return copy(b, p.data), &LLCPAddr{SAP: p.ssap}, nil
+}
+
+// Send datagram b to addr, which must be an *LLCPAddr. b must not be longer
+// than the MIU of the peer.
+func (pc *LLCPPacketConn) WriteTo(b []byte, addr net.Addr) (int, error) {
+	a, ok := addr.(*LLCPAddr)
+	if !ok || a.SAP > 63 {
+		return 0, Error(EINVARG)
+	}
+
+	l := pc.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if pc.closed {
+		return 0, net.ErrClosed
+	}
+
+	if l.err != nil {
+		return 0, l.err
+	}
+
+	if len(b) > l.Remote.MIU() {
+		return 0, errors.New("LLCP: datagram exceeds MIU")
+	}
+
+	l.send(llcpPDU{dsap: a.SAP, ptype: LLCPUI, ssap: pc.sap, info: append([]byte(nil), b...)})
+
+	return len(b), nil
+}
+
+// Stop receiving datagrams.
+func (pc *LLCPPacketConn) Close() error {
+	l := pc.link
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if pc.closed {
+		return net.ErrClosed
+	}
+
+	pc.closed = true
+	delete(l.packets, pc.sap)
+	if pc.service != "" {
+		delete(l.services, pc.service)
+	}
+
+	l.cond.Broadcast()
+
+	return nil
+}
+
+// Return the address the endpoint is bound to.
+func (pc *LLCPPacketConn) LocalAddr() net.Addr {
+	return &LLCPAddr{SAP: pc.sap, Service: pc.service}
+}
+
+// Set the read deadline. Writes never block.
+func (pc *LLCPPacketConn) SetDeadline(t time.Time) error {
+	return pc.SetReadDeadline(t)
+}
+
+// Set the read deadline.
+func (pc *LLCPPacketConn) SetReadDeadline(t time.Time) error {
+	pc.link.mu.Lock()
+	pc.readDeadline = t
+	pc.link.cond.Broadcast()
+	pc.link.mu.Unlock()
+
+	return nil
+}
+
+// Writes never block, so this function does nothing.
+func (pc *LLCPPacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "fmt"
+import "sync"
+import "time"
+
+// Kind of a DeviceEvent.
+type DeviceEventKind int
+
+const (
+	DeviceAdded   DeviceEventKind = iota // a device has been opened
+	DeviceRemoved                        // a device has been closed
+)
+
+func (k DeviceEventKind) String() string {
+	switch k {
+	case DeviceAdded:
+		return "added"
+	case DeviceRemoved:
+		return "removed"
+	default:
+		return fmt.Sprintf("DeviceEventKind(%d)", int(k))
+	}
+}
+
+// DeviceEvent is sent by a Manager whenever it adds or removes a device.
+type DeviceEvent struct {
+	Kind       DeviceEventKind
+	Connstring string // connection string the device was opened with
+	Device     Device // the device, already closed for DeviceRemoved
+	Err        error  // the error that caused the removal, if any
+}
+
+// Number of events buffered by a Manager.
+const managerEventBuffer = 16
+
+// Manager keeps track of all NFC devices attached to the system. It
+// periodically rescans with ListDevices(), opens devices that show up and
+// reports them on its Events() channel. When a call on one of its devices
+// fails with EIO or ENOTSUCHDEV, e.g. because the reader was unplugged, the
+// device is closed and reported as removed. Once the reader is found by
+// a later rescan, it is opened and reported again.
+//
+// Some libnfc drivers do not list devices that are currently open, so a
+// device is only ever removed because of an error, never because it is
+// missing from a rescan. A reader that is unplugged while idle is thus only
+// noticed once the application tries to use it. Closing a device obtained
+// from the Manager removes it, too; it is added again by the next rescan.
+type Manager struct {
+	interval time.Duration
+	list     func() ([]string, error)
+	open     func(string) (Device, error)
+
+	mu      sync.Mutex
+	devices map[string]*managedDevice
+	pending []DeviceEvent // events not yet sent
+	err     error         // error of the last rescan
+	events  chan DeviceEvent
+	wake    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+
+	stopOnce sync.Once
+}
+
+// Make a new Manager rescanning every interval and start it. The first scan
+// is performed immediately. Call Close() to stop the Manager and close its
+// devices.
+func NewManager(interval time.Duration) *Manager {
+	return newManager(interval, ListDevices, Open)
+}
+
+// Make a new Manager using the given functions to list and open devices.
+func newManager(interval time.Duration, list func() ([]string, error), open func(string) (Device, error)) *Manager {
+	m := &Manager{
+		interval: interval,
+		list:     list,
+		open:     open,
+		devices:  make(map[string]*managedDevice),
+		events:   make(chan DeviceEvent, managerEventBuffer),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go m.run()
+
+	return m
+}
+
+// Return the channel on which events are sent. It is closed when the
+// Manager is closed. Events must be received promptly; the Manager does
+// not rescan while it waits for an event to be received.
+func (m *Manager) Events() <-chan DeviceEvent {
+	return m.events
+}
+
+// Return the currently open devices by connection string.
+func (m *Manager) Devices() map[string]Device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	devices := make(map[string]Device, len(m.devices))
+	for conn, md := range m.devices {
+		devices[conn] = md.device()
+	}
+
+	return devices
+}
+
+// Return the error of the last rescan, or nil if it succeeded.
+func (m *Manager) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+// Rescan immediately instead of waiting for the interval to elapse.
+func (m *Manager) Rescan() {
+	select {
+	case m.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Stop the Manager and close all its devices. No DeviceRemoved events are
+// sent for them.
+func (m *Manager) Close() error {
+	m.stopOnce.Do(func() { close(m.stop) })
+	<-m.done
+
+	m.mu.Lock()
+	devices := m.devices
+	m.devices = make(map[string]*managedDevice)
+	m.mu.Unlock()
+
+	var err error
+	for _, md := range devices {
+		if e := md.d.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// Rescan periodically and send pending events until stopped.
+func (m *Manager) run() {
+	defer close(m.done)
+	defer close(m.events)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.rescan()
+		if !m.flush() {
+			return
+		}
+
+		select {
+		case <-ticker.C:
+		case <-m.wake:
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Open all devices found by the device list that are not open yet.
+func (m *Manager) rescan() {
+	conns, err := m.list()
+
+	m.mu.Lock()
+	m.err = err
+	m.mu.Unlock()
+
+	for _, conn := range conns {
+		m.mu.Lock()
+		_, ok := m.devices[conn]
+		m.mu.Unlock()
+		if ok {
+			continue
+		}
+
+		// devices that fail to open are retried with the next rescan
+		d, err := m.open(conn)
+		if err != nil {
+			continue
+		}
+
+		md := &managedDevice{m: m, conn: conn, d: d, state: d.state.wrap()}
+		m.mu.Lock()
+		m.devices[conn] = md
+		m.pending = append(m.pending, DeviceEvent{Kind: DeviceAdded, Connstring: conn, Device: md.device()})
+		m.mu.Unlock()
+	}
+}
+
+// Send pending events. Return false if the Manager was stopped meanwhile.
+func (m *Manager) flush() bool {
+	for {
+		m.mu.Lock()
+		if len(m.pending) == 0 {
+			m.mu.Unlock()
+			return true
+		}
+
+		ev := m.pending[0]
+		m.pending = m.pending[1:]
+		m.mu.Unlock()
+
+		select {
+		case m.events <- ev:
+		case <-m.stop:
+			return false
+		}
+	}
+}
+
+// Report md as removed because of err, closing it unless err is nil, i.e.
+// unless the application closed it. Does nothing if md has already been
+// removed.
+func (m *Manager) remove(md *managedDevice, err error) {
+	m.mu.Lock()
+	if m.devices[md.conn] != md {
+		m.mu.Unlock()
+		return
+	}
+
+	delete(m.devices, md.conn)
+	m.mu.Unlock()
+
+	// closing a hung device may block, so do it without holding m.mu
+	if err != nil {
+		md.d.Close()
+	}
+
+	m.mu.Lock()
+	m.pending = append(m.pending, DeviceEvent{Kind: DeviceRemoved, Connstring: md.conn, Device: md.device(), Err: err})
+	m.mu.Unlock()
+
+	// deliver the event right away
+	m.Rescan()
+}
+
+// A backend watching a device of a Manager for errors.
+type managedDevice struct {
+	m     *Manager
+	conn  string
+	d     Device
+	state *deviceState // state of the Device handed out
+}
+
+// Return the Device handed out to the application.
+func (md *managedDevice) device() Device {
+	return Device{d: md.d.d, state: md.state, b: md}
+}
+
+func (md *managedDevice) call(c *deviceCall, do func(d Device)) error {
+	do(md.d)
+
+	if c.Op == "Close" {
+		md.m.remove(md, nil)
+	} else if e, ok := c.err().(Error); ok && (e == EIO || e == ENOTSUCHDEV) {
+		md.m.remove(md, e)
+	}
+
+	return nil
+}
+
+func (md *managedDevice) abort() error {
+	return md.d.AbortCommand()
+}
+
+func (md *managedDevice) name() string {
+	return md.d.String()
+}
+
+func (md *managedDevice) connection() string {
+	return md.d.Connection()
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "sync"
+import "testing"
+import "time"
+
+// Receive the next event from m or fail after a timeout.
+func nextEvent(t *testing.T, m *Manager) DeviceEvent {
+	select {
+	case ev, ok := <-m.Events():
+		if !ok {
+			t.Fatal("event channel closed")
+		}
+
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("timeout waiting for event")
+	}
+
+	panic("unreachable")
+}
+
+// Unplug a device and check that it is removed and reopened.
+func TestManager(t *testing.T) {
+	var mu sync.Mutex
+	opened := 0
+	open := func(conn string) (Device, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		opened++
+		if opened == 1 {
+			// the first device goes away
+			return replayCalls(t, []deviceCall{
+				{Op: "InitiatorInit", callResult: callResult{Err: EIO}},
+				{Op: "Close"},
+			}), nil
+		}
+
+		return replayCalls(t, []deviceCall{{Op: "Close"}}), nil
+	}
+
+	list := func() ([]string, error) {
+		return []string{"fake:0"}, nil
+	}
+
+	m := newManager(time.Hour, list, open)
+
+	ev := nextEvent(t, m)
+	if ev.Kind != DeviceAdded || ev.Connstring != "fake:0" {
+		t.Fatalf("got event %v %s, want added fake:0", ev.Kind, ev.Connstring)
+	}
+
+	if err := ev.Device.InitiatorInit(); err != Error(EIO) {
+		t.Fatalf("InitiatorInit() = %v, want EIO", err)
+	}
+
+	if len(m.Devices()) != 0 {
+		t.Error("failed device not removed")
+	}
+
+	ev = nextEvent(t, m)
+	if ev.Kind != DeviceRemoved || ev.Err != Error(EIO) {
+		t.Fatalf("got event %v %v, want removed EIO", ev.Kind, ev.Err)
+	}
+
+	ev = nextEvent(t, m)
+	if ev.Kind != DeviceAdded || ev.Connstring != "fake:0" {
+		t.Fatalf("got event %v %s, want added fake:0", ev.Kind, ev.Connstring)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Error("Close():", err)
+	}
+
+	if _, ok := <-m.Events(); ok {
+		t.Error("event channel not closed")
+	}
+}
+
+// Closing a Manager concurrently must not panic.
+func TestManagerCloseConcurrent(t *testing.T) {
+	list := func() ([]string, error) { return nil, nil }
+	open := func(conn string) (Device, error) { return Device{}, Error(ENOTSUCHDEV) }
+	m := newManager(time.Hour, list, open)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.Close()
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,181 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "fmt"
+import "strings"
+
+// Look up the name of a modulation type as found in ModulationTypes,
+// ignoring case. Return -1 if it is not known.
+func modulationTypeByName(name string) int {
+	for i, n := range ModulationTypes {
+		if i != 0 && strings.EqualFold(n, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Look up the name of a baud rate as found in BaudRates, ignoring case.
+// Return -1 if it is not known.
+func baudRateByName(name string) int {
+	for i, n := range BaudRates {
+		if strings.EqualFold(n, name) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// Parse a baud rate as found in BaudRates, e.g. "212". Case is ignored and
+// a unit of "k", "kbps" or "kbit/s" may follow the number.
+func ParseBaudRate(s string) (int, error) {
+	name := strings.TrimSpace(s)
+	lower := strings.ToLower(name)
+	for _, unit := range []string{"kbps", "kbit/s", "k"} {
+		if strings.HasSuffix(lower, unit) {
+			name = strings.TrimSpace(name[:len(name)-len(unit)])
+			break
+		}
+	}
+
+	nbr := baudRateByName(name)
+	if nbr < 0 {
+		return 0, fmt.Errorf("unknown baud rate %q (want one of %s)", s, strings.Join(BaudRates[Nbr106:], ", "))
+	}
+
+	return nbr, nil
+}
+
+// Parse a modulation type as found in ModulationTypes, ignoring case.
+func parseModulationType(s string) (int, error) {
+	nmt := modulationTypeByName(strings.TrimSpace(s))
+	if nmt < 0 {
+		return 0, fmt.Errorf("unknown modulation type %q (want one of %s)", s, strings.Join(ModulationTypes[1:], ", "))
+	}
+
+	return nmt, nil
+}
+
+// The baud rate used for modulation type nmt if none is given.
+func defaultBaudRate(nmt int) int {
+	if nmt == Felica {
+		return Nbr212
+	}
+
+	return Nbr106
+}
+
+// Split s into modulation type and baud rate at the first '@' or ':'.
+// baud is empty if there is no separator.
+func splitModulation(s string) (nmt, baud string, ok bool) {
+	i := strings.IndexAny(s, "@:")
+	if i < 0 {
+		return s, "", false
+	}
+
+	return s[:i], s[i+1:], true
+}
+
+// Parse a modulation of the form type@baudrate, e.g. "ISO14443a@106" or
+// "felica:212", as produced by Modulation.MarshalText(). Names are looked
+// up in ModulationTypes and BaudRates ignoring case; see ParseBaudRate()
+// for the syntax of the baud rate. If the baud rate is omitted, 212 kbps is
+// used for FeliCa and 106 kbps for all other modulation types.
+func ParseModulation(s string) (Modulation, error) {
+	typ, baud, hasBaud := splitModulation(s)
+	nmt, err := parseModulationType(typ)
+	if err != nil {
+		return Modulation{}, err
+	}
+
+	if !hasBaud {
+		return Modulation{nmt, defaultBaudRate(nmt)}, nil
+	}
+
+	nbr, err := ParseBaudRate(baud)
+	if err != nil {
+		return Modulation{}, err
+	}
+
+	return Modulation{nmt, nbr}, nil
+}
+
+// Parse a comma separated list of modulations as understood by
+// ParseModulation(). Additional baud rates for the same modulation type
+// may follow a modulation, e.g. "felica:212,424,ISO14443a@106" is FeliCa
+// at 212 and 424 kbps and ISO14443a at 106 kbps.
+func ParseModulationList(s string) ([]Modulation, error) {
+	var list []Modulation
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			return nil, fmt.Errorf("empty modulation in list %q", s)
+		}
+
+		// an additional baud rate for the previous modulation type
+		if nbr, err := ParseBaudRate(item); err == nil {
+			if len(list) == 0 {
+				return nil, fmt.Errorf("baud rate %q without modulation type", item)
+			}
+
+			list = append(list, Modulation{list[len(list)-1].Type, nbr})
+			continue
+		}
+
+		m, err := ParseModulation(item)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, m)
+	}
+
+	return list, nil
+}
+
+// ModulationList is a list of modulations implementing flag.Value for use
+// with flag.Var(). Each occurrence of the flag appends the modulations in
+// its argument, parsed with ParseModulationList().
+type ModulationList []Modulation
+
+// Format the list in the syntax understood by ParseModulationList().
+func (l *ModulationList) String() string {
+	if l == nil {
+		return ""
+	}
+
+	items := make([]string, len(*l))
+	for i, m := range *l {
+		text, _ := m.MarshalText()
+		items[i] = string(text)
+	}
+
+	return strings.Join(items, ",")
+}
+
+// Parse s with ParseModulationList() and append the result to l.
+func (l *ModulationList) Set(s string) error {
+	list, err := ParseModulationList(s)
+	if err != nil {
+		return err
+	}
+
+	*l = append(*l, list...)
+	return nil
+}
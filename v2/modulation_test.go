@@ -0,0 +1,76 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "flag"
+import "reflect"
+import "testing"
+
+func TestParseModulation(t *testing.T) {
+	good := map[string]Modulation{
+		"ISO14443a@106":    {ISO14443a, Nbr106},
+		"felica:424":       {Felica, Nbr424},
+		"Felica":           {Felica, Nbr212},
+		"iso14443b":        {ISO14443b, Nbr106},
+		"Jewel@106kbps":    {Jewel, Nbr106},
+		" DEP @ 424 k ":    {DEP, Nbr424},
+		"ISO14443b2ct@106": {ISO14443b2ct, Nbr106},
+	}
+
+	for s, want := range good {
+		if got, err := ParseModulation(s); err != nil || got != want {
+			t.Errorf("ParseModulation(%q) = %v, %v, want %v", s, got, err, want)
+		}
+	}
+
+	for _, s := range []string{"", "Felica@", "Felica@999", "Foo@106", "@106", "106"} {
+		if _, err := ParseModulation(s); err == nil {
+			t.Errorf("ParseModulation(%q) succeeded", s)
+		}
+	}
+
+	if nbr, err := ParseBaudRate("847 kbps"); err != nil || nbr != Nbr847 {
+		t.Errorf("ParseBaudRate(\"847 kbps\") = %v, %v", nbr, err)
+	}
+}
+
+func TestParseModulationList(t *testing.T) {
+	got, err := ParseModulationList("felica:212,424, ISO14443a@106")
+	want := []Modulation{{Felica, Nbr212}, {Felica, Nbr424}, {ISO14443a, Nbr106}}
+	if err != nil || !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseModulationList() = %v, %v, want %v", got, err, want)
+	}
+
+	for _, s := range []string{"", "106,felica", "felica,,iso14443a", "felica,bogus"} {
+		if _, err := ParseModulationList(s); err == nil {
+			t.Errorf("ParseModulationList(%q) succeeded", s)
+		}
+	}
+
+	var list ModulationList
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&list, "m", "modulations")
+	if err = fs.Parse([]string{"-m", "felica:212,424", "-m", "ISO14443a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual([]Modulation(list), want) {
+		t.Errorf("flag value = %v, want %v", list, want)
+	}
+
+	if s := list.String(); s != "Felica@212,Felica@424,ISO14443a@106" {
+		t.Errorf("String() = %q", s)
+	}
+}
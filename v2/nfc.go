@@ -215,6 +215,25 @@ func (m Modulation) GoString() string {
 	return "nfc.Modulation{Type: " + typeStr + ", BaudRate: " + brStr + "}"
 }
 
+// Encode a modulation as text of the form type@baudrate, e.g. ISO14443a@106,
+// using the names in ModulationTypes and BaudRates. Modulations with unknown
+// types or baud rates cannot be decoded and are therefore rejected.
+func (m Modulation) MarshalText() ([]byte, error) {
+	if m.Type <= 0 || m.Type >= len(ModulationTypes) || m.BaudRate < 0 || m.BaudRate >= len(BaudRates) {
+		return nil, fmt.Errorf("cannot encode unknown modulation %#v", m)
+	}
+
+	h := newTargetJSONHeader(m)
+	return []byte(h.Type + "@" + h.Baud), nil
+}
+
+// Decode a modulation encoded with MarshalText(). This is the same as
+// ParseModulation().
+func (m *Modulation) UnmarshalText(text []byte) (err error) {
+	*m, err = ParseModulation(string(text))
+	return
+}
+
 // An error as reported by various methods of Device. If device returns an error
 // that is not castable to Error, something outside on the Go side went wrong.
 type Error int
@@ -230,6 +249,34 @@ func (e Error) Error() string {
 	return errorMessages[int(e)]
 }
 
+// Encode an error as the name of its error code, e.g. ETIMEOUT. Unknown
+// error codes are encoded as numbers.
+func (e Error) MarshalText() ([]byte, error) {
+	if name, ok := errorNames[int(e)]; ok {
+		return []byte(name), nil
+	}
+
+	return []byte(strconv.Itoa(int(e))), nil
+}
+
+// Decode an error encoded with MarshalText().
+func (e *Error) UnmarshalText(text []byte) error {
+	for code, name := range errorNames {
+		if name == string(text) {
+			*e = Error(code)
+			return nil
+		}
+	}
+
+	code, err := strconv.Atoi(string(text))
+	if err != nil {
+		return fmt.Errorf("unknown error code %q", text)
+	}
+
+	*e = Error(code)
+	return nil
+}
+
 // Error codes. Casted to Error, these yield all possible errors this package
 // provides. Use nfc.Error(errorcode).Error() to get a descriptive string for an
 // error code.
@@ -270,5 +317,23 @@ var errorMessages = map[int]string{
 	ECHIP:        "device's internal chip error",
 }
 
+// names of the error codes for Error.MarshalText()
+var errorNames = map[int]string{
+	SUCCESS:      "SUCCESS",
+	EIO:          "EIO",
+	EINVARG:      "EINVARG",
+	EDEVNOTSUPP:  "EDEVNOTSUPP",
+	ENOTSUCHDEV:  "ENOTSUCHDEV",
+	EOVFLOW:      "EOVFLOW",
+	ETIMEOUT:     "ETIMEOUT",
+	EOPABORTED:   "EOPABORTED",
+	ENOTIMPL:     "ENOTIMPL",
+	ETGRELEASED:  "ETGRELEASED",
+	EMFCAUTHFAIL: "EMFCAUTHFAIL",
+	ERFTRANS:     "ERFTRANS",
+	ESOFT:        "ESOFT",
+	ECHIP:        "ECHIP",
+}
+
 // the global library context
 var theContext *context = &context{}
@@ -0,0 +1,539 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bufio"
+import "bytes"
+import "encoding/hex"
+import "errors"
+import "fmt"
+import "strconv"
+import "strings"
+import "time"
+
+// NXP NTAG21x (NFC Forum Type 2 Tag) commands. See the NTAG213/215/216
+// datasheet for details.
+const (
+	NTAGGetVersion = 0x60
+	NTAGRead       = 0x30
+	NTAGFastRead   = 0x3a
+	NTAGWrite      = 0xa2
+	NTAGReadSig    = 0x3c
+	NTAGPwdAuth    = 0x1b
+	NTAGHalt       = 0x50 // followed by 0x00
+)
+
+// NTAG21x 4 bit acknowledge and negative acknowledge codes.
+const (
+	NTAGAck            = 0xa
+	NTAGNakInvalidArg  = 0x0 // invalid argument or page address
+	NTAGNakCRC         = 0x1 // parity or CRC error
+	NTAGNakAuthLimit   = 0x4 // authentication counter overflow
+	NTAGNakWriteFailed = 0x5 // EEPROM write error
+)
+
+// NXP NTAG21x chip models.
+type NTAGModel int
+
+// Known NTAG21x models. The values correspond to the storage size byte of
+// the GET_VERSION response.
+const (
+	NTAGUnknown NTAGModel = -1
+	NTAG213     NTAGModel = 0x0f
+	NTAG215     NTAGModel = 0x11
+	NTAG216     NTAGModel = 0x13
+)
+
+// Print the name of an NTAG21x model, e.g. "NTAG215".
+func (m NTAGModel) String() string {
+	switch m {
+	case NTAG213:
+		return "NTAG213"
+	case NTAG215:
+		return "NTAG215"
+	case NTAG216:
+		return "NTAG216"
+	default:
+		return "unknown NTAG model"
+	}
+}
+
+// Return the number of 4 byte pages of model m including the configuration
+// pages. Returns 0 for unknown models.
+func (m NTAGModel) Pages() int {
+	switch m {
+	case NTAG213:
+		return 45
+	case NTAG215:
+		return 135
+	case NTAG216:
+		return 231
+	default:
+		return 0
+	}
+}
+
+// Return the GET_VERSION response of model m.
+func (m NTAGModel) Version() [8]byte {
+	return [8]byte{0x00, 0x04, 0x04, 0x02, 0x01, 0x00, byte(m), 0x03}
+}
+
+// Determine the model from the number of pages.
+func ntagModelFromPages(pages int) NTAGModel {
+	for _, m := range []NTAGModel{NTAG213, NTAG215, NTAG216} {
+		if m.Pages() == pages {
+			return m
+		}
+	}
+
+	return NTAGUnknown
+}
+
+// NTAGEmulator emulates an NXP NTAG213, NTAG215 or NTAG216 tag from a memory
+// image. Unlike Type4Emulator, it implements the tag protocol itself on top
+// of the raw frames exchanged with TargetTransceiveBits() and
+// TargetSendBits(), computing and checking the CRC of each frame. It answers
+// GET_VERSION, READ, FAST_READ, WRITE, READ_SIG and PWD_AUTH, honouring the
+// static and dynamic lock bits (but not the dynamic block-locking bits) as
+// well as the password protection configured with AUTH0 and ACCESS.
+//
+// Note that the PN53x can only emulate 4 byte UIDs beginning with 0x08,
+// so readers will likely see a different UID than the one in the memory
+// image.
+type NTAGEmulator struct {
+	Device    TargetDevice    // device used to emulate the tag
+	Target    ISO14443aTarget // the tag emulated
+	Model     NTAGModel       // the model emulated
+	Memory    []byte          // memory image, 4 * Model.Pages() bytes
+	Signature [32]byte        // originality signature returned by READ_SIG
+	Timeout   time.Duration   // see Device.TargetInitTimeout()
+
+	authed       bool // PWD_AUTH succeeded in this session
+	authFailures int  // number of failed PWD_AUTH attempts
+}
+
+// Make a new NTAGEmulator using d emulating a blank tag of model m holding
+// an empty NDEF message. The timeout is NoTimeout, so the emulator waits
+// for readers indefinitely.
+func NewNTAGEmulator(d TargetDevice, m NTAGModel) *NTAGEmulator {
+	e := &NTAGEmulator{Device: d}
+	e.setMemory(m, ntagBlank(m))
+	return e
+}
+
+// Generate the memory image of a blank tag of model m.
+func ntagBlank(m NTAGModel) []byte {
+	pages := m.Pages()
+	mem := make([]byte, 4*pages)
+	uid := []byte{0x04, 0x4e, 0x54, 0x41, 0x47, 0x21, 0x78}
+
+	copy(mem[0:3], uid[0:3])
+	mem[3] = 0x88 ^ uid[0] ^ uid[1] ^ uid[2]
+	copy(mem[4:8], uid[3:7])
+	mem[8] = uid[3] ^ uid[4] ^ uid[5] ^ uid[6]
+	mem[9] = 0x48
+
+	// capability container as programmed by NXP and empty NDEF message
+	size := map[NTAGModel]byte{NTAG213: 0x12, NTAG215: 0x3e, NTAG216: 0x6d}[m]
+	copy(mem[12:], []byte{0xe1, 0x10, size, 0x00})
+	copy(mem[16:], []byte{0x03, 0x00, 0xfe, 0x00})
+
+	copy(mem[4*(pages-5):], []byte{
+		0x00, 0x00, 0x00, 0xbd, // dynamic lock bytes
+		0x04, 0x00, 0x00, 0xff, // CFG0: AUTH0 beyond memory
+		0x00, 0x05, 0x00, 0x00, // CFG1
+		0xff, 0xff, 0xff, 0xff, // PWD
+	})
+
+	return mem
+}
+
+// Set model and memory and derive the target from the memory.
+func (e *NTAGEmulator) setMemory(m NTAGModel, mem []byte) {
+	e.Model = m
+	e.Memory = mem
+	e.Target = ISO14443aTarget{
+		Atqa:   [2]byte{0x00, 0x44},
+		Sak:    0x00,
+		UIDLen: 7,
+		Baud:   Nbr106,
+	}
+
+	copy(e.Target.UID[0:3], mem[0:3])
+	copy(e.Target.UID[3:7], mem[4:8])
+}
+
+// Load the memory image and signature from a dump of an NTAG213, NTAG215
+// or NTAG216. The model is determined from the size of the dump. Raw
+// binary dumps (as written by nfc-mfultralight), Proxmark3 binary dumps
+// (with their 56 byte header) and Flipper Zero NFC files are understood.
+// The Target is derived from the UID in the dump.
+func (e *NTAGEmulator) LoadDump(b []byte) error {
+	var mem []byte
+	var sig [32]byte
+
+	switch {
+	case bytes.HasPrefix(b, []byte("Filetype: Flipper NFC device")):
+		var err error
+		mem, sig, err = parseFlipperNTAG(b)
+		if err != nil {
+			return err
+		}
+
+	case ntagModelFromPages(len(b)/4) != NTAGUnknown && len(b)%4 == 0:
+		mem = append([]byte(nil), b...)
+
+	case len(b) > 56 && ntagModelFromPages((len(b)-56)/4) != NTAGUnknown && len(b)%4 == 0:
+		// Proxmark3 header: version, TBO, TBO1, pages, signature,
+		// counters and tearing flags
+		copy(sig[:], b[12:44])
+		mem = append([]byte(nil), b[56:]...)
+
+	default:
+		return fmt.Errorf("NTAG: unrecognised dump of %d bytes", len(b))
+	}
+
+	m := ntagModelFromPages(len(mem) / 4)
+	if m == NTAGUnknown || len(mem)%4 != 0 {
+		return fmt.Errorf("NTAG: dump of %d pages does not match any model", len(mem)/4)
+	}
+
+	e.setMemory(m, mem)
+	e.Signature = sig
+
+	return nil
+}
+
+// Parse a Flipper Zero NFC file holding an NTAG21x dump.
+func parseFlipperNTAG(b []byte) (mem []byte, sig [32]byte, err error) {
+	pages := map[int][]byte{}
+
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		line := s.Text()
+		i := strings.IndexByte(line, ':')
+		if i < 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+
+		data, herr := hex.DecodeString(strings.Replace(value, " ", "", -1))
+
+		switch {
+		case key == "Signature":
+			if herr != nil || len(data) != len(sig) {
+				return nil, sig, errors.New("NTAG: malformed signature")
+			}
+
+			copy(sig[:], data)
+
+		case strings.HasPrefix(key, "Page "):
+			var page int
+			page, err = strconv.Atoi(key[len("Page "):])
+			if err != nil || herr != nil || len(data) != 4 {
+				return nil, sig, fmt.Errorf("NTAG: malformed line %q", line)
+			}
+
+			pages[page] = data
+		}
+	}
+
+	if err = s.Err(); err != nil {
+		return nil, sig, err
+	}
+
+	mem = make([]byte, 4*len(pages))
+	for i := range pages {
+		if i < 0 || i >= len(pages) {
+			return nil, sig, fmt.Errorf("NTAG: page %d out of range", i)
+		}
+
+		copy(mem[4*i:], pages[i])
+	}
+
+	return mem, sig, nil
+}
+
+// Page numbers of the configuration area.
+func (e *NTAGEmulator) pageDynLock() int { return e.Model.Pages() - 5 }
+func (e *NTAGEmulator) pageCfg0() int    { return e.Model.Pages() - 4 }
+func (e *NTAGEmulator) pageCfg1() int    { return e.Model.Pages() - 3 }
+func (e *NTAGEmulator) pagePwd() int     { return e.Model.Pages() - 2 }
+func (e *NTAGEmulator) pagePack() int    { return e.Model.Pages() - 1 }
+
+// The first page protected by the password.
+func (e *NTAGEmulator) auth0() int {
+	return int(e.Memory[4*e.pageCfg0()+3])
+}
+
+// The ACCESS configuration byte.
+func (e *NTAGEmulator) access() byte {
+	return e.Memory[4*e.pageCfg1()]
+}
+
+// The number of pages that can currently be read. Pages beyond are
+// protected by the password.
+func (e *NTAGEmulator) readLimit() int {
+	pages := e.Model.Pages()
+	if e.access()&0x80 != 0 && !e.authed && e.auth0() < pages {
+		return e.auth0()
+	}
+
+	return pages
+}
+
+// Read a page as the reader sees it. PWD and PACK always read as zero.
+func (e *NTAGEmulator) readPage(page int) []byte {
+	if page == e.pagePwd() || page == e.pagePack() {
+		return make([]byte, 4)
+	}
+
+	return e.Memory[4*page : 4*page+4]
+}
+
+// Determine if a user page is locked by the lock bits.
+func (e *NTAGEmulator) locked(page int) bool {
+	switch {
+	case page < 3:
+		return true
+	case page == 3:
+		return e.Memory[10]&0x08 != 0
+	case page < 8:
+		return e.Memory[10]&(1<<uint(page)) != 0
+	case page < 16:
+		return e.Memory[11]&(1<<uint(page-8)) != 0
+	case page < e.pageDynLock():
+		// NTAG213 has one lock bit per 2 pages, the others one
+		// per 16 pages
+		perBit := 16
+		if e.Model == NTAG213 {
+			perBit = 2
+		}
+
+		bit := (page - 16) / perBit
+		return e.Memory[4*e.pageDynLock()+bit/8]&(1<<uint(bit%8)) != 0
+	case page == e.pageCfg0() || page == e.pageCfg1():
+		return e.access()&0x40 != 0
+	default:
+		return false
+	}
+}
+
+// Handle WRITE and return the ACK or NAK code.
+func (e *NTAGEmulator) write(page int, data []byte) byte {
+	if page >= e.Model.Pages() || page < 2 {
+		return NTAGNakInvalidArg
+	}
+
+	if page >= e.auth0() && !e.authed {
+		return NTAGNakInvalidArg
+	}
+
+	mem := e.Memory[4*page : 4*page+4]
+	switch {
+	case page == 2:
+		// static lock bytes, the block-locking bits freeze
+		// groups of lock bits
+		mask0, mask1 := byte(0xff), byte(0xff)
+		if mem[2]&0x01 != 0 {
+			mask0 &^= 0x08
+		}
+
+		if mem[2]&0x02 != 0 {
+			mask0 &^= 0xf0
+			mask1 &^= 0x03
+		}
+
+		if mem[2]&0x04 != 0 {
+			mask1 &^= 0xfc
+		}
+
+		mem[2] |= data[2] & mask0
+		mem[3] |= data[3] & mask1
+
+	case page == e.pageDynLock():
+		mem[0] |= data[0]
+		mem[1] |= data[1]
+		mem[2] |= data[2]
+
+	case e.locked(page):
+		return NTAGNakInvalidArg
+
+	case page == 3:
+		// the capability container is OTP
+		for i := range mem {
+			mem[i] |= data[i]
+		}
+
+	default:
+		copy(mem, data)
+	}
+
+	return NTAGAck
+}
+
+// Handle PWD_AUTH and return the PACK or a NAK code.
+func (e *NTAGEmulator) pwdAuth(pwd []byte) ([]byte, byte) {
+	limit := int(e.access() & 0x07)
+	if limit != 0 && e.authFailures >= 1<<uint(limit) {
+		return nil, NTAGNakAuthLimit
+	}
+
+	if !bytes.Equal(pwd, e.Memory[4*e.pagePwd():4*e.pagePwd()+4]) {
+		e.authFailures++
+		return nil, NTAGNakInvalidArg
+	}
+
+	e.authed = true
+	e.authFailures = 0
+	pack := e.Memory[4*e.pagePack() : 4*e.pagePack()+2]
+	return append([]byte(nil), pack...), NTAGAck
+}
+
+// Process a command frame without CRC. Return either a response frame
+// without CRC or, if res is nil, a 4 bit ACK or NAK code.
+func (e *NTAGEmulator) Process(cmd []byte) (res []byte, ack byte) {
+	if len(cmd) == 0 {
+		return nil, NTAGNakInvalidArg
+	}
+
+	switch {
+	case cmd[0] == NTAGGetVersion && len(cmd) == 1:
+		v := e.Model.Version()
+		return v[:], NTAGAck
+
+	case cmd[0] == NTAGRead && len(cmd) == 2:
+		limit := e.readLimit()
+		page := int(cmd[1])
+		if page >= limit {
+			return nil, NTAGNakInvalidArg
+		}
+
+		// reads roll over to page 0 at the end of the readable area
+		res = make([]byte, 0, 16)
+		for i := 0; i < 4; i++ {
+			res = append(res, e.readPage((page+i)%limit)...)
+		}
+
+		return res, NTAGAck
+
+	case cmd[0] == NTAGFastRead && len(cmd) == 3:
+		start, end := int(cmd[1]), int(cmd[2])
+		if start > end || end >= e.readLimit() {
+			return nil, NTAGNakInvalidArg
+		}
+
+		for page := start; page <= end; page++ {
+			res = append(res, e.readPage(page)...)
+		}
+
+		return res, NTAGAck
+
+	case cmd[0] == NTAGWrite && len(cmd) == 6:
+		return nil, e.write(int(cmd[1]), cmd[2:6])
+
+	case cmd[0] == NTAGReadSig && len(cmd) == 2 && cmd[1] == 0x00:
+		return append([]byte(nil), e.Signature[:]...), NTAGAck
+
+	case cmd[0] == NTAGPwdAuth && len(cmd) == 5:
+		return e.pwdAuth(cmd[1:5])
+
+	default:
+		return nil, NTAGNakInvalidArg
+	}
+}
+
+// Send a response frame with CRC or a 4 bit ACK/NAK if res is nil.
+func (e *NTAGEmulator) respond(res []byte, ack byte) error {
+	if res == nil {
+		_, err := e.Device.TargetSendBits([]byte{ack}, []byte{0}, 4)
+		return err
+	}
+
+	tx := AppendISO14443aCRC(append([]byte(nil), res...))
+	_, err := e.Device.TargetSendBits(tx, make([]byte, len(tx)), uint(8*len(tx)))
+	return err
+}
+
+// Emulate the tag for one session: wait for a reader with TargetInit() and
+// answer its commands until it halts or releases the tag, in which case nil
+// is returned. Call Run() in a loop to serve one reader after another.
+// Writes modify Memory in place. An authentication with PWD_AUTH lasts
+// until the end of the session.
+func (e *NTAGEmulator) Run() error {
+	if len(e.Memory) != 4*e.Model.Pages() || e.Model.Pages() == 0 {
+		return errors.New("NTAG: memory image does not match model")
+	}
+
+	timeout, err := timeoutMillis(e.Timeout)
+	if err != nil {
+		return err
+	}
+
+	e.authed = false
+
+	// the device strips the CRC of the first frame
+	rx := make([]byte, 64)
+	n, _, err := e.Device.TargetInit(&e.Target, rx, timeout)
+	if err != nil {
+		return err
+	}
+
+	if err = e.Device.SetPropertyBool(EasyFraming, false); err != nil {
+		return err
+	}
+
+	if err = e.Device.SetPropertyBool(HandleCRC, false); err != nil {
+		return err
+	}
+
+	cmd := rx[:n]
+	rxPar := make([]byte, len(rx))
+	for {
+		if len(cmd) == 2 && cmd[0] == NTAGHalt && cmd[1] == 0x00 {
+			break
+		}
+
+		if cmd == nil {
+			err = e.respond(nil, NTAGNakCRC)
+		} else {
+			err = e.respond(e.Process(cmd))
+		}
+
+		if err != nil {
+			break
+		}
+
+		if n, err = e.Device.TargetTransceiveBits(rx, rxPar, uint(8*len(rx))); err != nil {
+			break
+		}
+
+		cmd = nil
+		if n >= 24 && n%8 == 0 {
+			crc := ISO14443aCRC(rx[:n/8-2])
+			if rx[n/8-2] == crc[0] && rx[n/8-1] == crc[1] {
+				cmd = rx[:n/8-2]
+			}
+		}
+	}
+
+	if err == Error(ETGRELEASED) {
+		err = nil
+	}
+
+	return err
+}
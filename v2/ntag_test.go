@@ -0,0 +1,146 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "fmt"
+import "strings"
+import "testing"
+
+// Exercise reads, writes, lock bits and password protection of an emulated
+// NTAG213.
+func TestNTAGEmulator(t *testing.T) {
+	crc := func(b ...byte) []byte { return AppendISO14443aCRC(b) }
+
+	dev := &fakeTargetDevice{script: [][]byte{
+		{NTAGGetVersion}, // first frame, CRC stripped by the device
+		crc(NTAGRead, 0x03),
+		crc(NTAGWrite, 0x05, 'a', 'b', 'c', 'd'),
+		crc(NTAGFastRead, 0x04, 0x05),
+		{NTAGRead, 0x03, 0x00, 0x00},
+		crc(NTAGWrite, 0x02, 0xff, 0xff, 0x20, 0x00),
+		crc(NTAGWrite, 0x05, 'e', 'f', 'g', 'h'),
+		crc(NTAGWrite, 0x00, 0x00, 0x00, 0x00, 0x00),
+		crc(NTAGWrite, 0x2b, 0x11, 0x22, 0x33, 0x44),
+		crc(NTAGWrite, 0x2c, 0xca, 0xfe, 0x00, 0x00),
+		crc(NTAGWrite, 0x2a, 0x81, 0x05, 0x00, 0x00),
+		crc(NTAGWrite, 0x29, 0x04, 0x00, 0x00, 0x10),
+		crc(NTAGRead, 0x10),
+		crc(NTAGRead, 0x0e),
+		crc(NTAGPwdAuth, 0x00, 0x00, 0x00, 0x00),
+		crc(NTAGPwdAuth, 0x11, 0x22, 0x33, 0x44),
+		crc(NTAGFastRead, 0x2b, 0x2c),
+		crc(NTAGHalt, 0x00),
+	}}
+
+	e := NewNTAGEmulator(dev, NTAG213)
+	if err := e.Run(); err != nil {
+		t.Fatal("Run():", err)
+	}
+
+	page0 := append([]byte(nil), e.Memory[0:4]...)
+	want := [][]byte{
+		crc(0x00, 0x04, 0x04, 0x02, 0x01, 0x00, 0x0f, 0x03),
+		crc(append([]byte{0xe1, 0x10, 0x12, 0x00, 0x03, 0x00, 0xfe, 0x00}, make([]byte, 8)...)...),
+		{NTAGAck},
+		crc(0x03, 0x00, 0xfe, 0x00, 'a', 'b', 'c', 'd'),
+		{NTAGNakCRC},
+		{NTAGAck},
+		{NTAGNakInvalidArg},
+		{NTAGNakInvalidArg},
+		{NTAGAck},
+		{NTAGAck},
+		{NTAGAck},
+		{NTAGAck},
+		{NTAGNakInvalidArg},
+		crc(append(append(make([]byte, 8), page0...), e.Memory[4:8]...)...),
+		{NTAGNakInvalidArg},
+		crc(0xca, 0xfe),
+		crc(make([]byte, 8)...),
+	}
+
+	if len(dev.sent) != len(want) {
+		t.Fatalf("got %d responses, want %d", len(dev.sent), len(want))
+	}
+
+	for i := range want {
+		if !bytes.Equal(dev.sent[i], want[i]) {
+			t.Errorf("response %d: got % x, want % x", i, dev.sent[i], want[i])
+		}
+	}
+
+	if !bytes.Equal(e.Memory[20:24], []byte("abcd")) {
+		t.Errorf("page 5 is % x, want % x", e.Memory[20:24], "abcd")
+	}
+}
+
+// Load the same memory image from the supported dump formats.
+func TestNTAGLoadDump(t *testing.T) {
+	mem := ntagBlank(NTAG215)
+	mem[20] = 0x42
+	sig := bytes.Repeat([]byte{0x5a}, 32)
+
+	version := NTAG215.Version()
+	pm3 := make([]byte, 56)
+	copy(pm3, version[:])
+	pm3[11] = byte(NTAG215.Pages() - 1)
+	copy(pm3[12:], sig)
+	pm3 = append(pm3, mem...)
+
+	var flipper strings.Builder
+	flipper.WriteString("Filetype: Flipper NFC device\nVersion: 3\n# comment\nDevice type: NTAG215\n")
+	fmt.Fprintf(&flipper, "Signature: % X\nPages total: %d\n", sig, NTAG215.Pages())
+	for i := 0; i < NTAG215.Pages(); i++ {
+		fmt.Fprintf(&flipper, "Page %d: % X\n", i, mem[4*i:4*i+4])
+	}
+
+	dumps := []struct {
+		name string
+		dump []byte
+		sig  []byte
+	}{
+		{"raw", mem, make([]byte, 32)},
+		{"Proxmark3", pm3, sig},
+		{"Flipper", []byte(flipper.String()), sig},
+	}
+
+	for _, d := range dumps {
+		e := NewNTAGEmulator(nil, NTAG213)
+		if err := e.LoadDump(d.dump); err != nil {
+			t.Errorf("%s: LoadDump(): %v", d.name, err)
+			continue
+		}
+
+		if e.Model != NTAG215 {
+			t.Errorf("%s: model %v, want %v", d.name, e.Model, NTAG215)
+		}
+
+		if !bytes.Equal(e.Memory, mem) {
+			t.Errorf("%s: memory mismatch", d.name)
+		}
+
+		if !bytes.Equal(e.Signature[:], d.sig) {
+			t.Errorf("%s: signature % x, want % x", d.name, e.Signature, d.sig)
+		}
+
+		if e.Target.UID[0] != 0x04 || e.Target.UID[3] != mem[4] {
+			t.Errorf("%s: UID % x does not match dump", d.name, e.Target.UID[:7])
+		}
+	}
+
+	if err := NewNTAGEmulator(nil, NTAG213).LoadDump(make([]byte, 100)); err == nil {
+		t.Error("LoadDump() of garbage succeeded")
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "io"
+import "math/bits"
+import "strings"
+import "sync"
+import "time"
+
+// Link type of ISO14443 traces in pcap and pcapng files. Each packet starts
+// with a four byte pseudo-header: version (0), event type and the big endian
+// length of the data.
+const LinkTypeISO14443 = 264
+
+// Event types of the ISO14443 pseudo-header as understood by Wireshark.
+type PcapEvent byte
+
+const (
+	PcapPICCToPCD           PcapEvent = 0xff // data sent by the card
+	PcapPCDToPICC           PcapEvent = 0xfe // data sent by the reader
+	PcapFieldOff            PcapEvent = 0xfd // the reader turned off the field
+	PcapFieldOn             PcapEvent = 0xfc // the reader turned on the field
+	PcapPICCToPCDCRCDropped PcapEvent = 0xfb // like PcapPICCToPCD, CRC removed
+	PcapPCDToPICCCRCDropped PcapEvent = 0xfa // like PcapPCDToPICC, CRC removed
+)
+
+// A frame of an ISO14443 trace in a pcapng file.
+type PcapFrame struct {
+	Time  time.Time
+	Event PcapEvent
+	Data  []byte // nil for PcapFieldOff and PcapFieldOn
+}
+
+// pcapng block types and constants.
+const (
+	pcapngSHB         = 0x0a0d0d0a // section header block
+	pcapngIDB         = 0x00000001 // interface description block
+	pcapngEPB         = 0x00000006 // enhanced packet block
+	pcapngMagic       = 0x1a2b3c4d // byte order magic
+	pcapngOptEnd      = 0
+	pcapngOptComment  = 1
+	pcapngOptTSResol  = 9
+	pcapngMaxBlockLen = 1 << 24
+)
+
+// Pad n to a multiple of 4.
+func pcapngPad(n int) int {
+	return (n + 3) &^ 3
+}
+
+// PcapngWriter writes ISO14443 traces in the pcapng format with link type
+// LinkTypeISO14443 and nanosecond timestamps, to be analysed with
+// Wireshark. It is safe for concurrent use.
+type PcapngWriter struct {
+	// Set if the traced frames include the CRC, i.e. if HandleCRC has been
+	// disabled. Otherwise, frames traced with Tracer() are marked as having
+	// their CRC removed.
+	CRCIncluded bool
+
+	m   sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// Make a new PcapngWriter writing to w. The section header and interface
+// description are written immediately.
+func NewPcapngWriter(w io.Writer) (*PcapngWriter, error) {
+	p := &PcapngWriter{w: w}
+
+	shb := make([]byte, 16)
+	binary.LittleEndian.PutUint32(shb[0:4], pcapngMagic)
+	binary.LittleEndian.PutUint16(shb[4:6], 1) // major version
+	binary.LittleEndian.PutUint16(shb[6:8], 0) // minor version
+	binary.LittleEndian.PutUint64(shb[8:16], ^uint64(0))
+	if err := p.writeBlock(pcapngSHB, shb); err != nil {
+		return nil, err
+	}
+
+	idb := make([]byte, 8, 20)
+	binary.LittleEndian.PutUint16(idb[0:2], LinkTypeISO14443)
+	idb = appendPcapngOption(idb, pcapngOptTSResol, []byte{9})
+	idb = appendPcapngOption(idb, pcapngOptEnd, nil)
+	if err := p.writeBlock(pcapngIDB, idb); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Append a pcapng option to buf.
+func appendPcapngOption(buf []byte, code uint16, value []byte) []byte {
+	var hdr [4]byte
+	binary.LittleEndian.PutUint16(hdr[0:2], code)
+	binary.LittleEndian.PutUint16(hdr[2:4], uint16(len(value)))
+	buf = append(buf, hdr[:]...)
+	buf = append(buf, value...)
+	return append(buf, make([]byte, pcapngPad(len(value))-len(value))...)
+}
+
+// Write a block with the given type and body. The body must be padded.
+func (p *PcapngWriter) writeBlock(typ uint32, body []byte) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	block := make([]byte, 8, 12+len(body))
+	binary.LittleEndian.PutUint32(block[0:4], typ)
+	binary.LittleEndian.PutUint32(block[4:8], uint32(12+len(body)))
+	block = append(block, body...)
+	block = append(block, block[4:8]...)
+
+	_, p.err = p.w.Write(block)
+	return p.err
+}
+
+// Write frame f with the optional comment. Once writing has failed, all
+// further writes fail with the same error.
+func (p *PcapngWriter) WriteFrame(f PcapFrame, comment string) error {
+	if len(f.Data) > 0xffff {
+		return Error(EOVFLOW)
+	}
+
+	pkt := make([]byte, 4, 4+len(f.Data))
+	pkt[1] = byte(f.Event)
+	binary.BigEndian.PutUint16(pkt[2:4], uint16(len(f.Data)))
+	pkt = append(pkt, f.Data...)
+
+	ts := uint64(f.Time.UnixNano())
+	epb := make([]byte, 20, 20+pcapngPad(len(pkt))+pcapngPad(len(comment))+8)
+	binary.LittleEndian.PutUint32(epb[4:8], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(epb[8:12], uint32(ts))
+	binary.LittleEndian.PutUint32(epb[12:16], uint32(len(pkt)))
+	binary.LittleEndian.PutUint32(epb[16:20], uint32(len(pkt)))
+	epb = append(epb, pkt...)
+	epb = append(epb, make([]byte, pcapngPad(len(pkt))-len(pkt))...)
+	if comment != "" {
+		epb = appendPcapngOption(epb, pcapngOptComment, []byte(comment))
+		epb = appendPcapngOption(epb, pcapngOptEnd, nil)
+	}
+
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.writeBlock(pcapngEPB, epb)
+}
+
+// Return the first error writing to the underlying writer, if any.
+func (p *PcapngWriter) Err() error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	return p.err
+}
+
+// Determine the event type of a traced transfer. Data sent by the device
+// in target mode goes from card to reader, in initiator mode from reader to
+// card.
+func pcapEvent(ev TraceEvent, crcIncluded bool) PcapEvent {
+	toReader := (ev.Direction == TraceSend) == strings.HasPrefix(ev.Op, "Target")
+
+	switch {
+	case toReader && crcIncluded:
+		return PcapPICCToPCD
+	case toReader:
+		return PcapPICCToPCDCRCDropped
+	case crcIncluded:
+		return PcapPCDToPICC
+	default:
+		return PcapPCDToPICCCRCDropped
+	}
+}
+
+// Return a Tracer writing each traced frame to p, for use with
+// Device.SetTracer(). The name of the operation and the error, if any, are
+// recorded as packet comment. Failed receive operations are not written.
+// Check Err() for write errors after tracing.
+func (p *PcapngWriter) Tracer() Tracer {
+	return func(ev TraceEvent) {
+		if ev.Err != nil && ev.Direction == TraceReceive {
+			return
+		}
+
+		comment := ev.Op
+		if ev.Err != nil {
+			comment += ": " + ev.Err.Error()
+		}
+
+		p.WriteFrame(PcapFrame{ev.Time, pcapEvent(ev, p.CRCIncluded), ev.Data}, comment)
+	}
+}
+
+// A pcapng interface as seen by PcapngReader.
+type pcapngInterface struct {
+	linkType uint16
+	tsUnit   uint64 // timestamp units per second
+}
+
+// PcapngReader reads ISO14443 frames from a pcapng file. Packets of
+// interfaces with a link type other than LinkTypeISO14443 are skipped.
+type PcapngReader struct {
+	r          io.Reader
+	order      binary.ByteOrder
+	interfaces []pcapngInterface
+}
+
+// Make a new PcapngReader reading from r. The section header is read
+// immediately.
+func NewPcapngReader(r io.Reader) (*PcapngReader, error) {
+	p := &PcapngReader{r: r}
+
+	typ, _, err := p.readBlock()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if typ != pcapngSHB {
+		return nil, errors.New("pcapng: missing section header")
+	}
+
+	return p, nil
+}
+
+// Read the next block and return its type and body. Section headers are
+// processed.
+func (p *PcapngReader) readBlock() (uint32, []byte, error) {
+	var hdr [12]byte
+	if _, err := io.ReadFull(p.r, hdr[:8]); err != nil {
+		return 0, nil, err
+	}
+
+	typ := binary.LittleEndian.Uint32(hdr[0:4])
+	if typ == pcapngSHB {
+		// the byte order magic determines the byte order of the section
+		if _, err := io.ReadFull(p.r, hdr[8:12]); err != nil {
+			return 0, nil, pcapngEOF(err)
+		}
+
+		switch {
+		case binary.LittleEndian.Uint32(hdr[8:12]) == pcapngMagic:
+			p.order = binary.LittleEndian
+		case binary.BigEndian.Uint32(hdr[8:12]) == pcapngMagic:
+			p.order = binary.BigEndian
+		default:
+			return 0, nil, errors.New("pcapng: bad byte order magic")
+		}
+
+		p.interfaces = nil
+	} else if p.order == nil {
+		return 0, nil, errors.New("pcapng: missing section header")
+	}
+
+	length := p.order.Uint32(hdr[4:8])
+	if length < 12 || length%4 != 0 || length > pcapngMaxBlockLen {
+		return 0, nil, fmt.Errorf("pcapng: bad block length %d", length)
+	}
+
+	block := make([]byte, length)
+	copy(block, hdr[:])
+	read := 8
+	if typ == pcapngSHB {
+		read = 12
+	}
+
+	if length < uint32(read)+4 {
+		return 0, nil, fmt.Errorf("pcapng: bad block length %d", length)
+	}
+
+	if _, err := io.ReadFull(p.r, block[read:]); err != nil {
+		return 0, nil, pcapngEOF(err)
+	}
+
+	if p.order.Uint32(block[length-4:]) != length {
+		return 0, nil, errors.New("pcapng: block length mismatch")
+	}
+
+	return p.order.Uint32(block[0:4]), block[8 : length-4], nil
+}
+
+// Turn io.EOF into io.ErrUnexpectedEOF in the middle of a block.
+func pcapngEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// Parse an interface description block.
+func (p *PcapngReader) parseIDB(body []byte) error {
+	if len(body) < 8 {
+		return errors.New("pcapng: truncated interface description")
+	}
+
+	iface := pcapngInterface{linkType: p.order.Uint16(body[0:2]), tsUnit: 1000000}
+	opts := body[8:]
+	for len(opts) >= 4 {
+		code, n := p.order.Uint16(opts[0:2]), int(p.order.Uint16(opts[2:4]))
+		if code == pcapngOptEnd || 4+n > len(opts) {
+			break
+		}
+
+		if code == pcapngOptTSResol && n == 1 {
+			// base 10 or, if the MSB is set, base 2
+			res, base := uint64(opts[4]&0x7f), uint64(10)
+			if opts[4]&0x80 != 0 {
+				base = 2
+			}
+
+			if res > 19 && base == 10 || res > 63 {
+				return errors.New("pcapng: unsupported timestamp resolution")
+			}
+
+			iface.tsUnit = 1
+			for i := uint64(0); i < res; i++ {
+				iface.tsUnit *= base
+			}
+		}
+
+		opts = opts[4+pcapngPad(n):]
+	}
+
+	p.interfaces = append(p.interfaces, iface)
+	return nil
+}
+
+// Read the next ISO14443 frame. Returns io.EOF at the end of the file.
+func (p *PcapngReader) ReadFrame() (PcapFrame, error) {
+	for {
+		typ, body, err := p.readBlock()
+		if err != nil {
+			return PcapFrame{}, err
+		}
+
+		switch typ {
+		case pcapngIDB:
+			if err = p.parseIDB(body); err != nil {
+				return PcapFrame{}, err
+			}
+
+		case pcapngEPB:
+			if len(body) < 20 {
+				return PcapFrame{}, errors.New("pcapng: truncated packet")
+			}
+
+			id := p.order.Uint32(body[0:4])
+			if id >= uint32(len(p.interfaces)) {
+				return PcapFrame{}, fmt.Errorf("pcapng: packet of unknown interface %d", id)
+			}
+
+			iface := p.interfaces[id]
+			if iface.linkType != LinkTypeISO14443 {
+				continue
+			}
+
+			caplen := p.order.Uint32(body[12:16])
+			if uint64(caplen) > uint64(len(body)-20) {
+				return PcapFrame{}, errors.New("pcapng: truncated packet")
+			}
+
+			pkt := body[20 : 20+caplen]
+			if len(pkt) < 4 || pkt[0] != 0 {
+				return PcapFrame{}, errors.New("pcapng: bad ISO14443 pseudo-header")
+			}
+
+			n := int(binary.BigEndian.Uint16(pkt[2:4]))
+			if 4+n > len(pkt) {
+				return PcapFrame{}, errors.New("pcapng: truncated ISO14443 frame")
+			}
+
+			ts := uint64(p.order.Uint32(body[4:8]))<<32 | uint64(p.order.Uint32(body[8:12]))
+			sec, frac := ts/iface.tsUnit, ts%iface.tsUnit
+			hi, lo := bits.Mul64(frac, uint64(time.Second))
+			nsec, _ := bits.Div64(hi, lo, iface.tsUnit)
+
+			f := PcapFrame{
+				Time:  time.Unix(int64(sec), int64(nsec)),
+				Event: PcapEvent(pkt[1]),
+			}
+
+			if n > 0 {
+				f.Data = append([]byte(nil), pkt[4:4+n]...)
+			}
+
+			return f, nil
+		}
+	}
+}
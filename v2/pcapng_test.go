@@ -0,0 +1,116 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "io"
+import "testing"
+import "time"
+
+// Write a trace and read it back.
+func TestPcapng(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewPcapngWriter(&buf)
+	if err != nil {
+		t.Fatal("NewPcapngWriter():", err)
+	}
+
+	start := time.Unix(1700000000, 123456789)
+	tr := w.Tracer()
+	tr(TraceEvent{Op: "InitiatorTransceiveBytes", Direction: TraceSend, Data: []byte{0x30, 0x04}, Time: start})
+	tr(TraceEvent{Op: "InitiatorTransceiveBytes", Direction: TraceReceive, Data: bytes.Repeat([]byte{0xaa}, 16), Time: start})
+	tr(TraceEvent{Op: "TargetReceiveBytes", Direction: TraceReceive, Time: start, Err: Error(ETGRELEASED)})
+	tr(TraceEvent{Op: "TargetSendBits", Direction: TraceSend, Data: []byte{0x0a}, Time: start.Add(time.Millisecond)})
+
+	w.CRCIncluded = true
+	tr(TraceEvent{Op: "TargetTransceiveBits", Direction: TraceReceive, Data: []byte{0x50, 0x00, 0x57, 0xcd}, Time: start})
+
+	if err = w.WriteFrame(PcapFrame{Time: start, Event: PcapFieldOff}, ""); err != nil {
+		t.Fatal("WriteFrame():", err)
+	}
+
+	if buf.Len()%4 != 0 {
+		t.Errorf("file length %d not a multiple of 4", buf.Len())
+	}
+
+	want := []PcapFrame{
+		{start, PcapPCDToPICCCRCDropped, []byte{0x30, 0x04}},
+		{start, PcapPICCToPCDCRCDropped, bytes.Repeat([]byte{0xaa}, 16)},
+		{start.Add(time.Millisecond), PcapPICCToPCDCRCDropped, []byte{0x0a}},
+		{start, PcapPCDToPICC, []byte{0x50, 0x00, 0x57, 0xcd}},
+		{start, PcapFieldOff, nil},
+	}
+
+	r, err := NewPcapngReader(&buf)
+	if err != nil {
+		t.Fatal("NewPcapngReader():", err)
+	}
+
+	for i, wf := range want {
+		f, err := r.ReadFrame()
+		if err != nil {
+			t.Fatalf("ReadFrame() %d: %v", i, err)
+		}
+
+		if !f.Time.Equal(wf.Time) || f.Event != wf.Event || !bytes.Equal(f.Data, wf.Data) {
+			t.Errorf("frame %d: got %+v, want %+v", i, f, wf)
+		}
+	}
+
+	if _, err = r.ReadFrame(); err != io.EOF {
+		t.Errorf("ReadFrame() at end: got error %v, want EOF", err)
+	}
+}
+
+// Read a big endian file with microsecond timestamps as written by other
+// tools.
+func TestPcapngReaderBigEndian(t *testing.T) {
+	file := []byte{
+		// section header block
+		0x0a, 0x0d, 0x0d, 0x0a, 0x00, 0x00, 0x00, 0x1c,
+		0x1a, 0x2b, 0x3c, 0x4d, 0x00, 0x01, 0x00, 0x00,
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0x00, 0x00, 0x00, 0x1c,
+		// interface description block, no options
+		0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x14,
+		0x01, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x14,
+		// enhanced packet block at 1.5 s
+		0x00, 0x00, 0x00, 0x06, 0x00, 0x00, 0x00, 0x28,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x16, 0xe3, 0x60,
+		0x00, 0x00, 0x00, 0x05, 0x00, 0x00, 0x00, 0x05,
+		0x00, 0xfe, 0x00, 0x01, 0x26, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x28,
+	}
+
+	r, err := NewPcapngReader(bytes.NewReader(file))
+	if err != nil {
+		t.Fatal("NewPcapngReader():", err)
+	}
+
+	f, err := r.ReadFrame()
+	if err != nil {
+		t.Fatal("ReadFrame():", err)
+	}
+
+	if !f.Time.Equal(time.Unix(1, 500000000)) || f.Event != PcapPCDToPICC || !bytes.Equal(f.Data, []byte{0x26}) {
+		t.Errorf("got %+v", f)
+	}
+
+	if _, err = NewPcapngReader(bytes.NewReader(file[28:])); err == nil {
+		t.Error("NewPcapngReader() without section header succeeded")
+	}
+}
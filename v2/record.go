@@ -0,0 +1,345 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/json"
+import "errors"
+import "fmt"
+import "io"
+import "reflect"
+import "sync"
+import "time"
+
+// A backend performs the calls made on a Device instead of libnfc. This is
+// used to record and replay sessions, see Record() and Replay().
+type backend interface {
+	// Perform call c, filling in its results. do performs c on a device
+	// and fills in the results, if the backend has a device to talk to.
+	// A non-nil error is returned in place of the results.
+	call(c *deviceCall, do func(d Device)) error
+
+	// Implementations of AbortCommand(), String() and Connection(). These
+	// are not recorded as they may be called at any time.
+	abort() error
+	name() string
+	connection() string
+}
+
+// Arguments of a call on a Device. Only the fields needed for the call are
+// set. Buffers passed to receive data are recorded by their length.
+type callArgs struct {
+	Property    int           `json:"property,omitempty"`
+	Value       int           `json:"value,omitempty"`
+	Mode        int           `json:"mode,omitempty"`
+	Type        int           `json:"type,omitempty"`
+	Baud        int           `json:"baud,omitempty"`
+	Modulations []Modulation  `json:"modulations,omitempty"`
+	Times       int           `json:"times,omitempty"`
+	Period      time.Duration `json:"period,omitempty"`
+	Target      *jsonTarget   `json:"target,omitempty"`
+	InitData    []byte        `json:"initdata,omitempty"`
+	Tx          []byte        `json:"tx,omitempty"`
+	TxPar       []byte        `json:"txpar,omitempty"`
+	TxBits      uint          `json:"txbits,omitempty"`
+	RxLen       int           `json:"rxlen,omitempty"`
+	RxBits      uint          `json:"rxbits,omitempty"`
+	Timeout     int           `json:"timeout,omitempty"`
+	Cycles      uint32        `json:"cycles,omitempty"`
+}
+
+// Results of a call on a Device.
+type callResult struct {
+	N          int          `json:"n,omitempty"`
+	C          uint32       `json:"c,omitempty"`
+	Rx         []byte       `json:"rx,omitempty"`
+	RxPar      []byte       `json:"rxpar,omitempty"`
+	Ints       []int        `json:"ints,omitempty"`
+	Result     *jsonTarget  `json:"result,omitempty"`
+	Results    []jsonTarget `json:"results,omitempty"`
+	Info       string       `json:"info,omitempty"`
+	Name       string       `json:"name,omitempty"`
+	Connstring string       `json:"connstring,omitempty"`
+	Err        int          `json:"err,omitempty"`    // libnfc error code
+	ErrMsg     string       `json:"errmsg,omitempty"` // other errors
+}
+
+// A call on a Device as recorded by Record(). Op is the name of the method.
+type deviceCall struct {
+	Op string `json:"op"`
+	callArgs
+	callResult
+}
+
+// A Target wrapped for encoding as JSON, such that it can be decoded
+// without knowing its type.
+type jsonTarget struct {
+	Target
+}
+
+// Wrap t for encoding as JSON. Return nil if t is nil.
+func newJSONTarget(t Target) *jsonTarget {
+	if t == nil || reflect.ValueOf(t).IsNil() {
+		return nil
+	}
+
+	return &jsonTarget{t}
+}
+
+func (t jsonTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Target)
+}
+
+func (t *jsonTarget) UnmarshalJSON(buf []byte) (err error) {
+	t.Target, err = UnmarshalTargetJSON(buf)
+	return
+}
+
+// Return b or nil if b is empty, such that recorded and replayed arguments
+// compare equal.
+func nilIfEmpty(b []byte) []byte {
+	if len(b) == 0 {
+		return nil
+	}
+
+	return b
+}
+
+// Record the result of a call returning err.
+func (c *deviceCall) setErr(err error) {
+	if e, ok := err.(Error); ok {
+		c.Err = int(e)
+	} else if err != nil {
+		c.ErrMsg = err.Error()
+	}
+}
+
+// Return the recorded error of a call.
+func (c *deviceCall) err() error {
+	switch {
+	case c.Err != 0:
+		return Error(c.Err)
+	case c.ErrMsg != "":
+		return errors.New(c.ErrMsg)
+	default:
+		return nil
+	}
+}
+
+// Record the first n bytes of rx as received data.
+func (c *deviceCall) setBytes(rx []byte, n int) {
+	c.N = n
+	if n > len(rx) {
+		n = len(rx)
+	}
+
+	if n > 0 {
+		c.Rx = rx[:n]
+	}
+}
+
+// Record the first n bits of rx and rxPar as received data.
+func (c *deviceCall) setBits(rx, rxPar []byte, n int) {
+	c.N = n
+	nbytes := (n + 7) / 8
+	if nbytes > len(rx) {
+		nbytes = len(rx)
+	}
+
+	if nbytes > 0 {
+		c.Rx = rx[:nbytes]
+		if nbytes <= len(rxPar) {
+			c.RxPar = rxPar[:nbytes]
+		}
+	}
+}
+
+// Copy the received data of a call to rx and rxPar (which may be nil) and
+// return the result.
+func (c *deviceCall) getData(rx, rxPar []byte) int {
+	copy(rx, c.Rx)
+	copy(rxPar, c.RxPar)
+	return c.N
+}
+
+// Return the recorded targets of a call.
+func (c *deviceCall) targets() []Target {
+	targets := make([]Target, len(c.Results))
+	for i := range c.Results {
+		targets[i] = c.Results[i].Target
+	}
+
+	return targets
+}
+
+// Return the recorded target of a call or nil if there is none.
+func (c *deviceCall) target() Target {
+	if c.Result == nil {
+		return nil
+	}
+
+	return c.Result.Target
+}
+
+// A backend recording each call made on the wrapped device.
+type recorder struct {
+	m   sync.Mutex
+	d   Device
+	enc *json.Encoder
+	err error
+}
+
+// Record returns a Device that forwards every call to d and writes it with
+// its arguments and results to w, one JSON object per line. Pass the
+// recording to Replay() to replay the session without hardware, e.g. in
+// regression tests. The returned Device shares its tracer with d. Closing
+// it closes d.
+//
+// AbortCommand(), String() and Connection() are not recorded. If writing the
+// recording fails, the call and all further calls return the error after
+// the call has been performed on d.
+func Record(d Device, w io.Writer) Device {
+	r := &recorder{d: d, enc: json.NewEncoder(w)}
+	r.err = r.enc.Encode(&deviceCall{Op: "Open", callResult: callResult{
+		Name:       d.String(),
+		Connstring: d.Connection(),
+	}})
+
+	return Device{d: d.d, state: d.state.wrap(), b: r}
+}
+
+func (r *recorder) call(c *deviceCall, do func(d Device)) error {
+	r.m.Lock()
+	defer r.m.Unlock()
+
+	do(r.d)
+	if r.err == nil {
+		r.err = r.enc.Encode(c)
+	}
+
+	return r.err
+}
+
+func (r *recorder) abort() error {
+	return r.d.AbortCommand()
+}
+
+func (r *recorder) name() string {
+	return r.d.String()
+}
+
+func (r *recorder) connection() string {
+	return r.d.Connection()
+}
+
+// A backend replaying a recorded session.
+type replayer struct {
+	m     sync.Mutex
+	calls []deviceCall
+	next  int
+	err   error
+	open  deviceCall
+}
+
+// Replay returns a Device replaying a session recorded with Record() from
+// r. The calls made on the Device must match the recorded ones in order and
+// arguments; they return the recorded results. The first call that does
+// not match the recording and all further calls fail with an error
+// describing the divergence. Close() fails if not all recorded calls have
+// been replayed.
+//
+// AbortCommand() does nothing. String() and Connection() return the name and
+// connection string of the recorded device. The replayed Device has no
+// libnfc device, so Pointer() returns 0. Replayed calls are not traced.
+func Replay(r io.Reader) (Device, error) {
+	p := &replayer{}
+
+	dec := json.NewDecoder(r)
+	if err := dec.Decode(&p.open); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return Device{}, err
+	}
+
+	if p.open.Op != "Open" {
+		return Device{}, errors.New("replay: recording does not start with Open")
+	}
+
+	for {
+		var c deviceCall
+		err := dec.Decode(&c)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return Device{}, err
+		}
+
+		p.calls = append(p.calls, c)
+	}
+
+	return Device{state: newDeviceState(), b: p}, nil
+}
+
+// Format the operation and arguments of a call for error messages.
+func (c *deviceCall) describe() string {
+	args, err := json.Marshal(&c.callArgs)
+	if err != nil {
+		return c.Op
+	}
+
+	return c.Op + string(args)
+}
+
+func (p *replayer) call(c *deviceCall, do func(d Device)) error {
+	p.m.Lock()
+	defer p.m.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+
+	if p.next >= len(p.calls) {
+		p.err = fmt.Errorf("replay: call %d %s after end of recording", p.next+1, c.describe())
+		return p.err
+	}
+
+	want := &p.calls[p.next]
+	if c.Op != want.Op || !reflect.DeepEqual(c.callArgs, want.callArgs) {
+		p.err = fmt.Errorf("replay: call %d diverges: got %s, want %s", p.next+1, c.describe(), want.describe())
+		return p.err
+	}
+
+	p.next++
+	if c.Op == "Close" && p.next < len(p.calls) {
+		p.err = fmt.Errorf("replay: %d recorded calls not replayed", len(p.calls)-p.next)
+		return p.err
+	}
+
+	c.callResult = want.callResult
+	return nil
+}
+
+func (p *replayer) abort() error {
+	return nil
+}
+
+func (p *replayer) name() string {
+	return p.open.Name
+}
+
+func (p *replayer) connection() string {
+	return p.open.Connstring
+}
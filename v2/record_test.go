@@ -0,0 +1,131 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "encoding/json"
+import "strings"
+import "testing"
+
+// A recorded session reading page 4 of an NTAG.
+func recordedSession(t *testing.T) []byte {
+	tag := &ISO14443aTarget{Atqa: [2]byte{0x00, 0x44}, Sak: 0x00, UIDLen: 7, UID: [10]byte{0x04, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, Baud: Nbr106}
+	calls := []deviceCall{
+		{Op: "Open", callResult: callResult{Name: "fake reader", Connstring: "fake:0"}},
+		{Op: "InitiatorInit"},
+		{Op: "SetPropertyBool", callArgs: callArgs{Property: InfiniteSelect}},
+		{Op: "InitiatorSelectPassiveTarget", callArgs: callArgs{Modulations: []Modulation{{ISO14443a, Nbr106}}}, callResult: callResult{Result: &jsonTarget{tag}}},
+		{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{0x30, 0x04}, RxLen: 16, Timeout: -1}, callResult: callResult{N: 16, Rx: bytes.Repeat([]byte{0xa5}, 16)}},
+		{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{0x30, 0xff}, RxLen: 16, Timeout: -1}, callResult: callResult{N: ETIMEOUT, Err: ETIMEOUT}},
+		{Op: "Close"},
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for i := range calls {
+		if err := enc.Encode(&calls[i]); err != nil {
+			t.Fatal("Encode():", err)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// Run the session of recordedSession() on d.
+func runSession(d Device) error {
+	if err := d.InitiatorInit(); err != nil {
+		return err
+	}
+
+	if err := d.SetPropertyBool(InfiniteSelect, false); err != nil {
+		return err
+	}
+
+	tag, err := d.InitiatorSelectPassiveTarget(Modulation{ISO14443a, Nbr106}, nil)
+	if err != nil {
+		return err
+	}
+
+	if a, ok := tag.(*ISO14443aTarget); !ok || a.UIDLen != 7 || a.UID[6] != 0x66 {
+		return Error(EINVARG)
+	}
+
+	rx := make([]byte, 16)
+	n, err := d.InitiatorTransceiveBytes([]byte{0x30, 0x04}, rx, -1)
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(rx[:n], bytes.Repeat([]byte{0xa5}, 16)) {
+		return Error(EINVARG)
+	}
+
+	if _, err = d.InitiatorTransceiveBytes([]byte{0x30, 0xff}, rx, -1); err != Error(ETIMEOUT) {
+		return Error(EINVARG)
+	}
+
+	return d.Close()
+}
+
+// Replay a session and record the replay.
+func TestReplay(t *testing.T) {
+	session := recordedSession(t)
+	d, err := Replay(bytes.NewReader(session))
+	if err != nil {
+		t.Fatal("Replay():", err)
+	}
+
+	if d.String() != "fake reader" || d.Connection() != "fake:0" || d.Pointer() != 0 {
+		t.Errorf("unexpected device %q at %q", d.String(), d.Connection())
+	}
+
+	var buf bytes.Buffer
+	if err = runSession(Record(d, &buf)); err != nil {
+		t.Fatal("session failed:", err)
+	}
+
+	if !bytes.Equal(buf.Bytes(), session) {
+		t.Errorf("recording differs:\n%s\nwant:\n%s", buf.Bytes(), session)
+	}
+}
+
+// Check that divergence from the recording is detected.
+func TestReplayDivergence(t *testing.T) {
+	session := recordedSession(t)
+	d, err := Replay(bytes.NewReader(session))
+	if err != nil {
+		t.Fatal("Replay():", err)
+	}
+
+	if err = d.InitiatorInit(); err != nil {
+		t.Fatal("InitiatorInit():", err)
+	}
+
+	err = d.SetPropertyBool(InfiniteSelect, true)
+	if err == nil || !strings.Contains(err.Error(), "call 2 diverges") {
+		t.Fatal("diverging call not detected:", err)
+	}
+
+	if err2 := d.SetPropertyBool(InfiniteSelect, false); err2 != err {
+		t.Error("divergence not sticky:", err2)
+	}
+
+	// a session ending early
+	d, _ = Replay(bytes.NewReader(session))
+	d.InitiatorInit()
+	if err = d.Close(); err == nil {
+		t.Error("Close() with calls left succeeded")
+	}
+}
@@ -0,0 +1,306 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "io"
+import "net"
+import "time"
+
+// Message types of the relay link protocol. Each message consists of the
+// type, a two byte big endian length and the payload.
+const (
+	relayMsgCommand  = 0x01 // command from the reader
+	relayMsgResponse = 0x02 // response from the card
+	relayMsgError    = 0x03 // the card could not be reached, see relayError()
+	relayMsgRelease  = 0x04 // the reader released the target
+)
+
+// Kinds of events recorded by RelayTarget and RelayInitiator.
+type RelayEventType int
+
+const (
+	RelayCommand  RelayEventType = iota // a command from the reader was received
+	RelayResponse                       // a response from the card was received
+	RelayRelease                        // the reader released the target
+)
+
+func (t RelayEventType) String() string {
+	switch t {
+	case RelayCommand:
+		return "command"
+	case RelayResponse:
+		return "response"
+	case RelayRelease:
+		return "release"
+	default:
+		return fmt.Sprintf("RelayEventType(%d)", int(t))
+	}
+}
+
+// An event recorded by RelayTarget or RelayInitiator. Each side records
+// when it receives a command and when it receives the matching response,
+// so on the RelayTarget side, the difference is the latency seen by the
+// reader, while on the RelayInitiator side, it is the time the card took
+// to respond. The difference between both is the latency of the link.
+type RelayEvent struct {
+	Type RelayEventType
+	Time time.Time
+	Data []byte // the frame relayed, nil for RelayRelease
+}
+
+// Write a message of the relay link protocol.
+func relayWrite(c net.Conn, typ byte, payload []byte) error {
+	if len(payload) > 0xffff {
+		return Error(EOVFLOW)
+	}
+
+	msg := make([]byte, 3, 3+len(payload))
+	msg[0] = typ
+	binary.BigEndian.PutUint16(msg[1:3], uint16(len(payload)))
+	_, err := c.Write(append(msg, payload...))
+	return err
+}
+
+// Encode err as the payload of a relayMsgError message: the libnfc error
+// code as a four byte big endian number (0 if err is not an Error) followed
+// by the error message.
+func relayError(err error) []byte {
+	var code Error
+	errors.As(err, &code)
+
+	msg := err.Error()
+	if len(msg) > 0xffff-4 {
+		msg = msg[:0xffff-4]
+	}
+
+	payload := make([]byte, 4, 4+len(msg))
+	binary.BigEndian.PutUint32(payload, uint32(int32(code)))
+	return append(payload, msg...)
+}
+
+// Decode the payload of a relayMsgError message as made by relayError().
+func parseRelayError(payload []byte) error {
+	if len(payload) < 4 {
+		return fmt.Errorf("relay: malformed error message")
+	}
+
+	if code := Error(int32(binary.BigEndian.Uint32(payload))); code != 0 {
+		return &RelayRemoteError{code}
+	}
+
+	return &RelayRemoteError{errors.New(string(payload[4:]))}
+}
+
+// RelayRemoteError is returned by RelayTarget.Run() when the RelayInitiator
+// could not reach the card. If the card failed with an Error, Err is that
+// Error, so errors.Is() can be used to check for a particular libnfc error
+// code.
+type RelayRemoteError struct {
+	Err error // the error reported by the RelayInitiator
+}
+
+func (e *RelayRemoteError) Error() string {
+	return "relay: remote error: " + e.Err.Error()
+}
+
+func (e *RelayRemoteError) Unwrap() error {
+	return e.Err
+}
+
+// Read a message of the relay link protocol.
+func relayRead(c net.Conn) (typ byte, payload []byte, err error) {
+	var hdr [3]byte
+	if _, err = io.ReadFull(c, hdr[:]); err != nil {
+		return
+	}
+
+	payload = make([]byte, binary.BigEndian.Uint16(hdr[1:3]))
+	if _, err = io.ReadFull(c, payload); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+
+		return
+	}
+
+	return hdr[0], payload, nil
+}
+
+// RelayTarget is the reader facing side of a relay. It emulates Target
+// towards a reader and forwards the commands of the reader over Conn to a
+// RelayInitiator, which passes them on to a card and sends back the
+// responses. For ISO14443-4 targets, APDUs are relayed; the device handles
+// the ISO14443-4 layer. Relaying is only suitable for protocols whose
+// timing requirements are loose enough to tolerate the latency of the
+// link.
+type RelayTarget struct {
+	Device  TargetDevice     // device used to emulate the target
+	Target  Target           // the target emulated, usually that of the card
+	Conn    net.Conn         // link to the RelayInitiator
+	Timeout time.Duration    // see Device.TargetReceiveBytesTimeout()
+	Log     func(RelayEvent) // if not nil, called for each event
+}
+
+// Make a new RelayTarget using d emulating t and relaying over c. The
+// timeout is NoTimeout, so the relay waits for readers indefinitely.
+func NewRelayTarget(d TargetDevice, t Target, c net.Conn) *RelayTarget {
+	return &RelayTarget{Device: d, Target: t, Conn: c}
+}
+
+// Call r.Log if set.
+func (r *RelayTarget) log(typ RelayEventType, data []byte) {
+	if r.Log != nil {
+		r.Log(RelayEvent{typ, time.Now(), append([]byte(nil), data...)})
+	}
+}
+
+// Relay one session: wait for a reader with TargetInit() and relay its
+// commands until it releases the target, in which case nil is returned and
+// the RelayInitiator is notified. Call Run() in a loop to relay one session
+// after another over the same link.
+//
+// If the card cannot be reached, the command of the reader cannot be
+// answered, so the session ends with a *RelayRemoteError. The RelayInitiator
+// keeps serving, so the next call to Run() can relay the next session. When
+// the session ends with an error, the target is released by calling the
+// Idle() method of Device if it has one, so the reader notices that the
+// card went away instead of waiting for an answer.
+func (r *RelayTarget) Run() error {
+	timeout, err := timeoutMillis(r.Timeout)
+	if err != nil {
+		return err
+	}
+
+	rx := make([]byte, 264)
+	n, _, err := r.Device.TargetInit(r.Target, rx, timeout)
+	if err != nil {
+		return err
+	}
+
+	err = r.session(rx, n, timeout)
+	if err != Error(ETGRELEASED) {
+		if i, ok := r.Device.(interface{ Idle() error }); ok {
+			i.Idle()
+		}
+
+		return err
+	}
+
+	r.log(RelayRelease, nil)
+	return relayWrite(r.Conn, relayMsgRelease, nil)
+}
+
+// Relay the commands of the reader, starting with the first n bytes of rx,
+// until an error occurs. Return ETGRELEASED once the reader releases the
+// target. timeout is in milliseconds.
+func (r *RelayTarget) session(rx []byte, n, timeout int) error {
+	if err := r.Device.SetPropertyBool(EasyFraming, true); err != nil {
+		return err
+	}
+
+	for {
+		r.log(RelayCommand, rx[:n])
+		if err := relayWrite(r.Conn, relayMsgCommand, rx[:n]); err != nil {
+			return err
+		}
+
+		typ, res, err := relayRead(r.Conn)
+		switch {
+		case err != nil:
+			return err
+		case typ == relayMsgError:
+			return parseRelayError(res)
+		case typ != relayMsgResponse:
+			return fmt.Errorf("relay: unexpected message type %#02x", typ)
+		}
+
+		r.log(RelayResponse, res)
+		if _, err = r.Device.TargetSendBytes(res, timeout); err != nil {
+			return err
+		}
+
+		if n, err = r.Device.TargetReceiveBytes(rx, timeout); err != nil {
+			return err
+		}
+	}
+}
+
+// RelayInitiator is the card facing side of a relay. It receives commands
+// from a RelayTarget over Conn, sends them to a card with the device in
+// initiator mode and sends back the responses. The card must be selected
+// before calling Serve().
+type RelayInitiator struct {
+	Device  Initiator        // device talking to the card
+	Conn    net.Conn         // link to the RelayTarget
+	Timeout time.Duration    // see Device.InitiatorTransceiveBytesTimeout()
+	Log     func(RelayEvent) // if not nil, called for each event
+}
+
+// Make a new RelayInitiator using d and relaying over c with the default
+// timeout.
+func NewRelayInitiator(d Initiator, c net.Conn) *RelayInitiator {
+	return &RelayInitiator{Device: d, Conn: c, Timeout: DefaultTimeout}
+}
+
+// Call r.Log if set.
+func (r *RelayInitiator) log(typ RelayEventType, data []byte) {
+	if r.Log != nil {
+		r.Log(RelayEvent{typ, time.Now(), append([]byte(nil), data...)})
+	}
+}
+
+// Relay commands to the card until the RelayTarget closes the link, in
+// which case nil is returned. If the card cannot be reached, the error is
+// reported to the RelayTarget, which ends its session, and Serve() goes on
+// with the next command, e.g. after the card was selected again. Sessions
+// released by the reader are logged, but do not stop Serve() either.
+func (r *RelayInitiator) Serve() error {
+	rx := make([]byte, 264)
+	for {
+		typ, cmd, err := relayRead(r.Conn)
+		if err == io.EOF || errors.Is(err, net.ErrClosed) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		switch typ {
+		case relayMsgCommand:
+			r.log(RelayCommand, cmd)
+			n, err := transceiveBytesTimeout(r.Device, cmd, rx, r.Timeout)
+			if err != nil {
+				if err = relayWrite(r.Conn, relayMsgError, relayError(err)); err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			r.log(RelayResponse, rx[:n])
+			if err = relayWrite(r.Conn, relayMsgResponse, rx[:n]); err != nil {
+				return err
+			}
+
+		case relayMsgRelease:
+			r.log(RelayRelease, nil)
+
+		default:
+			return fmt.Errorf("relay: unexpected message type %#02x", typ)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "errors"
+import "net"
+import "testing"
+
+// An Initiator whose card is simulated by a function.
+type initiatorFunc func(tx []byte) ([]byte, error)
+
+func (f initiatorFunc) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (int, error) {
+	res, err := f(tx)
+	if err != nil {
+		return 0, err
+	}
+
+	return copy(rx, res), nil
+}
+
+func (f initiatorFunc) InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxPar []byte) (int, error) {
+	return 0, Error(EDEVNOTSUPP)
+}
+
+// Relay a session with a simulated Type 4 Tag over a pipe.
+func TestRelay(t *testing.T) {
+	selectNDEF := []byte{0x00, 0xa4, 0x04, 0x00, 0x07, 0xd2, 0x76, 0x00, 0x00, 0x85, 0x01, 0x01, 0x00}
+	selectCC := []byte{0x00, 0xa4, 0x00, 0x0c, 0x02, 0xe1, 0x03}
+	reader := &fakeTargetDevice{script: [][]byte{selectNDEF, selectCC, {0x00, 0xb0, 0x00, 0x00, 0x02}}}
+
+	card := NewType4Emulator(nil, NewNDEFBuffer(nil, true))
+	sim := initiatorFunc(func(tx []byte) ([]byte, error) { return card.Process(tx), nil })
+
+	tc, ic := net.Pipe()
+	rt := NewRelayTarget(reader, &card.Target, tc)
+	ri := NewRelayInitiator(sim, ic)
+
+	var targetLog, initiatorLog []RelayEvent
+	rt.Log = func(ev RelayEvent) { targetLog = append(targetLog, ev) }
+	ri.Log = func(ev RelayEvent) { initiatorLog = append(initiatorLog, ev) }
+
+	done := make(chan error, 1)
+	go func() { done <- ri.Serve() }()
+
+	if err := rt.Run(); err != nil {
+		t.Fatal("RelayTarget.Run():", err)
+	}
+
+	tc.Close()
+	if err := <-done; err != nil {
+		t.Fatal("RelayInitiator.Serve():", err)
+	}
+
+	want := [][]byte{{0x90, 0x00}, {0x90, 0x00}, {0x00, 0x0f, 0x90, 0x00}}
+	if len(reader.sent) != len(want) {
+		t.Fatalf("reader got %d responses, want %d", len(reader.sent), len(want))
+	}
+
+	for i := range want {
+		if !bytes.Equal(reader.sent[i], want[i]) {
+			t.Errorf("response %d: got % x, want % x", i, reader.sent[i], want[i])
+		}
+	}
+
+	for _, l := range [][]RelayEvent{targetLog, initiatorLog} {
+		if len(l) != 7 {
+			t.Errorf("got %d events, want 7", len(l))
+			continue
+		}
+
+		for i, ev := range l {
+			wantType := RelayEventType(i % 2)
+			if i == 6 {
+				wantType = RelayRelease
+			}
+
+			if ev.Type != wantType {
+				t.Errorf("event %d: got %v, want %v", i, ev.Type, wantType)
+			}
+
+			if i > 0 && ev.Time.Before(l[i-1].Time) {
+				t.Errorf("event %d: timestamps out of order", i)
+			}
+		}
+
+		if !bytes.Equal(l[2].Data, selectCC) {
+			t.Errorf("event 2: relayed % x, want % x", l[2].Data, selectCC)
+		}
+	}
+}
+
+// A fakeTargetDevice counting calls to Idle().
+type idleTargetDevice struct {
+	fakeTargetDevice
+	idled int
+}
+
+func (d *idleTargetDevice) Idle() error {
+	d.idled++
+	return nil
+}
+
+// Report errors of the card to the reader side and relay the next session.
+func TestRelayError(t *testing.T) {
+	readBinary := []byte{0x00, 0xb0, 0x00, 0x00, 0x02}
+	cardErr := error(Error(ERFTRANS))
+	sim := initiatorFunc(func(tx []byte) ([]byte, error) {
+		if cardErr != nil {
+			return nil, cardErr
+		}
+
+		return []byte{0x00, 0x0f, 0x90, 0x00}, nil
+	})
+
+	tc, ic := net.Pipe()
+
+	done := make(chan error, 1)
+	go func() { done <- NewRelayInitiator(sim, ic).Serve() }()
+
+	reader := &idleTargetDevice{fakeTargetDevice: fakeTargetDevice{script: [][]byte{readBinary}}}
+	err := NewRelayTarget(reader, &ISO14443aTarget{}, tc).Run()
+	var remote *RelayRemoteError
+	if !errors.As(err, &remote) || !errors.Is(err, Error(ERFTRANS)) {
+		t.Errorf("RelayTarget.Run(): got error %v, want remote %v", err, Error(ERFTRANS))
+	}
+
+	if reader.idled != 1 {
+		t.Errorf("target released %d times, want 1", reader.idled)
+	}
+
+	cardErr = errors.New("card on fire")
+	reader = &idleTargetDevice{fakeTargetDevice: fakeTargetDevice{script: [][]byte{readBinary}}}
+	err = NewRelayTarget(reader, &ISO14443aTarget{}, tc).Run()
+	if err == nil || err.Error() != "relay: remote error: card on fire" {
+		t.Errorf("RelayTarget.Run(): got error %v, want remote card on fire", err)
+	}
+
+	cardErr = nil
+	reader = &idleTargetDevice{fakeTargetDevice: fakeTargetDevice{script: [][]byte{readBinary}}}
+	if err = NewRelayTarget(reader, &ISO14443aTarget{}, tc).Run(); err != nil {
+		t.Error("RelayTarget.Run() after card error:", err)
+	}
+
+	if reader.idled != 0 || len(reader.sent) != 1 {
+		t.Errorf("released session: %d responses, idled %d times", len(reader.sent), reader.idled)
+	}
+
+	tc.Close()
+	if err = <-done; err != nil {
+		t.Error("RelayInitiator.Serve():", err)
+	}
+}
@@ -0,0 +1,391 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "io"
+import "net"
+
+// SNEP protocol version implemented by this package, 1.0.
+const SNEPVersion = 0x10
+
+// SNEP request codes.
+const (
+	SNEPContinue = 0x00
+	SNEPGet      = 0x01
+	SNEPPut      = 0x02
+	SNEPReject   = 0x7f
+)
+
+// SNEP response codes. Responses other than SNEPRespContinue and
+// SNEPRespSuccess are reported as SNEPError.
+const (
+	SNEPRespContinue           = 0x80
+	SNEPRespSuccess            = 0x81
+	SNEPRespNotFound           = 0xc0
+	SNEPRespExcessData         = 0xc1
+	SNEPRespBadRequest         = 0xc2
+	SNEPRespNotImplemented     = 0xe0
+	SNEPRespUnsupportedVersion = 0xe1
+	SNEPRespReject             = 0xff
+)
+
+// Length of the SNEP message header: version, code and length.
+const snepHeaderLen = 6
+
+// Default maximum length of a message accepted by the SNEP server.
+const snepDefaultMaxMessage = 1 << 20
+
+// SNEPError is an unsuccessful SNEP response code. Handlers of an
+// SNEPServer can return an SNEPError to choose the response sent.
+type SNEPError byte
+
+var snepErrorMessages = map[SNEPError]string{
+	SNEPRespNotFound:           "not found",
+	SNEPRespExcessData:         "excess data",
+	SNEPRespBadRequest:         "bad request",
+	SNEPRespNotImplemented:     "not implemented",
+	SNEPRespUnsupportedVersion: "unsupported version",
+	SNEPRespReject:             "rejected",
+}
+
+func (e SNEPError) Error() string {
+	msg, ok := snepErrorMessages[e]
+	if !ok {
+		return fmt.Sprintf("SNEP: response code %#02x", byte(e))
+	}
+
+	return "SNEP: " + msg
+}
+
+// Return the fragment size to use for c, the MIU of the peer for LLCP
+// connections.
+func snepFragmentSize(c net.Conn) int {
+	if lc, ok := c.(*LLCPConn); ok {
+		lc.link.mu.Lock()
+		defer lc.link.mu.Unlock()
+
+		return lc.miu
+	}
+
+	return llcpDefaultMIU
+}
+
+// Write a SNEP message with code and info to c, fragmenting it if it is
+// larger than frag. After the first fragment, the peer's answer is read: if
+// it is cont, the remaining fragments are sent, if it is reject, the
+// transfer is aborted.
+func snepWrite(c net.Conn, frag int, code byte, info []byte, cont, reject byte) error {
+	msg := make([]byte, snepHeaderLen, snepHeaderLen+len(info))
+	msg[0] = SNEPVersion
+	msg[1] = code
+	binary.BigEndian.PutUint32(msg[2:], uint32(len(info)))
+	msg = append(msg, info...)
+
+	if len(msg) <= frag {
+		_, err := c.Write(msg)
+		return err
+	}
+
+	if _, err := c.Write(msg[:frag]); err != nil {
+		return err
+	}
+
+	var hdr [snepHeaderLen]byte
+	if _, err := io.ReadFull(c, hdr[:]); err != nil {
+		return err
+	}
+
+	switch hdr[1] {
+	case cont:
+	case reject:
+		return SNEPError(SNEPRespReject)
+	default:
+		return fmt.Errorf("SNEP: unexpected code %#02x during fragmentation", hdr[1])
+	}
+
+	_, err := c.Write(msg[frag:])
+	return err
+}
+
+// Read a SNEP message of at most maxLen bytes of information from c. If the
+// message is fragmented, its first fragment is answered with cont, or with
+// reject if the message is too long, in which case SNEPRespReject is
+// returned. A message that is too long but not fragmented yields
+// SNEPRespExcessData.
+func snepRead(c net.Conn, maxLen int, cont, reject byte) (code byte, info []byte, err error) {
+	buf := make([]byte, 4096)
+	n := 0
+	for n < snepHeaderLen {
+		var m int
+		m, err = c.Read(buf[n:])
+		n += m
+		if err != nil {
+			if err == io.EOF && n > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+
+			return
+		}
+	}
+
+	code = buf[1]
+	if buf[0]>>4 != SNEPVersion>>4 {
+		err = SNEPError(SNEPRespUnsupportedVersion)
+		return
+	}
+
+	length := binary.BigEndian.Uint32(buf[2:])
+	fragmented := uint64(length) > uint64(n-snepHeaderLen)
+	if uint64(length) > uint64(maxLen) {
+		if fragmented {
+			snepWriteHeader(c, reject)
+			err = SNEPError(SNEPRespReject)
+		} else {
+			err = SNEPError(SNEPRespExcessData)
+		}
+
+		return
+	}
+
+	info = make([]byte, length)
+	got := copy(info, buf[snepHeaderLen:n])
+	if fragmented {
+		if err = snepWriteHeader(c, cont); err != nil {
+			return
+		}
+
+		_, err = io.ReadFull(c, info[got:])
+	}
+
+	return
+}
+
+// Write a SNEP message without information.
+func snepWriteHeader(c net.Conn, code byte) error {
+	_, err := c.Write([]byte{SNEPVersion, code, 0, 0, 0, 0})
+	return err
+}
+
+// SNEPClient sends requests to a SNEP server.
+type SNEPClient struct {
+	Conn     net.Conn // connection to the server
+	Fragment int      // maximum size of a fragment
+
+	// Maximum length of a Get response the client accepts.
+	MaxResponse uint32
+}
+
+// Make a new SNEP client talking over c. Fragments are no larger than the
+// MIU of the peer if c is an *LLCPConn.
+func NewSNEPClient(c net.Conn) *SNEPClient {
+	return &SNEPClient{
+		Conn:        c,
+		Fragment:    snepFragmentSize(c),
+		MaxResponse: snepDefaultMaxMessage,
+	}
+}
+
+// Connect to the default SNEP server of the peer of l.
+func DialSNEP(l *LLCPLink) (*SNEPClient, error) {
+	c, err := l.Dial(LLCPServiceSNEP)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewSNEPClient(c), nil
+}
+
+// Send a request and wait for the response. Returns the information of a
+// successful response or an SNEPError.
+func (s *SNEPClient) request(code byte, info []byte) ([]byte, error) {
+	err := snepWrite(s.Conn, s.Fragment, code, info, SNEPRespContinue, SNEPRespReject)
+	if err != nil {
+		return nil, err
+	}
+
+	rcode, rinfo, err := snepRead(s.Conn, int(s.MaxResponse), SNEPContinue, SNEPReject)
+	if err != nil {
+		return nil, err
+	}
+
+	if rcode != SNEPRespSuccess {
+		return nil, SNEPError(rcode)
+	}
+
+	return rinfo, nil
+}
+
+// Push NDEF message msg to the server.
+func (s *SNEPClient) Put(msg []byte) error {
+	_, err := s.request(SNEPPut, msg)
+	return err
+}
+
+// Request an NDEF message from the server, identified by the NDEF message
+// req. Returns the message sent by the server.
+func (s *SNEPClient) Get(req []byte) ([]byte, error) {
+	info := make([]byte, 4, 4+len(req))
+	binary.BigEndian.PutUint32(info, s.MaxResponse)
+	info = append(info, req...)
+
+	return s.request(SNEPGet, info)
+}
+
+// Close the connection to the server.
+func (s *SNEPClient) Close() error {
+	return s.Conn.Close()
+}
+
+// SNEPServer answers SNEP requests by calling its handlers.
+type SNEPServer struct {
+	// Called for each Put request with the NDEF message received. If the
+	// handler returns an SNEPError, that code is sent as response, any
+	// other error is answered with SNEPRespBadRequest. If Put is nil,
+	// Put requests are answered with SNEPRespNotImplemented.
+	Put func(msg []byte) error
+
+	// Called for each Get request with the NDEF message identifying the
+	// message requested. Errors are handled like for Put. If Get is
+	// nil, Get requests are answered with SNEPRespNotImplemented.
+	Get func(req []byte) ([]byte, error)
+
+	// Maximum length of a request accepted, 0 for 1 MiB.
+	MaxMessage int
+}
+
+// Make the default SNEP server which hands the messages received with Put
+// requests to put and does not implement Get.
+func NewSNEPServer(put func(msg []byte)) *SNEPServer {
+	return &SNEPServer{
+		Put: func(msg []byte) error {
+			put(msg)
+			return nil
+		},
+	}
+}
+
+// Accept connections on ln and serve each of them in its own goroutine
+// until ln is closed.
+func (s *SNEPServer) Serve(ln net.Listener) error {
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+
+			return err
+		}
+
+		go s.ServeConn(c)
+	}
+}
+
+// Listen on the well-known SNEP service of l and serve requests until the
+// link goes down.
+func (s *SNEPServer) ListenAndServe(l *LLCPLink) error {
+	ln, err := l.Listen(LLCPServiceSNEP)
+	if err != nil {
+		return err
+	}
+
+	defer ln.Close()
+
+	return s.Serve(ln)
+}
+
+// Turn an error returned by a handler into a response code.
+func snepErrorCode(err error) byte {
+	var e SNEPError
+	if errors.As(err, &e) {
+		return byte(e)
+	}
+
+	return SNEPRespBadRequest
+}
+
+// Serve requests on c until the client closes it. c is closed on return.
+func (s *SNEPServer) ServeConn(c net.Conn) error {
+	defer c.Close()
+
+	maxLen := s.MaxMessage
+	if maxLen == 0 {
+		maxLen = snepDefaultMaxMessage
+	}
+
+	frag := snepFragmentSize(c)
+	for {
+		code, info, err := snepRead(c, maxLen, SNEPRespContinue, SNEPRespReject)
+		switch err {
+		case nil:
+		case io.EOF:
+			return nil
+		case SNEPError(SNEPRespUnsupportedVersion):
+			snepWriteHeader(c, SNEPRespUnsupportedVersion)
+			return err
+		case SNEPError(SNEPRespReject):
+			// the client was sent a Reject already
+			continue
+		case SNEPError(SNEPRespExcessData):
+			if err = snepWriteHeader(c, SNEPRespReject); err != nil {
+				return err
+			}
+
+			continue
+		default:
+			return err
+		}
+
+		rcode := byte(SNEPRespSuccess)
+		var rinfo []byte
+
+		switch {
+		case code == SNEPPut && s.Put != nil:
+			if err = s.Put(info); err != nil {
+				rcode = snepErrorCode(err)
+			}
+
+		case code == SNEPGet && s.Get != nil:
+			if len(info) < 4 {
+				rcode = SNEPRespBadRequest
+				break
+			}
+
+			acceptable := binary.BigEndian.Uint32(info)
+			rinfo, err = s.Get(info[4:])
+			if err != nil {
+				rcode = snepErrorCode(err)
+				rinfo = nil
+			} else if uint64(len(rinfo)) > uint64(acceptable) {
+				rcode = SNEPRespExcessData
+				rinfo = nil
+			}
+
+		case code == SNEPPut || code == SNEPGet:
+			rcode = SNEPRespNotImplemented
+
+		default:
+			rcode = SNEPRespBadRequest
+		}
+
+		err = snepWrite(c, frag, rcode, rinfo, SNEPContinue, SNEPReject)
+		if err != nil && err != SNEPError(SNEPRespReject) {
+			return err
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "testing"
+
+// Push and fetch fragmented messages over a loopback LLCP link.
+func TestSNEP(t *testing.T) {
+	ini, tgt := llcpPipe(t)
+	defer ini.Close()
+
+	// an NDEF message with a single short text record, padded to
+	// require fragmentation
+	msg := append([]byte{0xd1, 0x01, 0xf4, 'T', 0x02, 'e', 'n'}, bytes.Repeat([]byte("x"), 241)...)
+
+	received := make(chan []byte, 1)
+	srv := NewSNEPServer(func(m []byte) { received <- m })
+	srv.Get = func(req []byte) ([]byte, error) {
+		if !bytes.Equal(req, msg[:7]) {
+			return nil, SNEPError(SNEPRespNotFound)
+		}
+
+		return msg, nil
+	}
+
+	ln, err := tgt.Listen(LLCPServiceSNEP)
+	if err != nil {
+		t.Fatal("Listen():", err)
+	}
+
+	go srv.Serve(ln)
+
+	c, err := DialSNEP(ini)
+	if err != nil {
+		t.Fatal("DialSNEP():", err)
+	}
+
+	defer c.Close()
+
+	if err = c.Put(msg); err != nil {
+		t.Fatal("Put():", err)
+	}
+
+	if m := <-received; !bytes.Equal(m, msg) {
+		t.Errorf("server received % x, want % x", m, msg)
+	}
+
+	m, err := c.Get(msg[:7])
+	if err != nil {
+		t.Fatal("Get():", err)
+	}
+
+	if !bytes.Equal(m, msg) {
+		t.Errorf("Get() = % x, want % x", m, msg)
+	}
+
+	if _, err = c.Get([]byte{0xd0, 0x00, 0x00}); err != SNEPError(SNEPRespNotFound) {
+		t.Errorf("Get() of missing message: got error %v, want %v", err, SNEPError(SNEPRespNotFound))
+	}
+
+	c.MaxResponse = 16
+	if _, err = c.Get(msg[:7]); err != SNEPError(SNEPRespExcessData) {
+		t.Errorf("Get() of long message: got error %v, want %v", err, SNEPError(SNEPRespExcessData))
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/binary"
+import "errors"
+import "fmt"
+import "time"
+
+// ST SRx (ISO14443-2B) commands. See the SRI512, SRT512, SRI2K, SRI4K and
+// SRIX4K datasheets for details.
+const (
+	SRxInitiate         = 0x06 // followed by 0x00
+	SRxReadBlock        = 0x08
+	SRxWriteBlock       = 0x09
+	SRxGetUID           = 0x0b
+	SRxResetToInventory = 0x0c
+	SRxSelect           = 0x0e
+	SRxCompletion       = 0x0f
+)
+
+// Blocks of the SRx memory map with special semantics. Not all models have
+// all of them, see the datasheet of your tag for details.
+const (
+	SRxBlockOTP      = 0   // first of five blocks of resettable OTP bits
+	SRxBlockCounter5 = 5   // 32 bit binary counter, can only be decremented
+	SRxBlockCounter6 = 6   // dito, decrementing it resets the OTP area
+	SRxBlockSystem   = 255 // system area holding OTP_Lock_Reg
+)
+
+// ST SRx chip models. The model can be determined from the product code in
+// the tag's UID, see SRxModelFromUID().
+type SRxModel int
+
+// Known SRx chip models. The values correspond to the product codes found
+// in the UID.
+const (
+	SRxUnknown SRxModel = -1
+	SRIX4K     SRxModel = 0x03
+	SRIX512    SRxModel = 0x04
+	SRI512     SRxModel = 0x06
+	SRI4K      SRxModel = 0x07
+	SRT512     SRxModel = 0x0c
+	SRI2K      SRxModel = 0x0f
+)
+
+var srxModelNames = map[SRxModel]string{
+	SRIX4K:  "SRIX4K",
+	SRIX512: "SRIX512",
+	SRI512:  "SRI512",
+	SRI4K:   "SRI4K",
+	SRT512:  "SRT512",
+	SRI2K:   "SRI2K",
+}
+
+// Print the name of an SRx model, e.g. "SRIX4K".
+func (m SRxModel) String() string {
+	name, ok := srxModelNames[m]
+	if !ok {
+		return "unknown SRx model"
+	}
+
+	return name
+}
+
+// Return the number of user addressable 32 bit blocks of model m, not
+// counting the system area. Returns 0 for unknown models.
+func (m SRxModel) Blocks() int {
+	switch m {
+	case SRI512, SRT512, SRIX512:
+		return 16
+	case SRI2K:
+		return 64
+	case SRI4K, SRIX4K:
+		return 128
+	default:
+		return 0
+	}
+}
+
+// Determine the chip model from the UID of an SRx tag as returned by the
+// GET_UID command. The UID is stored least significant byte first; its most
+// significant byte is always 0xd0 followed by the ST manufacturer code 0x02
+// and the 6 bit product code.
+func SRxModelFromUID(uid [8]byte) SRxModel {
+	if uid[7] != 0xd0 || uid[6] != 0x02 {
+		return SRxUnknown
+	}
+
+	m := SRxModel(uid[5] >> 2)
+	if _, ok := srxModelNames[m]; !ok {
+		return SRxUnknown
+	}
+
+	return m
+}
+
+// Determine the chip model of an SRx target. See SRxModelFromUID().
+func (t *ISO14443b2srTarget) Model() SRxModel {
+	return SRxModelFromUID(t.UID)
+}
+
+// The system area (block 255) of an SRx tag. The layout of OTP_Lock_Reg
+// depends on the chip model.
+type SRxSystemArea struct {
+	Model SRxModel
+	Raw   uint32 // block contents, bit b0 is the least significant bit
+}
+
+// The fixed Chip_ID stored in bits b0 to b7 of the system area.
+func (a SRxSystemArea) ChipID() byte {
+	return byte(a.Raw)
+}
+
+// The OTP_Lock_Reg. On 512 bit models it occupies bits b16 to b31, on the
+// other models bits b24 to b31. A cleared bit write-protects the
+// corresponding blocks; bits can never be set again once cleared.
+func (a SRxSystemArea) OTPLockReg() uint16 {
+	switch a.Model {
+	case SRI512, SRT512, SRIX512:
+		return uint16(a.Raw >> 16)
+	default:
+		return uint16(a.Raw >> 24)
+	}
+}
+
+// Report whether block is write-protected by OTP_Lock_Reg. On 512 bit
+// models, each of the 16 blocks has its own lock bit. On the other models,
+// only blocks 7 to 15 can be locked, with blocks 7 and 8 sharing one bit.
+func (a SRxSystemArea) IsLocked(block int) bool {
+	lock := a.OTPLockReg()
+
+	switch a.Model {
+	case SRI512, SRT512, SRIX512:
+		if block < 0 || block > 15 {
+			return false
+		}
+
+		return lock&(1<<uint(block)) == 0
+	default:
+		if block < 7 || block > 15 {
+			return false
+		}
+
+		if block == 7 {
+			block = 8
+		}
+
+		return lock&(1<<uint(block-8)) == 0
+	}
+}
+
+// SRx is a driver for ST SRx tags (ISO14443b2srTarget). Select a tag with
+// Device.InitiatorSelectPassiveTarget() using modulation ISO14443b2sr before
+// issuing commands; libnfc performs INITIATE, SELECT and GET_UID in the
+// process. CRC generation and checking is left to the device, so HandleCRC
+// must be enabled (the default).
+type SRx struct {
+	Device  Initiator     // device used to communicate with the tag
+	Timeout time.Duration // see Device.InitiatorTransceiveBytesTimeout()
+}
+
+// Make a new SRx driver communicating through d using the default timeout.
+func NewSRx(d Initiator) *SRx {
+	return &SRx{Device: d, Timeout: DefaultTimeout}
+}
+
+// Send tx to the tag and return the response, which must be exactly rxLen
+// bytes long.
+func (s *SRx) transceive(tx []byte, rxLen int) ([]byte, error) {
+	rx := make([]byte, rxLen)
+	n, err := transceiveBytesTimeout(s.Device, tx, rx, s.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if n != rxLen {
+		return nil, fmt.Errorf("SRx: command %#02x: expected %d byte response, got %d", tx[0], rxLen, n)
+	}
+
+	return rx, nil
+}
+
+// Send tx to the tag for a command that has no response. As the tag never
+// answers, a timeout indicates success.
+func (s *SRx) transmit(tx []byte) error {
+	var rx [1]byte
+	_, err := transceiveBytesTimeout(s.Device, tx, rx[:], s.Timeout)
+	switch err {
+	case nil:
+		return errors.New("SRx: unexpected response")
+	case Error(ETIMEOUT):
+		return nil
+	default:
+		return err
+	}
+}
+
+// Issue INITIATE. All tags in the field in ready state answer with a
+// random Chip_ID and enter inventory state.
+func (s *SRx) Initiate() (chipID byte, err error) {
+	rx, err := s.transceive([]byte{SRxInitiate, 0x00}, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	return rx[0], nil
+}
+
+// Issue SELECT for the tag with the given Chip_ID. The tag answers with its
+// Chip_ID and enters selected state.
+func (s *SRx) Select(chipID byte) error {
+	rx, err := s.transceive([]byte{SRxSelect, chipID}, 1)
+	if err != nil {
+		return err
+	}
+
+	if rx[0] != chipID {
+		return fmt.Errorf("SRx: selected Chip_ID %#02x, but %#02x answered", chipID, rx[0])
+	}
+
+	return nil
+}
+
+// Issue GET_UID. The UID is returned least significant byte first as in
+// ISO14443b2srTarget.UID.
+func (s *SRx) GetUID() (uid [8]byte, err error) {
+	rx, err := s.transceive([]byte{SRxGetUID}, len(uid))
+	if err != nil {
+		return
+	}
+
+	copy(uid[:], rx)
+	return
+}
+
+// Issue READ_BLOCK for block addr.
+func (s *SRx) ReadBlock(addr byte) (data [4]byte, err error) {
+	rx, err := s.transceive([]byte{SRxReadBlock, addr}, len(data))
+	if err != nil {
+		return
+	}
+
+	copy(data[:], rx)
+	return
+}
+
+// Issue WRITE_BLOCK for block addr. The tag does not acknowledge writes and
+// silently ignores writes to locked blocks, so read the block back if you
+// need to know whether the write succeeded.
+func (s *SRx) WriteBlock(addr byte, data [4]byte) error {
+	tx := []byte{SRxWriteBlock, addr, data[0], data[1], data[2], data[3]}
+	return s.transmit(tx)
+}
+
+// Issue COMPLETION. The tag enters deactivated state and does not respond
+// to any further command until it leaves the field.
+func (s *SRx) Completion() error {
+	return s.transmit([]byte{SRxCompletion})
+}
+
+// Issue RESET_TO_INVENTORY. The tag returns to inventory state and can be
+// selected again with Select().
+func (s *SRx) ResetToInventory() error {
+	return s.transmit([]byte{SRxResetToInventory})
+}
+
+// Read the 32 bit binary counter in block addr (SRxBlockCounter5 or
+// SRxBlockCounter6).
+func (s *SRx) ReadCounter(addr byte) (uint32, error) {
+	data, err := s.ReadBlock(addr)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.LittleEndian.Uint32(data[:]), nil
+}
+
+// Write value to the binary counter in block addr. The tag only accepts
+// writes that decrement the counter; this is checked before the write is
+// issued.
+func (s *SRx) WriteCounter(addr byte, value uint32) error {
+	old, err := s.ReadCounter(addr)
+	if err != nil {
+		return err
+	}
+
+	if value > old {
+		return fmt.Errorf("SRx: counter %d can only be decremented (%d > %d)", addr, value, old)
+	}
+
+	var data [4]byte
+	binary.LittleEndian.PutUint32(data[:], value)
+	return s.WriteBlock(addr, data)
+}
+
+// Read the resettable OTP area (blocks 0 to 4) as five 32 bit words.
+func (s *SRx) ReadOTP() (otp [5]uint32, err error) {
+	for i := range otp {
+		var data [4]byte
+		data, err = s.ReadBlock(SRxBlockOTP + byte(i))
+		if err != nil {
+			return
+		}
+
+		otp[i] = binary.LittleEndian.Uint32(data[:])
+	}
+
+	return
+}
+
+// Write value to the OTP block addr. Bits in the OTP area can only be
+// cleared, so writes that would set a bit are refused.
+func (s *SRx) WriteOTP(addr byte, value uint32) error {
+	if addr >= SRxBlockOTP+5 {
+		return fmt.Errorf("SRx: block %d is not an OTP block", addr)
+	}
+
+	data, err := s.ReadBlock(addr)
+	if err != nil {
+		return err
+	}
+
+	old := binary.LittleEndian.Uint32(data[:])
+	if value&^old != 0 {
+		return fmt.Errorf("SRx: cannot set OTP bits %#08x in block %d", value&^old, addr)
+	}
+
+	binary.LittleEndian.PutUint32(data[:], value)
+	return s.WriteBlock(addr, data)
+}
+
+// Read the system area of a tag of model m.
+func (s *SRx) ReadSystemArea(m SRxModel) (SRxSystemArea, error) {
+	data, err := s.ReadBlock(SRxBlockSystem)
+	if err != nil {
+		return SRxSystemArea{}, err
+	}
+
+	return SRxSystemArea{Model: m, Raw: binary.LittleEndian.Uint32(data[:])}, nil
+}
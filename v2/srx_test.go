@@ -0,0 +1,169 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "testing"
+
+// A frame exchanged with a scriptedInitiator: the expected command tx is
+// answered with rx and err.
+type scriptedExchange struct {
+	tx, rx []byte
+	err    error
+}
+
+// An Initiator answering the commands in script one after another. Each
+// command must match the next entry of the script.
+type scriptedInitiator struct {
+	t      *testing.T
+	script []scriptedExchange
+}
+
+func (s *scriptedInitiator) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (int, error) {
+	if len(s.script) == 0 {
+		s.t.Errorf("unexpected command % x", tx)
+		return 0, Error(EIO)
+	}
+
+	e := s.script[0]
+	s.script = s.script[1:]
+	if !bytes.Equal(tx, e.tx) {
+		s.t.Errorf("got command % x, want % x", tx, e.tx)
+	}
+
+	return copy(rx, e.rx), e.err
+}
+
+func (s *scriptedInitiator) InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxPar []byte) (int, error) {
+	return 0, Error(EDEVNOTSUPP)
+}
+
+// Check that the whole script has been played.
+func (s *scriptedInitiator) done() {
+	if len(s.script) != 0 {
+		s.t.Errorf("%d commands not sent, next is % x", len(s.script), s.script[0].tx)
+	}
+}
+
+func TestSRxModelFromUID(t *testing.T) {
+	uids := map[[8]byte]SRxModel{
+		{0x01, 0x02, 0x03, 0x04, 0x05, 0x0c, 0x02, 0xd0}: SRIX4K,
+		{0x01, 0x02, 0x03, 0x04, 0x05, 0x1b, 0x02, 0xd0}: SRI512, // low bits belong to the serial number
+		{0x01, 0x02, 0x03, 0x04, 0x05, 0x3c, 0x02, 0xd0}: SRI2K,
+		{0x01, 0x02, 0x03, 0x04, 0x05, 0x0c, 0x04, 0xd0}: SRxUnknown, // not made by ST
+		{0x01, 0x02, 0x03, 0x04, 0x05, 0x0c, 0x02, 0xe0}: SRxUnknown,
+		{0x01, 0x02, 0x03, 0x04, 0x05, 0xfc, 0x02, 0xd0}: SRxUnknown, // unknown product code
+	}
+
+	for uid, want := range uids {
+		if got := SRxModelFromUID(uid); got != want {
+			t.Errorf("SRxModelFromUID(% x) = %v, want %v", uid, got, want)
+		}
+	}
+}
+
+// The same system area locks different blocks depending on the model.
+func TestSRxSystemArea(t *testing.T) {
+	sri512 := SRxSystemArea{Model: SRI512, Raw: 0xfe0000ab}
+	srix4k := SRxSystemArea{Model: SRIX4K, Raw: 0xfe0000ab}
+
+	if sri512.ChipID() != 0xab || sri512.OTPLockReg() != 0xfe00 || srix4k.OTPLockReg() != 0x00fe {
+		t.Errorf("unexpected Chip_ID %#02x or OTP_Lock_Reg %#04x, %#04x",
+			sri512.ChipID(), sri512.OTPLockReg(), srix4k.OTPLockReg())
+	}
+
+	tests := []struct {
+		block          int
+		sri512, srix4k bool
+	}{
+		{-1, false, false},
+		{0, true, false},
+		{6, true, false},
+		{7, true, true}, // shares a lock bit with block 8 on SRIX4K
+		{8, true, true},
+		{9, false, false},
+		{15, false, false},
+		{16, false, false},
+	}
+
+	for _, tt := range tests {
+		if got := sri512.IsLocked(tt.block); got != tt.sri512 {
+			t.Errorf("SRI512: IsLocked(%d) = %v, want %v", tt.block, got, tt.sri512)
+		}
+
+		if got := srix4k.IsLocked(tt.block); got != tt.srix4k {
+			t.Errorf("SRIX4K: IsLocked(%d) = %v, want %v", tt.block, got, tt.srix4k)
+		}
+	}
+}
+
+// The tag does not answer write commands, so a timeout means success.
+func TestSRxTransmit(t *testing.T) {
+	d := &scriptedInitiator{t: t, script: []scriptedExchange{
+		{tx: []byte{SRxWriteBlock, 0x07, 1, 2, 3, 4}, err: Error(ETIMEOUT)},
+		{tx: []byte{SRxResetToInventory}, rx: []byte{0x00}},
+		{tx: []byte{SRxCompletion}, err: Error(EIO)},
+	}}
+	s := NewSRx(d)
+
+	if err := s.WriteBlock(7, [4]byte{1, 2, 3, 4}); err != nil {
+		t.Error("WriteBlock():", err)
+	}
+
+	if err := s.ResetToInventory(); err == nil {
+		t.Error("ResetToInventory() succeeded despite a response")
+	}
+
+	if err := s.Completion(); err != Error(EIO) {
+		t.Errorf("Completion(): got error %v, want %v", err, Error(EIO))
+	}
+
+	d.done()
+}
+
+// Writes that the tag would refuse must not be issued.
+func TestSRxRefusedWrites(t *testing.T) {
+	d := &scriptedInitiator{t: t, script: []scriptedExchange{
+		{tx: []byte{SRxReadBlock, SRxBlockCounter5}, rx: []byte{0x10, 0x00, 0x00, 0x00}},
+		{tx: []byte{SRxReadBlock, SRxBlockCounter5}, rx: []byte{0x10, 0x00, 0x00, 0x00}},
+		{tx: []byte{SRxWriteBlock, SRxBlockCounter5, 0x0f, 0x00, 0x00, 0x00}, err: Error(ETIMEOUT)},
+		{tx: []byte{SRxReadBlock, 2}, rx: []byte{0x0f, 0x00, 0x00, 0x00}},
+		{tx: []byte{SRxReadBlock, 2}, rx: []byte{0x0f, 0x00, 0x00, 0x00}},
+		{tx: []byte{SRxWriteBlock, 2, 0x05, 0x00, 0x00, 0x00}, err: Error(ETIMEOUT)},
+	}}
+	s := NewSRx(d)
+
+	if err := s.WriteCounter(SRxBlockCounter5, 0x11); err == nil {
+		t.Error("WriteCounter() incrementing the counter succeeded")
+	}
+
+	if err := s.WriteCounter(SRxBlockCounter5, 0x0f); err != nil {
+		t.Error("WriteCounter():", err)
+	}
+
+	if err := s.WriteOTP(SRxBlockCounter5, 0); err == nil {
+		t.Error("WriteOTP() to a counter block succeeded")
+	}
+
+	if err := s.WriteOTP(2, 0x10); err == nil {
+		t.Error("WriteOTP() setting a bit succeeded")
+	}
+
+	if err := s.WriteOTP(2, 0x05); err != nil {
+		t.Error("WriteOTP():", err)
+	}
+
+	d.done()
+}
@@ -16,6 +16,7 @@ package nfc
 
 // #include <nfc/nfc.h>
 // #include "marshall.h"
+// #include <stdlib.h>
 import "C"
 import "unsafe"
 import "errors"
@@ -26,18 +27,6 @@ func mallocTarget() *C.nfc_target {
 	return (*C.nfc_target)(C.malloc(targetSize))
 }
 
-// generic implementation for the String() functions of the Target interface.
-// Notice that this panics when TargetString returns an error.
-func tString(t Target) string {
-	str, err := TargetString(t, true)
-
-	if err != nil {
-		panic(err)
-	}
-
-	return str
-}
-
 // Go wrapper for nfc_target. Since the nfc_target structure contains a union,
 // we cannot directly map it to a Go type. Modulation() can be used to figure
 // out what kind of modulation was used for this Target and what type an
@@ -49,13 +38,15 @@ func tString(t Target) string {
 type Target interface {
 	Modulation() Modulation
 	Marshall() uintptr
-	String() string // uses TargetString() with verbose = true
+	String() string // same as Describe(true) of the concrete type
 }
 
 // Make a string from a target with proper error reporting. This is a wrapper
-// around str_nfc_target.
+// around str_nfc_target. The targets of this package provide the same
+// output through their Describe() methods without calling into C.
 func TargetString(t Target, verbose bool) (string, error) {
 	ptr := unsafe.Pointer(t.Marshall())
+	defer C.free(ptr)
 
 	var result *C.char = nil
 
@@ -137,7 +128,7 @@ type DEPTarget struct {
 }
 
 func (t *DEPTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always DEP
@@ -180,7 +171,7 @@ type ISO14443aTarget struct {
 }
 
 func (t *ISO14443aTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always ISO14443A
@@ -222,7 +213,7 @@ type FelicaTarget struct {
 }
 
 func (t *FelicaTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always FELICA
@@ -261,7 +252,7 @@ type ISO14443bTarget struct {
 }
 
 func (t *ISO14443bTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always ISO14443B
@@ -301,7 +292,7 @@ type ISO14443biTarget struct {
 }
 
 func (t *ISO14443biTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always ISO14443BI
@@ -337,7 +328,7 @@ type ISO14443b2srTarget struct {
 }
 
 func (t *ISO14443b2srTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always ISO14443B2SR
@@ -377,7 +368,7 @@ type ISO14443b2ctTarget struct {
 }
 
 func (t *ISO14443b2ctTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always ISO1444B2CT
@@ -414,7 +405,7 @@ type JewelTarget struct {
 }
 
 func (t *JewelTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always Jewel
@@ -451,7 +442,7 @@ type BarcodeTarget struct {
 }
 
 func (t *BarcodeTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always Barcode
@@ -487,7 +478,7 @@ type ISO14443biClassTarget struct {
 }
 
 func (t *ISO14443biClassTarget) String() string {
-	return tString(t)
+	return t.Describe(true)
 }
 
 // Type is always ISO14443biClass
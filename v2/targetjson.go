@@ -0,0 +1,535 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/hex"
+import "encoding/json"
+import "fmt"
+import "strconv"
+
+// JSON encoding of targets
+//
+// The MarshalJSON() methods of the targets encode them as a JSON object
+// whose "type" member holds the name of the modulation type as found in
+// ModulationTypes and whose "baud" member holds the baud rate as found in
+// BaudRates.  The other members correspond to the fields of the target;
+// byte fields are encoded as hex strings, cut to their length if the
+// target has a length field for them.  For example:
+//
+//	{"type":"ISO14443a","baud":"106","atqa":"0044","sak":"00","uid":"04112233445566"}
+//
+// Use UnmarshalTargetJSON() to decode a target of unknown type.
+
+// A byte slice encoded as a hex string.
+type hexBytes []byte
+
+func (h hexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *hexBytes) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	}
+
+	*h = b
+	return nil
+}
+
+// A single byte encoded as a hex string.
+type hexByte byte
+
+func (h hexByte) MarshalText() ([]byte, error) {
+	return []byte(fmt.Sprintf("%02x", byte(h))), nil
+}
+
+func (h *hexByte) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return err
+	} else if len(b) != 1 {
+		return fmt.Errorf("target: %q is not a single byte", text)
+	}
+
+	*h = hexByte(b[0])
+	return nil
+}
+
+// Copy h into the fixed-size field dst.
+func copyHex(dst []byte, h hexBytes, name string) error {
+	if len(h) != len(dst) {
+		return fmt.Errorf("target: %s must be %d bytes, not %d", name, len(dst), len(h))
+	}
+
+	copy(dst, h)
+	return nil
+}
+
+// Copy h into the variable-length field dst and return its length.
+func copyVarHex(dst []byte, h hexBytes, name string) (int, error) {
+	if len(h) > len(dst) {
+		return 0, fmt.Errorf("target: %s must be at most %d bytes, not %d", name, len(dst), len(h))
+	}
+
+	return copy(dst, h), nil
+}
+
+// The members common to all encoded targets.
+type targetJSONHeader struct {
+	Type string `json:"type"`
+	Baud string `json:"baud"`
+}
+
+// Make the header of the JSON encoding of a target.
+func newTargetJSONHeader(m Modulation) targetJSONHeader {
+	h := targetJSONHeader{strconv.Itoa(m.Type), strconv.Itoa(m.BaudRate)}
+	if 0 < m.Type && m.Type < len(ModulationTypes) {
+		h.Type = ModulationTypes[m.Type]
+	}
+
+	if 0 <= m.BaudRate && m.BaudRate < len(BaudRates) {
+		h.Baud = BaudRates[m.BaudRate]
+	}
+
+	return h
+}
+
+// Check that the header is that of a target of modulation type nmt and
+// return the baud rate.
+func (h *targetJSONHeader) check(nmt int) (int, error) {
+	if modulationTypeByName(h.Type) != nmt {
+		return 0, fmt.Errorf("target: type %q, want %q", h.Type, ModulationTypes[nmt])
+	}
+
+	baud := baudRateByName(h.Baud)
+	if baud < 0 {
+		return 0, fmt.Errorf("target: unknown baud rate %q", h.Baud)
+	}
+
+	return baud, nil
+}
+
+// Names of the D.E.P. modes in the JSON encoding of DEPTarget.
+var depModeNames = [...]string{
+	Undefined: "undefined",
+	Passive:   "passive",
+	Active:    "active",
+}
+
+// UnmarshalTargetJSON decodes a target encoded with the MarshalJSON()
+// method of any of the target types, using the "type" member to determine
+// the type of the target.
+func UnmarshalTargetJSON(buf []byte) (Target, error) {
+	var h targetJSONHeader
+	if err := json.Unmarshal(buf, &h); err != nil {
+		return nil, err
+	}
+
+	t := newTarget(modulationTypeByName(h.Type))
+	if t == nil {
+		return nil, fmt.Errorf("target: unknown type %q", h.Type)
+	}
+
+	if err := json.Unmarshal(buf, t); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+type depTargetJSON struct {
+	targetJSONHeader
+	NFCID3  hexBytes `json:"nfcid3"`
+	DID     hexByte  `json:"did"`
+	BS      hexByte  `json:"bs"`
+	BR      hexByte  `json:"br"`
+	TO      hexByte  `json:"to"`
+	PP      hexByte  `json:"pp"`
+	GB      hexBytes `json:"gb,omitempty"`
+	DepMode string   `json:"depMode"`
+}
+
+// Encode t as JSON in the format described above. DepMode is encoded as
+// "undefined", "passive" or "active".
+func (t *DEPTarget) MarshalJSON() ([]byte, error) {
+	if t.GBLen < 0 || t.GBLen > len(t.GB) {
+		return nil, fmt.Errorf("target: invalid GBLen %d", t.GBLen)
+	}
+
+	if t.DepMode < 0 || t.DepMode >= len(depModeNames) {
+		return nil, fmt.Errorf("target: invalid DepMode %d", t.DepMode)
+	}
+
+	return json.Marshal(depTargetJSON{
+		newTargetJSONHeader(t.Modulation()),
+		t.NFCID3[:], hexByte(t.DID), hexByte(t.BS), hexByte(t.BR),
+		hexByte(t.TO), hexByte(t.PP), t.GB[:t.GBLen],
+		depModeNames[t.DepMode],
+	})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *DEPTarget) UnmarshalJSON(buf []byte) error {
+	var j depTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+
+	var err error
+	dt := DEPTarget{DID: byte(j.DID), BS: byte(j.BS), BR: byte(j.BR), TO: byte(j.TO), PP: byte(j.PP), DepMode: -1}
+	if dt.Baud, err = j.check(DEP); err != nil {
+		return err
+	} else if err = copyHex(dt.NFCID3[:], j.NFCID3, "nfcid3"); err != nil {
+		return err
+	} else if dt.GBLen, err = copyVarHex(dt.GB[:], j.GB, "gb"); err != nil {
+		return err
+	}
+
+	for i, name := range depModeNames {
+		if name == j.DepMode {
+			dt.DepMode = i
+		}
+	}
+
+	if dt.DepMode < 0 {
+		return fmt.Errorf("target: unknown depMode %q", j.DepMode)
+	}
+
+	*t = dt
+	return nil
+}
+
+type iso14443aTargetJSON struct {
+	targetJSONHeader
+	Atqa hexBytes `json:"atqa"`
+	Sak  hexByte  `json:"sak"`
+	UID  hexBytes `json:"uid"`
+	Ats  hexBytes `json:"ats,omitempty"`
+}
+
+// Encode t as JSON in the format described above.
+func (t *ISO14443aTarget) MarshalJSON() ([]byte, error) {
+	if t.UIDLen < 0 || t.UIDLen > len(t.UID) {
+		return nil, fmt.Errorf("target: invalid UIDLen %d", t.UIDLen)
+	}
+
+	if t.AtsLen < 0 || t.AtsLen > len(t.Ats) {
+		return nil, fmt.Errorf("target: invalid AtsLen %d", t.AtsLen)
+	}
+
+	return json.Marshal(iso14443aTargetJSON{
+		newTargetJSONHeader(t.Modulation()),
+		t.Atqa[:], hexByte(t.Sak), t.UID[:t.UIDLen], t.Ats[:t.AtsLen],
+	})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *ISO14443aTarget) UnmarshalJSON(buf []byte) error {
+	var j iso14443aTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+
+	var err error
+	it := ISO14443aTarget{Sak: byte(j.Sak)}
+	if it.Baud, err = j.check(ISO14443a); err != nil {
+		return err
+	} else if err = copyHex(it.Atqa[:], j.Atqa, "atqa"); err != nil {
+		return err
+	} else if it.UIDLen, err = copyVarHex(it.UID[:], j.UID, "uid"); err != nil {
+		return err
+	} else if it.AtsLen, err = copyVarHex(it.Ats[:], j.Ats, "ats"); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+type felicaTargetJSON struct {
+	targetJSONHeader
+	Len     uint     `json:"len"`
+	ResCode hexByte  `json:"resCode"`
+	ID      hexBytes `json:"id"`
+	Pad     hexBytes `json:"pad"`
+	SysCode hexBytes `json:"sysCode"`
+}
+
+// Encode t as JSON in the format described above.
+func (t *FelicaTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(felicaTargetJSON{
+		newTargetJSONHeader(t.Modulation()),
+		t.Len, hexByte(t.ResCode), t.ID[:], t.Pad[:], t.SysCode[:],
+	})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *FelicaTarget) UnmarshalJSON(buf []byte) error {
+	var j felicaTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+
+	var err error
+	ft := FelicaTarget{Len: j.Len, ResCode: byte(j.ResCode)}
+	if ft.Baud, err = j.check(Felica); err != nil {
+		return err
+	} else if err = copyHex(ft.ID[:], j.ID, "id"); err != nil {
+		return err
+	} else if err = copyHex(ft.Pad[:], j.Pad, "pad"); err != nil {
+		return err
+	} else if err = copyHex(ft.SysCode[:], j.SysCode, "sysCode"); err != nil {
+		return err
+	}
+
+	*t = ft
+	return nil
+}
+
+type iso14443bTargetJSON struct {
+	targetJSONHeader
+	Pupi            hexBytes `json:"pupi"`
+	ApplicationData hexBytes `json:"applicationData"`
+	ProtocolInfo    hexBytes `json:"protocolInfo"`
+	CardIdentifier  hexByte  `json:"cardIdentifier"`
+}
+
+// Encode t as JSON in the format described above.
+func (t *ISO14443bTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(iso14443bTargetJSON{
+		newTargetJSONHeader(t.Modulation()),
+		t.Pupi[:], t.ApplicationData[:], t.ProtocolInfo[:], hexByte(t.CardIdentifier),
+	})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *ISO14443bTarget) UnmarshalJSON(buf []byte) error {
+	var j iso14443bTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+
+	var err error
+	it := ISO14443bTarget{CardIdentifier: byte(j.CardIdentifier)}
+	if it.Baud, err = j.check(ISO14443b); err != nil {
+		return err
+	} else if err = copyHex(it.Pupi[:], j.Pupi, "pupi"); err != nil {
+		return err
+	} else if err = copyHex(it.ApplicationData[:], j.ApplicationData, "applicationData"); err != nil {
+		return err
+	} else if err = copyHex(it.ProtocolInfo[:], j.ProtocolInfo, "protocolInfo"); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+type iso14443biTargetJSON struct {
+	targetJSONHeader
+	DIV    hexBytes `json:"div"`
+	VerLog hexByte  `json:"verLog"`
+	Config hexByte  `json:"config"`
+	Atr    hexBytes `json:"atr,omitempty"`
+}
+
+// Encode t as JSON in the format described above.
+func (t *ISO14443biTarget) MarshalJSON() ([]byte, error) {
+	if t.AtrLen < 0 || t.AtrLen > len(t.Atr) {
+		return nil, fmt.Errorf("target: invalid AtrLen %d", t.AtrLen)
+	}
+
+	return json.Marshal(iso14443biTargetJSON{
+		newTargetJSONHeader(t.Modulation()),
+		t.DIV[:], hexByte(t.VerLog), hexByte(t.Config), t.Atr[:t.AtrLen],
+	})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *ISO14443biTarget) UnmarshalJSON(buf []byte) error {
+	var j iso14443biTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+
+	var err error
+	it := ISO14443biTarget{VerLog: byte(j.VerLog), Config: byte(j.Config)}
+	if it.Baud, err = j.check(ISO14443bi); err != nil {
+		return err
+	} else if err = copyHex(it.DIV[:], j.DIV, "div"); err != nil {
+		return err
+	} else if it.AtrLen, err = copyVarHex(it.Atr[:], j.Atr, "atr"); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+// JSON encoding of targets consisting of just a UID.
+type uidTargetJSON struct {
+	targetJSONHeader
+	UID hexBytes `json:"uid"`
+}
+
+// Decode a target consisting of just a UID of modulation type nmt into
+// uid and return the baud rate.
+func unmarshalUIDTargetJSON(buf []byte, nmt int, uid []byte) (int, error) {
+	var j uidTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return 0, err
+	}
+
+	baud, err := j.check(nmt)
+	if err != nil {
+		return 0, err
+	}
+
+	return baud, copyHex(uid, j.UID, "uid")
+}
+
+// Encode t as JSON in the format described above.
+func (t *ISO14443b2srTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(uidTargetJSON{newTargetJSONHeader(t.Modulation()), t.UID[:]})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *ISO14443b2srTarget) UnmarshalJSON(buf []byte) error {
+	var it ISO14443b2srTarget
+	var err error
+	if it.Baud, err = unmarshalUIDTargetJSON(buf, ISO14443b2sr, it.UID[:]); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+type iso14443b2ctTargetJSON struct {
+	targetJSONHeader
+	UID      hexBytes `json:"uid"`
+	ProdCode hexByte  `json:"prodCode"`
+	FabCode  hexByte  `json:"fabCode"`
+}
+
+// Encode t as JSON in the format described above.
+func (t *ISO14443b2ctTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(iso14443b2ctTargetJSON{
+		newTargetJSONHeader(t.Modulation()),
+		t.UID[:], hexByte(t.ProdCode), hexByte(t.FabCode),
+	})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *ISO14443b2ctTarget) UnmarshalJSON(buf []byte) error {
+	var j iso14443b2ctTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+
+	var err error
+	it := ISO14443b2ctTarget{ProdCode: byte(j.ProdCode), FabCode: byte(j.FabCode)}
+	if it.Baud, err = j.check(ISO14443b2ct); err != nil {
+		return err
+	} else if err = copyHex(it.UID[:], j.UID, "uid"); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+type jewelTargetJSON struct {
+	targetJSONHeader
+	SensRes hexBytes `json:"sensRes"`
+	ID      hexBytes `json:"id"`
+}
+
+// Encode t as JSON in the format described above.
+func (t *JewelTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jewelTargetJSON{newTargetJSONHeader(t.Modulation()), t.SensRes[:], t.ID[:]})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *JewelTarget) UnmarshalJSON(buf []byte) error {
+	var j jewelTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+
+	var jt JewelTarget
+	var err error
+	if jt.Baud, err = j.check(Jewel); err != nil {
+		return err
+	} else if err = copyHex(jt.SensRes[:], j.SensRes, "sensRes"); err != nil {
+		return err
+	} else if err = copyHex(jt.ID[:], j.ID, "id"); err != nil {
+		return err
+	}
+
+	*t = jt
+	return nil
+}
+
+type barcodeTargetJSON struct {
+	targetJSONHeader
+	Data hexBytes `json:"data"`
+}
+
+// Encode t as JSON in the format described above.
+func (t *BarcodeTarget) MarshalJSON() ([]byte, error) {
+	if t.DataLen < 0 || t.DataLen > len(t.Data) {
+		return nil, fmt.Errorf("target: invalid DataLen %d", t.DataLen)
+	}
+
+	return json.Marshal(barcodeTargetJSON{newTargetJSONHeader(t.Modulation()), t.Data[:t.DataLen]})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *BarcodeTarget) UnmarshalJSON(buf []byte) error {
+	var j barcodeTargetJSON
+	if err := json.Unmarshal(buf, &j); err != nil {
+		return err
+	}
+
+	var bt BarcodeTarget
+	var err error
+	if bt.Baud, err = j.check(Barcode); err != nil {
+		return err
+	} else if bt.DataLen, err = copyVarHex(bt.Data[:], j.Data, "data"); err != nil {
+		return err
+	}
+
+	*t = bt
+	return nil
+}
+
+// Encode t as JSON in the format described above.
+func (t *ISO14443biClassTarget) MarshalJSON() ([]byte, error) {
+	return json.Marshal(uidTargetJSON{newTargetJSONHeader(t.Modulation()), t.UID[:]})
+}
+
+// Decode a target encoded with MarshalJSON() into t.
+func (t *ISO14443biClassTarget) UnmarshalJSON(buf []byte) error {
+	var it ISO14443biClassTarget
+	var err error
+	if it.Baud, err = unmarshalUIDTargetJSON(buf, ISO14443biClass, it.UID[:]); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "encoding/json"
+import "reflect"
+import "testing"
+
+// Round trip each type of target through JSON.
+func TestTargetJSON(t *testing.T) {
+	for _, tt := range testTargets {
+		buf, err := json.Marshal(tt)
+		if err != nil {
+			t.Errorf("json.Marshal(%T): %v", tt, err)
+			continue
+		}
+
+		got, err := UnmarshalTargetJSON(buf)
+		if err != nil {
+			t.Errorf("UnmarshalTargetJSON(%s): %v", buf, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt) {
+			t.Errorf("%s round trip: got %+v", buf, got)
+		}
+	}
+
+	buf, _ := json.Marshal(testTargets[0])
+	want := `{"type":"ISO14443a","baud":"106","atqa":"0344","sak":"20","uid":"04010203040506","ats":"7577810280"}`
+	if string(buf) != want {
+		t.Errorf("ISO14443aTarget encoded as %s, want %s", buf, want)
+	}
+
+	bad := []string{
+		`{"type":"Felica","baud":"106","atqa":"0344","sak":"20","uid":"04010203040506"}`,
+		`{"type":"ISO14443a","baud":"105","atqa":"0344","sak":"20","uid":"04010203040506"}`,
+		`{"type":"ISO14443a","baud":"106","atqa":"034400","sak":"20","uid":"04010203040506"}`,
+		`{"type":"ISO14443a","baud":"106","atqa":"0344","sak":"2000","uid":"04010203040506"}`,
+		`{"type":"ISO14443a","baud":"106","atqa":"0344","sak":"20","uid":"0401020304050607080910"}`,
+		`{"type":"ISO14443a","baud":"106","atqa":"0344","sak":"20","uid":"xx"}`,
+		`{"type":"DEP","baud":"106","nfcid3":"00000000000000000000","depMode":"foo"}`,
+	}
+
+	var a ISO14443aTarget
+	for _, b := range bad {
+		if _, err := UnmarshalTargetJSON([]byte(b)); err == nil {
+			t.Errorf("UnmarshalTargetJSON(%s) succeeded", b)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"type":"Felica","baud":"212"}`), &a); err == nil {
+		t.Error("ISO14443aTarget.UnmarshalJSON() accepted a FeliCa target")
+	}
+}
+
+func TestModulationText(t *testing.T) {
+	m := Modulation{Felica, Nbr424}
+	text, err := m.MarshalText()
+	if err != nil || string(text) != "Felica@424" {
+		t.Errorf("MarshalText() = %q, %v", text, err)
+	}
+
+	var m2 Modulation
+	if err = m2.UnmarshalText(text); err != nil || m2 != m {
+		t.Errorf("UnmarshalText(%q) = %v, %v", text, m2, err)
+	}
+
+	// unknown values cannot be decoded, so they must not be encoded
+	for _, m := range []Modulation{{42, Nbr106}, {Felica, 42}, {0, Nbr106}, {-1, Nbr106}, {Felica, -1}} {
+		if text, err := m.MarshalText(); err == nil {
+			t.Errorf("MarshalText(%#v) = %q, want error", m, text)
+		}
+	}
+
+	for _, s := range []string{"Felica@999", "Felica@", "Foo@106", "@106"} {
+		if err = m2.UnmarshalText([]byte(s)); err == nil {
+			t.Errorf("UnmarshalText(%q) succeeded", s)
+		}
+	}
+
+	e := Error(ETIMEOUT)
+	if text, _ = e.MarshalText(); string(text) != "ETIMEOUT" {
+		t.Errorf("MarshalText() = %q, want ETIMEOUT", text)
+	}
+
+	if err = e.UnmarshalText([]byte("EOVFLOW")); err != nil || e != EOVFLOW {
+		t.Errorf("UnmarshalText(EOVFLOW) = %v, %v", int(e), err)
+	}
+
+	if text, _ = Error(-42).MarshalText(); string(text) != "-42" {
+		t.Errorf("MarshalText() = %q, want -42", text)
+	}
+}
@@ -0,0 +1,444 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "errors"
+import "fmt"
+
+// Binary encoding of targets
+//
+// The MarshalBinary() methods of the targets encode them in a stable format
+// that does not depend on libnfc. Each encoding starts with the modulation
+// type and the baud rate (one byte each), followed by the fields of the
+// target in the order they are declared. Fixed-size fields are stored as
+// is; fields with a length field (e.g. UID and UIDLen) are stored as a
+// length byte followed by that many bytes. DEPTarget ends with DepMode
+// (one byte) and FelicaTarget starts with Len (one byte).
+
+// Return a new, empty target of the given modulation type or nil if the
+// type is unknown.
+func newTarget(nmt int) Target {
+	switch nmt {
+	case ISO14443a:
+		return new(ISO14443aTarget)
+	case Jewel:
+		return new(JewelTarget)
+	case Barcode:
+		return new(BarcodeTarget)
+	case ISO14443b:
+		return new(ISO14443bTarget)
+	case ISO14443bi:
+		return new(ISO14443biTarget)
+	case ISO14443b2sr:
+		return new(ISO14443b2srTarget)
+	case ISO14443b2ct:
+		return new(ISO14443b2ctTarget)
+	case Felica:
+		return new(FelicaTarget)
+	case DEP:
+		return new(DEPTarget)
+	case ISO14443biClass:
+		return new(ISO14443biClassTarget)
+	default:
+		return nil
+	}
+}
+
+// UnmarshalTarget decodes a target encoded with the MarshalBinary() method
+// of any of the target types.
+func UnmarshalTarget(buf []byte) (Target, error) {
+	if len(buf) < 2 {
+		return nil, errors.New("target: encoding too short")
+	}
+
+	t := newTarget(int(buf[0]))
+	if t == nil {
+		return nil, fmt.Errorf("target: unknown modulation type %d", buf[0])
+	}
+
+	err := t.(interface{ UnmarshalBinary([]byte) error }).UnmarshalBinary(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// Start the encoding of a target.
+func marshalHeader(nmt, baud int) ([]byte, error) {
+	if baud < 0 || baud > 0xff {
+		return nil, fmt.Errorf("target: invalid baud rate %d", baud)
+	}
+
+	return []byte{byte(nmt), byte(baud)}, nil
+}
+
+// Append the first n bytes of b to buf, prefixed with n.
+func marshalVar(buf, b []byte, n int, name string) ([]byte, error) {
+	if n < 0 || n > len(b) {
+		return nil, fmt.Errorf("target: invalid %s %d", name, n)
+	}
+
+	buf = append(buf, byte(n))
+	return append(buf, b[:n]...), nil
+}
+
+// Decoder for the binary encoding of targets. The first error is retained
+// and reported by finish().
+type targetDecoder struct {
+	buf  []byte
+	err  error
+	baud int
+}
+
+// Start decoding buf, which must hold a target of modulation type nmt.
+func newTargetDecoder(buf []byte, nmt int) *targetDecoder {
+	d := &targetDecoder{buf: buf}
+	if len(buf) < 2 {
+		d.err = errors.New("target: encoding too short")
+	} else if int(buf[0]) != nmt {
+		d.err = fmt.Errorf("target: modulation type %d, want %d", buf[0], nmt)
+	} else {
+		d.baud = int(buf[1])
+		d.buf = buf[2:]
+	}
+
+	return d
+}
+
+// Copy the next len(dst) bytes into dst.
+func (d *targetDecoder) read(dst []byte) {
+	if d.err != nil {
+		return
+	}
+
+	if len(d.buf) < len(dst) {
+		d.err = errors.New("target: encoding too short")
+		return
+	}
+
+	d.buf = d.buf[copy(dst, d.buf):]
+}
+
+// Return the next byte.
+func (d *targetDecoder) readByte() byte {
+	var b [1]byte
+	d.read(b[:])
+	return b[0]
+}
+
+// Copy a length-prefixed field into dst and return its length.
+func (d *targetDecoder) varBytes(dst []byte, name string) int {
+	n := int(d.readByte())
+	if d.err == nil && n > len(dst) {
+		d.err = fmt.Errorf("target: invalid %s %d", name, n)
+		return 0
+	}
+
+	d.read(dst[:n])
+	return n
+}
+
+// Finish decoding, making sure all of the input has been consumed.
+func (d *targetDecoder) finish() error {
+	if d.err == nil && len(d.buf) != 0 {
+		d.err = errors.New("target: trailing garbage after encoding")
+	}
+
+	return d.err
+}
+
+// Encode t in the format described above.
+func (t *DEPTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(DEP, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, t.NFCID3[:]...)
+	buf = append(buf, t.DID, t.BS, t.BR, t.TO, t.PP)
+	if buf, err = marshalVar(buf, t.GB[:], t.GBLen, "GBLen"); err != nil {
+		return nil, err
+	}
+
+	if t.DepMode < 0 || t.DepMode > 0xff {
+		return nil, fmt.Errorf("target: invalid DepMode %d", t.DepMode)
+	}
+
+	return append(buf, byte(t.DepMode)), nil
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *DEPTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, DEP)
+	dt := DEPTarget{Baud: d.baud}
+	d.read(dt.NFCID3[:])
+	dt.DID = d.readByte()
+	dt.BS = d.readByte()
+	dt.BR = d.readByte()
+	dt.TO = d.readByte()
+	dt.PP = d.readByte()
+	dt.GBLen = d.varBytes(dt.GB[:], "GBLen")
+	dt.DepMode = int(d.readByte())
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = dt
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *ISO14443aTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(ISO14443a, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, t.Atqa[:]...)
+	buf = append(buf, t.Sak)
+	if buf, err = marshalVar(buf, t.UID[:], t.UIDLen, "UIDLen"); err != nil {
+		return nil, err
+	}
+
+	return marshalVar(buf, t.Ats[:], t.AtsLen, "AtsLen")
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *ISO14443aTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, ISO14443a)
+	it := ISO14443aTarget{Baud: d.baud}
+	d.read(it.Atqa[:])
+	it.Sak = d.readByte()
+	it.UIDLen = d.varBytes(it.UID[:], "UIDLen")
+	it.AtsLen = d.varBytes(it.Ats[:], "AtsLen")
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *FelicaTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(Felica, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Len > 0xff {
+		return nil, fmt.Errorf("target: invalid Len %d", t.Len)
+	}
+
+	buf = append(buf, byte(t.Len), t.ResCode)
+	buf = append(buf, t.ID[:]...)
+	buf = append(buf, t.Pad[:]...)
+	return append(buf, t.SysCode[:]...), nil
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *FelicaTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, Felica)
+	ft := FelicaTarget{Baud: d.baud}
+	ft.Len = uint(d.readByte())
+	ft.ResCode = d.readByte()
+	d.read(ft.ID[:])
+	d.read(ft.Pad[:])
+	d.read(ft.SysCode[:])
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = ft
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *ISO14443bTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(ISO14443b, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, t.Pupi[:]...)
+	buf = append(buf, t.ApplicationData[:]...)
+	buf = append(buf, t.ProtocolInfo[:]...)
+	return append(buf, t.CardIdentifier), nil
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *ISO14443bTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, ISO14443b)
+	it := ISO14443bTarget{Baud: d.baud}
+	d.read(it.Pupi[:])
+	d.read(it.ApplicationData[:])
+	d.read(it.ProtocolInfo[:])
+	it.CardIdentifier = d.readByte()
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *ISO14443biTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(ISO14443bi, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, t.DIV[:]...)
+	buf = append(buf, t.VerLog, t.Config)
+	return marshalVar(buf, t.Atr[:], t.AtrLen, "AtrLen")
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *ISO14443biTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, ISO14443bi)
+	it := ISO14443biTarget{Baud: d.baud}
+	d.read(it.DIV[:])
+	it.VerLog = d.readByte()
+	it.Config = d.readByte()
+	it.AtrLen = d.varBytes(it.Atr[:], "AtrLen")
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *ISO14443b2srTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(ISO14443b2sr, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buf, t.UID[:]...), nil
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *ISO14443b2srTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, ISO14443b2sr)
+	it := ISO14443b2srTarget{Baud: d.baud}
+	d.read(it.UID[:])
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *ISO14443b2ctTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(ISO14443b2ct, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, t.UID[:]...)
+	return append(buf, t.ProdCode, t.FabCode), nil
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *ISO14443b2ctTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, ISO14443b2ct)
+	it := ISO14443b2ctTarget{Baud: d.baud}
+	d.read(it.UID[:])
+	it.ProdCode = d.readByte()
+	it.FabCode = d.readByte()
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *JewelTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(Jewel, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	buf = append(buf, t.SensRes[:]...)
+	return append(buf, t.ID[:]...), nil
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *JewelTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, Jewel)
+	jt := JewelTarget{Baud: d.baud}
+	d.read(jt.SensRes[:])
+	d.read(jt.ID[:])
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = jt
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *BarcodeTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(Barcode, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	return marshalVar(buf, t.Data[:], t.DataLen, "DataLen")
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *BarcodeTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, Barcode)
+	bt := BarcodeTarget{Baud: d.baud}
+	bt.DataLen = d.varBytes(bt.Data[:], "DataLen")
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = bt
+	return nil
+}
+
+// Encode t in the format described above.
+func (t *ISO14443biClassTarget) MarshalBinary() ([]byte, error) {
+	buf, err := marshalHeader(ISO14443biClass, t.Baud)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(buf, t.UID[:]...), nil
+}
+
+// Decode a target encoded with MarshalBinary() into t.
+func (t *ISO14443biClassTarget) UnmarshalBinary(buf []byte) error {
+	d := newTargetDecoder(buf, ISO14443biClass)
+	it := ISO14443biClassTarget{Baud: d.baud}
+	d.read(it.UID[:])
+	if err := d.finish(); err != nil {
+		return err
+	}
+
+	*t = it
+	return nil
+}
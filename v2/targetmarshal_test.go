@@ -0,0 +1,87 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "encoding"
+import "reflect"
+import "testing"
+
+// One target of each type.
+var testTargets = []Target{
+	&ISO14443aTarget{Atqa: [2]byte{0x03, 0x44}, Sak: 0x20, UIDLen: 7, UID: [10]byte{0x04, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06}, AtsLen: 5, Ats: [254]byte{0x75, 0x77, 0x81, 0x02, 0x80}, Baud: Nbr106},
+	&JewelTarget{SensRes: [2]byte{0x0c, 0x00}, ID: [4]byte{1, 2, 3, 4}, Baud: Nbr106},
+	&BarcodeTarget{DataLen: 3, Data: [32]byte{0xb7, 0x01, 0x02}, Baud: Nbr106},
+	&ISO14443bTarget{Pupi: [4]byte{1, 2, 3, 4}, ApplicationData: [4]byte{5, 6, 7, 8}, ProtocolInfo: [3]byte{0x00, 0x81, 0x71}, CardIdentifier: 1, Baud: Nbr106},
+	&ISO14443biTarget{DIV: [4]byte{1, 2, 3, 4}, VerLog: 0x80, Config: 0x40, AtrLen: 2, Atr: [33]byte{0x3b, 0x00}, Baud: Nbr106},
+	&ISO14443b2srTarget{UID: [8]byte{1, 2, 3, 4, 5, 6, 7, 0xd0}, Baud: Nbr106},
+	&ISO14443b2ctTarget{UID: [4]byte{1, 2, 3, 4}, ProdCode: 0x20, FabCode: 0x10, Baud: Nbr106},
+	&FelicaTarget{Len: 18, ResCode: 1, ID: [8]byte{1, 0x2e, 1, 2, 3, 4, 5, 6}, Pad: [8]byte{0, 0xf1}, SysCode: [2]byte{0x12, 0xfc}, Baud: Nbr212},
+	&DEPTarget{NFCID3: [10]byte{1, 2, 3}, DID: 0, BS: 0, BR: 0, TO: 0x0e, PP: 0x32, GBLen: 3, GB: [48]byte{0x46, 0x66, 0x6d}, DepMode: Passive, Baud: Nbr424},
+	&ISO14443biClassTarget{UID: [8]byte{1, 2, 3, 4, 5, 6, 7, 8}, Baud: Nbr106},
+}
+
+// Round trip each type of target through its binary encoding.
+func TestTargetMarshalBinary(t *testing.T) {
+	for _, tt := range testTargets {
+		buf, err := tt.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Errorf("%T.MarshalBinary(): %v", tt, err)
+			continue
+		}
+
+		if buf[0] != byte(tt.Modulation().Type) || buf[1] != byte(tt.Modulation().BaudRate) {
+			t.Errorf("%T: bad header % x", tt, buf[:2])
+		}
+
+		got, err := UnmarshalTarget(buf)
+		if err != nil {
+			t.Errorf("UnmarshalTarget(%T): %v", tt, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, tt) {
+			t.Errorf("%T round trip: got %+v", tt, got)
+		}
+
+		if _, err = UnmarshalTarget(buf[:len(buf)-1]); err == nil {
+			t.Errorf("%T: truncated encoding accepted", tt)
+		}
+
+		if _, err = UnmarshalTarget(append(buf, 0)); err == nil {
+			t.Errorf("%T: trailing garbage accepted", tt)
+		}
+	}
+
+	// the format is stable
+	buf, _ := testTargets[0].(encoding.BinaryMarshaler).MarshalBinary()
+	want := []byte{
+		ISO14443a, Nbr106, 0x03, 0x44, 0x20,
+		7, 0x04, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06,
+		5, 0x75, 0x77, 0x81, 0x02, 0x80,
+	}
+
+	if !bytes.Equal(buf, want) {
+		t.Errorf("ISO14443aTarget encoded as % x, want % x", buf, want)
+	}
+
+	if err := new(FelicaTarget).UnmarshalBinary(want); err == nil {
+		t.Error("FelicaTarget.UnmarshalBinary() accepted an ISO14443aTarget")
+	}
+
+	if _, err := (&ISO14443aTarget{UIDLen: 11}).MarshalBinary(); err == nil {
+		t.Error("MarshalBinary() accepted invalid UIDLen")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+// The methods in this file are variants of the Device methods taking a
+// timeout in milliseconds that take a time.Duration instead.
+// InitiatorTransceiveBytesTimed() and InitiatorTransceiveBitsTimed() take a
+// cycle limit instead of a timeout and InitiatorTransceiveBits() takes no
+// timeout at all, so they have no such variant. The rest of the package,
+// e.g. DEPConnect() or the Timeout fields of the card drivers and
+// emulators, takes time.Duration timeouts throughout.
+
+package nfc
+
+import "math"
+import "time"
+
+// Special timeouts for the methods taking a time.Duration timeout. Other
+// negative timeouts are invalid. Timeouts are rounded up to the next
+// millisecond, such that a short timeout does not turn into NoTimeout;
+// timeouts too long for libnfc are invalid.
+const (
+	NoTimeout      time.Duration = 0  // block until the operation completes
+	DefaultTimeout time.Duration = -1 // use the default timeout of the device
+)
+
+// Granularity and limit of the period of Device.InitiatorPollTarget().
+const (
+	PollPeriodUnit = 150 * time.Millisecond
+	MaxPollPeriod  = 15 * PollPeriodUnit
+)
+
+// Convert a timeout to milliseconds as expected by libnfc, mapping NoTimeout
+// to 0 and DefaultTimeout to -1. Return EINVARG for invalid timeouts.
+func timeoutMillis(timeout time.Duration) (int, error) {
+	switch {
+	case timeout == NoTimeout:
+		return 0, nil
+	case timeout == DefaultTimeout:
+		return -1, nil
+	case timeout < 0:
+		return 0, Error(EINVARG)
+	}
+
+	ms := timeout / time.Millisecond
+	if timeout%time.Millisecond != 0 {
+		ms++
+	}
+
+	if ms > math.MaxInt32 {
+		return 0, Error(EINVARG)
+	}
+
+	return int(ms), nil
+}
+
+// Call d.InitiatorTransceiveBytes() with a timeout of type time.Duration.
+// The card drivers use this to honour their Timeout fields.
+func transceiveBytesTimeout(d Initiator, tx, rx []byte, timeout time.Duration) (n int, err error) {
+	ms, err := timeoutMillis(timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.InitiatorTransceiveBytes(tx, rx, ms)
+}
+
+// Convert the period of InitiatorPollTarget() to units of PollPeriodUnit,
+// rounding up. Return EINVARG if it is not between 1 and 15 units.
+func pollPeriodUnits(period time.Duration) (int, error) {
+	if period <= 0 || period > MaxPollPeriod {
+		return 0, Error(EINVARG)
+	}
+
+	return int((period + PollPeriodUnit - 1) / PollPeriodUnit), nil
+}
+
+// Like InitiatorTransceiveBytes(), but with a timeout of type time.Duration.
+func (d Device) InitiatorTransceiveBytesTimeout(tx, rx []byte, timeout time.Duration) (n int, err error) {
+	ms, err := timeoutMillis(timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.InitiatorTransceiveBytes(tx, rx, ms)
+}
+
+// Like InitiatorSelectDEPTarget(), but with a timeout of type time.Duration.
+func (d Device) InitiatorSelectDEPTargetTimeout(mode, baud int, initiator *DEPTarget, timeout time.Duration) (*DEPTarget, error) {
+	ms, err := timeoutMillis(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return d.InitiatorSelectDEPTarget(mode, baud, initiator, ms)
+}
+
+// Like TargetInit(), but with a timeout of type time.Duration.
+func (d Device) TargetInitTimeout(t Target, rx []byte, timeout time.Duration) (n int, tt Target, err error) {
+	ms, err := timeoutMillis(timeout)
+	if err != nil {
+		return 0, t, err
+	}
+
+	return d.TargetInit(t, rx, ms)
+}
+
+// Like TargetSendBytes(), but with a timeout of type time.Duration.
+func (d Device) TargetSendBytesTimeout(tx []byte, timeout time.Duration) (n int, err error) {
+	ms, err := timeoutMillis(timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.TargetSendBytes(tx, ms)
+}
+
+// Like TargetReceiveBytes(), but with a timeout of type time.Duration.
+func (d Device) TargetReceiveBytesTimeout(rx []byte, timeout time.Duration) (n int, err error) {
+	ms, err := timeoutMillis(timeout)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.TargetReceiveBytes(rx, ms)
+}
+
+// Set one of the properties TimeoutCommand, TimeoutATR and TimeoutCom to
+// value, which is rounded up to the next millisecond. Pass NoTimeout to
+// disable the timeout. DefaultTimeout and other negative values are invalid
+// as libnfc has no way to restore the default.
+func (d Device) SetPropertyDuration(property int, value time.Duration) error {
+	if property != TimeoutCommand && property != TimeoutATR && property != TimeoutCom || value < 0 {
+		return Error(EINVARG)
+	}
+
+	ms, err := timeoutMillis(value)
+	if err != nil {
+		return err
+	}
+
+	return d.SetPropertyInt(property, ms)
+}
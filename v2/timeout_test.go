@@ -0,0 +1,141 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "testing"
+import "time"
+
+func TestTimeoutMillis(t *testing.T) {
+	good := map[time.Duration]int{
+		NoTimeout:               0,
+		DefaultTimeout:          -1,
+		time.Nanosecond:         1,
+		time.Millisecond:        1,
+		1500 * time.Microsecond: 2,
+		5 * time.Second:         5000,
+	}
+
+	for d, want := range good {
+		if got, err := timeoutMillis(d); err != nil || got != want {
+			t.Errorf("timeoutMillis(%v) = %d, %v, want %d", d, got, err, want)
+		}
+	}
+
+	for _, d := range []time.Duration{-2, -time.Second, 1 << 62} {
+		if _, err := timeoutMillis(d); err == nil {
+			t.Errorf("timeoutMillis(%v) succeeded", d)
+		}
+	}
+
+	periods := map[time.Duration]int{
+		time.Microsecond:   1,
+		PollPeriodUnit:     1,
+		PollPeriodUnit + 1: 2,
+		MaxPollPeriod:      15,
+	}
+
+	for p, want := range periods {
+		if got, err := pollPeriodUnits(p); err != nil || got != want {
+			t.Errorf("pollPeriodUnits(%v) = %d, %v, want %d", p, got, err, want)
+		}
+	}
+
+	for _, p := range []time.Duration{0, -PollPeriodUnit, MaxPollPeriod + 1} {
+		if _, err := pollPeriodUnits(p); err == nil {
+			t.Errorf("pollPeriodUnits(%v) succeeded", p)
+		}
+	}
+}
+
+// The Duration variants must pass the converted timeout on.
+func TestTimeoutMethods(t *testing.T) {
+	d := replayCalls(t, []deviceCall{
+		{Op: "InitiatorTransceiveBytes", callArgs: callArgs{Tx: []byte{0x30, 0x04}, RxLen: 16, Timeout: 2}, callResult: callResult{N: 16, Rx: make([]byte, 16)}},
+		{Op: "SetPropertyInt", callArgs: callArgs{Property: TimeoutCommand, Value: 250}},
+		{Op: "Close"},
+	})
+
+	rx := make([]byte, 16)
+	if n, err := d.InitiatorTransceiveBytesTimeout([]byte{0x30, 0x04}, rx, 1500*time.Microsecond); err != nil || n != 16 {
+		t.Errorf("InitiatorTransceiveBytesTimeout() = %d, %v", n, err)
+	}
+
+	if n, err := d.InitiatorTransceiveBytesTimeout([]byte{0x30, 0x04}, rx, -time.Second); n != 0 || err != Error(EINVARG) {
+		t.Errorf("InitiatorTransceiveBytesTimeout() = %d, %v, want 0, EINVARG", n, err)
+	}
+
+	if n, err := d.TargetSendBytesTimeout([]byte{0x90, 0x00}, -time.Second); n != 0 || err != Error(EINVARG) {
+		t.Errorf("TargetSendBytesTimeout() = %d, %v, want 0, EINVARG", n, err)
+	}
+
+	if n, err := d.TargetReceiveBytesTimeout(rx, -time.Second); n != 0 || err != Error(EINVARG) {
+		t.Errorf("TargetReceiveBytesTimeout() = %d, %v, want 0, EINVARG", n, err)
+	}
+
+	if err := d.SetPropertyDuration(TimeoutCommand, 250*time.Millisecond); err != nil {
+		t.Error("SetPropertyDuration():", err)
+	}
+
+	if err := d.SetPropertyDuration(EasyFraming, time.Second); err != Error(EINVARG) {
+		t.Errorf("SetPropertyDuration(EasyFraming) = %v, want EINVARG", err)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Error("Close():", err)
+	}
+}
+
+// An Initiator recording the timeouts it is called with. The card never
+// answers.
+type timeoutRecorder []int
+
+func (r *timeoutRecorder) InitiatorTransceiveBytes(tx, rx []byte, timeout int) (int, error) {
+	*r = append(*r, timeout)
+	return 0, Error(ETIMEOUT)
+}
+
+func (r *timeoutRecorder) InitiatorTransceiveBits(tx, txPar []byte, txLength uint, rx, rxPar []byte) (int, error) {
+	return 0, Error(EDEVNOTSUPP)
+}
+
+// The Timeout fields and timeout parameters are converted to milliseconds.
+func TestTimeoutFields(t *testing.T) {
+	var r timeoutRecorder
+	c := NewIClass(&r, nil)
+	c.ActAll()
+	c.Timeout = 1500 * time.Microsecond
+	c.ActAll()
+	c.Timeout = NoTimeout
+	c.ActAll()
+	if len(r) != 3 || r[0] != -1 || r[1] != 2 || r[2] != 0 {
+		t.Errorf("got timeouts %v, want [-1 2 0]", r)
+	}
+
+	s := NewSRx(&r)
+	s.Timeout = -time.Second
+	if _, err := s.GetUID(); err != Error(EINVARG) {
+		t.Errorf("SRx.GetUID() with negative timeout: got error %v, want EINVARG", err)
+	}
+
+	if _, err := DEPConnect(nil, Active, Nbr424, nil, -time.Second); err != Error(EINVARG) {
+		t.Errorf("DEPConnect() with negative timeout: got error %v, want EINVARG", err)
+	}
+
+	e := NewFelicaEmulator(&fakeTargetDevice{}, NewFelicaType3Memory(nil, 4))
+	e.Timeout = -time.Second
+	if err := e.Run(); err != Error(EINVARG) {
+		t.Errorf("FelicaEmulator.Run() with negative timeout: got error %v, want EINVARG", err)
+	}
+}
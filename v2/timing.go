@@ -0,0 +1,224 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "fmt"
+import "math"
+import "sort"
+import "time"
+
+// Carrier frequency of ISO14443 and FeliCa in Hz. The cycle counts returned
+// by Device.InitiatorTransceiveBytesTimed() and
+// Device.InitiatorTransceiveBitsTimed() are in periods of the carrier.
+const CarrierFrequency = 13560000
+
+// Number of carrier periods in one elementary time unit (the duration of one
+// bit) at 106 kbps.
+const CyclesPerETU = 128
+
+// Convert a cycle count as returned by the timed transceive functions to a
+// duration.
+func CyclesToDuration(cycles uint32) time.Duration {
+	return time.Duration(uint64(cycles) * uint64(time.Second) / CarrierFrequency)
+}
+
+// Convert a duration into a cycle count, rounding down. Durations too long
+// to be represented saturate.
+func DurationToCycles(d time.Duration) uint32 {
+	if d <= 0 {
+		return 0
+	}
+
+	c := uint64(d) * CarrierFrequency / uint64(time.Second)
+	if c > math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	return uint32(c)
+}
+
+// TimedInitiator is the subset of the methods of Device needed by
+// TimingAnalyzer. Device implements TimedInitiator.
+type TimedInitiator interface {
+	InitiatorTransceiveBytesTimed(tx, rx []byte, cycles uint32) (n int, c uint32, err error)
+}
+
+// Baseline response time of a type of card as measured by
+// TimingAnalyzer.Calibrate().
+type TimingProfile struct {
+	Samples int           // number of rounds measured
+	Mean    time.Duration // mean response time
+	StdDev  time.Duration // standard deviation of the response time
+	Min     time.Duration // fastest response
+	Max     time.Duration // slowest response
+}
+
+// Result of TimingAnalyzer.Analyze().
+type TimingReport struct {
+	Profile    TimingProfile   // the baseline compared against
+	Samples    []time.Duration // response time of each round
+	Median     time.Duration   // median response time
+	Limit      time.Duration   // longest response time considered normal
+	Suspicious []int           // rounds whose response time exceeds Limit
+	Relay      bool            // the response times suggest a relay
+}
+
+// TimingAnalyzer detects relayed cards by measuring the time the card takes
+// to answer challenges with Device.InitiatorTransceiveBytesTimed() and
+// comparing it to a baseline obtained from genuine cards of the same type.
+// A relay adds the latency of its link to each response, which is usually
+// far more than the jitter of a genuine card.
+//
+// The challenges should be commands the card must answer with data that
+// depends on the challenge (e.g. an authentication with a fresh nonce) so
+// a relay cannot answer them ahead of time. Note that the timed transceive
+// functions require EasyFraming to be disabled, so the challenges are sent
+// as is. The analyzer is a heuristic: it raises the bar for relay attacks
+// but does not replace a proper distance-bounding protocol.
+type TimingAnalyzer struct {
+	Device    TimedInitiator           // device talking to the card
+	Cycles    uint32                   // cycle limit, see InitiatorTransceiveBytesTimed()
+	Threshold float64                  // tolerated deviation in standard deviations
+	Margin    time.Duration            // tolerated deviation in addition to Threshold
+	Profiles  map[string]TimingProfile // baseline for each type of card
+}
+
+// Make a new TimingAnalyzer using d without any profiles. Responses more
+// than 3 standard deviations plus 2 µs slower than the baseline are deemed
+// suspicious.
+func NewTimingAnalyzer(d TimedInitiator) *TimingAnalyzer {
+	return &TimingAnalyzer{
+		Device:    d,
+		Threshold: 3,
+		Margin:    2 * time.Microsecond,
+		Profiles:  make(map[string]TimingProfile),
+	}
+}
+
+// Send rounds challenges and return the response time of each.
+func (a *TimingAnalyzer) measure(rounds int, challenge func(round int) []byte) ([]time.Duration, error) {
+	if rounds < 1 {
+		return nil, Error(EINVARG)
+	}
+
+	rx := make([]byte, 264)
+	samples := make([]time.Duration, rounds)
+	for i := range samples {
+		_, c, err := a.Device.InitiatorTransceiveBytesTimed(challenge(i), rx, a.Cycles)
+		if err != nil {
+			return nil, err
+		}
+
+		samples[i] = CyclesToDuration(c)
+	}
+
+	return samples, nil
+}
+
+// Compute the profile of a set of samples.
+func timingProfile(samples []time.Duration) TimingProfile {
+	p := TimingProfile{Samples: len(samples), Min: samples[0], Max: samples[0]}
+
+	var sum float64
+	for _, s := range samples {
+		sum += float64(s)
+		if s < p.Min {
+			p.Min = s
+		}
+
+		if s > p.Max {
+			p.Max = s
+		}
+	}
+
+	mean := sum / float64(len(samples))
+
+	var sq float64
+	for _, s := range samples {
+		sq += (float64(s) - mean) * (float64(s) - mean)
+	}
+
+	p.Mean = time.Duration(mean)
+	if len(samples) > 1 {
+		p.StdDev = time.Duration(math.Sqrt(sq / float64(len(samples)-1)))
+	}
+
+	return p
+}
+
+// Compute the median of a set of samples.
+func timingMedian(samples []time.Duration) time.Duration {
+	s := append([]time.Duration(nil), samples...)
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+
+	if len(s)%2 == 1 {
+		return s[len(s)/2]
+	}
+
+	return (s[len(s)/2-1] + s[len(s)/2]) / 2
+}
+
+// Measure the response time of a genuine card of type cardType over the
+// given number of rounds and store the result in Profiles. challenge is
+// called to generate the challenge for each round. The card type is an
+// arbitrary name chosen by the caller; cards of the same model answering
+// the same kind of challenge should share a profile.
+func (a *TimingAnalyzer) Calibrate(cardType string, rounds int, challenge func(round int) []byte) (TimingProfile, error) {
+	samples, err := a.measure(rounds, challenge)
+	if err != nil {
+		return TimingProfile{}, err
+	}
+
+	p := timingProfile(samples)
+	if a.Profiles == nil {
+		a.Profiles = make(map[string]TimingProfile)
+	}
+
+	a.Profiles[cardType] = p
+	return p, nil
+}
+
+// Run rounds rounds of challenge-response with a card of type cardType and
+// compare the response times to the profile of cardType. Rounds slower
+// than the limit are reported as suspicious; if the median response time
+// exceeds the limit, the card is deemed relayed. Using the median makes the
+// verdict robust against occasional delays of genuine cards.
+func (a *TimingAnalyzer) Analyze(cardType string, rounds int, challenge func(round int) []byte) (*TimingReport, error) {
+	p, ok := a.Profiles[cardType]
+	if !ok {
+		return nil, fmt.Errorf("timing: no profile for card type %q", cardType)
+	}
+
+	samples, err := a.measure(rounds, challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &TimingReport{
+		Profile: p,
+		Samples: samples,
+		Median:  timingMedian(samples),
+		Limit:   p.Mean + time.Duration(a.Threshold*float64(p.StdDev)) + a.Margin,
+	}
+
+	for i, s := range samples {
+		if s > r.Limit {
+			r.Suspicious = append(r.Suspicious, i)
+		}
+	}
+
+	r.Relay = r.Median > r.Limit
+	return r, nil
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "testing"
+import "time"
+
+// A TimedInitiator answering after a number of cycles computed by a
+// function of the round.
+type fakeTimedInitiator struct {
+	round  int
+	cycles func(round int) uint32
+}
+
+func (f *fakeTimedInitiator) InitiatorTransceiveBytesTimed(tx, rx []byte, cycles uint32) (int, uint32, error) {
+	c := f.cycles(f.round)
+	f.round++
+	return copy(rx, tx), c, nil
+}
+
+func TestCyclesToDuration(t *testing.T) {
+	if d := CyclesToDuration(CarrierFrequency / 1000); d != time.Millisecond {
+		t.Errorf("CyclesToDuration(%d) = %v, want 1ms", CarrierFrequency/1000, d)
+	}
+
+	if c := DurationToCycles(time.Millisecond); c != CarrierFrequency/1000 {
+		t.Errorf("DurationToCycles(1ms) = %d, want %d", c, CarrierFrequency/1000)
+	}
+
+	if c := DurationToCycles(time.Hour); c != 1<<32-1 {
+		t.Errorf("DurationToCycles(1h) = %d, want saturation", c)
+	}
+}
+
+// Calibrate with a genuine card, then tell genuine from relayed cards.
+func TestTimingAnalyzer(t *testing.T) {
+	// genuine card: 1236 cycles (FDT) plus some jitter
+	genuine := func(round int) uint32 { return 1236 + uint32(round%5)*16 }
+	challenge := func(round int) []byte { return []byte{0x60, byte(round)} }
+
+	dev := &fakeTimedInitiator{cycles: genuine}
+	a := NewTimingAnalyzer(dev)
+	p, err := a.Calibrate("test card", 50, challenge)
+	if err != nil {
+		t.Fatal("Calibrate():", err)
+	}
+
+	if p.Samples != 50 || p.Min != CyclesToDuration(1236) || p.Max != CyclesToDuration(1236+64) {
+		t.Errorf("unexpected profile %+v", p)
+	}
+
+	dev.round = 0
+	r, err := a.Analyze("test card", 20, challenge)
+	if err != nil {
+		t.Fatal("Analyze():", err)
+	}
+
+	if r.Relay || len(r.Suspicious) != 0 {
+		t.Errorf("genuine card flagged: %+v", r)
+	}
+
+	// relayed card: 500 µs of additional latency
+	dev.round = 0
+	dev.cycles = func(round int) uint32 { return genuine(round) + DurationToCycles(500*time.Microsecond) }
+	if r, err = a.Analyze("test card", 20, challenge); err != nil {
+		t.Fatal("Analyze():", err)
+	}
+
+	if !r.Relay || len(r.Suspicious) != 20 {
+		t.Errorf("relayed card not flagged: %+v", r)
+	}
+
+	// genuine card that happens to be slow once
+	dev.round = 0
+	dev.cycles = func(round int) uint32 {
+		if round == 3 {
+			return 20000
+		}
+
+		return genuine(round)
+	}
+
+	if r, err = a.Analyze("test card", 20, challenge); err != nil {
+		t.Fatal("Analyze():", err)
+	}
+
+	if r.Relay || len(r.Suspicious) != 1 || r.Suspicious[0] != 3 {
+		t.Errorf("single slow round misjudged: %+v", r)
+	}
+
+	if _, err = a.Analyze("other card", 20, challenge); err == nil {
+		t.Error("Analyze() without profile succeeded")
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "fmt"
+import "io"
+import "sync"
+import "time"
+
+// Direction of a traced transfer.
+type TraceDirection int
+
+const (
+	TraceSend    TraceDirection = iota // data sent by the device
+	TraceReceive                       // data received by the device
+)
+
+func (d TraceDirection) String() string {
+	switch d {
+	case TraceSend:
+		return "send"
+	case TraceReceive:
+		return "receive"
+	default:
+		return fmt.Sprintf("TraceDirection(%d)", int(d))
+	}
+}
+
+// A transfer traced by a Tracer. Operations that send and receive data
+// (like Device.InitiatorTransceiveBytes()) are reported as two events
+// sharing the same Time and Duration: one for the data sent and, if the
+// operation succeeded, one for the data received. The error of a failed
+// operation is reported with its last event.
+type TraceEvent struct {
+	Op        string         // the method, e.g. "InitiatorTransceiveBytes"
+	Direction TraceDirection // whether Data was sent or received
+	Data      []byte         // the data transferred
+	Bits      uint           // length of Data in bits, without parity bits
+	Parity    []byte         // parity bit of each byte, nil if not available
+	Time      time.Time      // when the operation started
+	Duration  time.Duration  // how long the operation took
+	Cycles    uint32         // cycles counted by the timed operations, else 0
+	Err       error          // the error returned by the operation
+}
+
+// A Tracer is called for each frame sent or received by a Device. See
+// Device.SetTracer(). Tracers are called synchronously while the device is
+// busy, so they should return quickly and must not call methods of the
+// device other than AbortCommand(). The event and the slices it refers to
+// may be retained.
+type Tracer func(TraceEvent)
+
+// The tracer of a Device, shared by all copies of the Device and by the
+// devices returned by Record() for it.
+type tracerState struct {
+	m sync.Mutex
+	t Tracer
+}
+
+// Install t as the tracer of d, replacing any previous tracer. t is called
+// for every frame sent or received with the Initiator* and Target* methods
+// of d or of any copy of d. Pass nil to disable tracing.
+func (d Device) SetTracer(t Tracer) {
+	if d.state == nil {
+		return
+	}
+
+	d.state.tracer.m.Lock()
+	d.state.tracer.t = t
+	d.state.tracer.m.Unlock()
+}
+
+// Return the tracer of d or nil if there is none.
+func (d Device) tracer() Tracer {
+	if d.state == nil {
+		return nil
+	}
+
+	d.state.tracer.m.Lock()
+	defer d.state.tracer.m.Unlock()
+
+	return d.state.tracer.t
+}
+
+// A frame to be traced.
+type traceFrame struct {
+	data, parity []byte
+	bits         uint
+	valid        bool
+}
+
+// The first n bytes of b as a frame to be traced. A negative n (i.e. an
+// error code) gives an empty frame.
+func traceBytes(b []byte, n int) traceFrame {
+	if n < 0 {
+		n = 0
+	} else if n > len(b) {
+		n = len(b)
+	}
+
+	return traceFrame{b[:n], nil, uint(8 * n), true}
+}
+
+// The first n bits of b with parity par as a frame to be traced. A
+// negative n (i.e. an error code) gives an empty frame.
+func traceBits(b, par []byte, n int) traceFrame {
+	if n < 0 {
+		n = 0
+	}
+
+	nbytes := (n + 7) / 8
+	if nbytes > len(b) || nbytes > len(par) {
+		return traceBytes(b, len(b))
+	}
+
+	return traceFrame{b[:nbytes], par[:nbytes], uint(n), true}
+}
+
+// Report the frames tx and rx of operation op to the tracer of d, if any.
+// Invalid frames are not reported.
+func (d Device) trace(op string, start time.Time, tx, rx traceFrame, cycles uint32, err error) {
+	t := d.tracer()
+	if t == nil {
+		return
+	}
+
+	ev := TraceEvent{Op: op, Time: start, Duration: time.Since(start), Cycles: cycles}
+	report := func(dir TraceDirection, f traceFrame, last bool) {
+		ev.Direction = dir
+		ev.Data = append([]byte(nil), f.data...)
+		ev.Bits = f.bits
+		ev.Parity = nil
+		if f.parity != nil {
+			ev.Parity = append([]byte(nil), f.parity...)
+		}
+
+		if last {
+			ev.Err = err
+		}
+
+		t(ev)
+	}
+
+	receive := rx.valid && (err == nil || !tx.valid)
+	if tx.valid {
+		report(TraceSend, tx, !receive)
+	}
+
+	if receive {
+		report(TraceReceive, rx, true)
+	}
+}
+
+// Make a Tracer writing a human readable hex dump of each event to w, one
+// line per event. The tracer is safe for concurrent use. Errors writing to
+// w are ignored.
+func HexDumpTracer(w io.Writer) Tracer {
+	var m sync.Mutex
+
+	return func(ev TraceEvent) {
+		var b bytes.Buffer
+
+		arrow := ">>"
+		if ev.Direction == TraceReceive {
+			arrow = "<<"
+		}
+
+		fmt.Fprintf(&b, "%s %s %s", ev.Time.Format("15:04:05.000000"), ev.Op, arrow)
+		for _, x := range ev.Data {
+			fmt.Fprintf(&b, " %02x", x)
+		}
+
+		if ev.Bits != uint(8*len(ev.Data)) {
+			fmt.Fprintf(&b, " (%d bits)", ev.Bits)
+		}
+
+		if ev.Parity != nil {
+			b.WriteString(" par ")
+			for _, p := range ev.Parity {
+				b.WriteByte('0' + p&1)
+			}
+		}
+
+		fmt.Fprintf(&b, " %v", ev.Duration)
+		if ev.Cycles != 0 {
+			fmt.Fprintf(&b, " %d cycles", ev.Cycles)
+		}
+
+		if ev.Err != nil {
+			fmt.Fprintf(&b, " error: %v", ev.Err)
+		}
+
+		b.WriteByte('\n')
+
+		m.Lock()
+		w.Write(b.Bytes())
+		m.Unlock()
+	}
+}
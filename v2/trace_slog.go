@@ -0,0 +1,64 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+//go:build go1.21
+// +build go1.21
+
+package nfc
+
+// the package has its own type called context
+import gocontext "context"
+import "encoding/hex"
+import "log/slog"
+
+// Make a Tracer logging each event as a structured record with message
+// "nfc transfer" to l. Successful transfers are logged with the given
+// level, failed ones with slog.LevelError. The record carries the
+// attributes op, direction, data (hex), bits, parity (if available),
+// start, duration, cycles (if nonzero) and error (if any).
+func SlogTracer(l *slog.Logger, level slog.Level) Tracer {
+	return func(ev TraceEvent) {
+		lvl := level
+		if ev.Err != nil {
+			lvl = slog.LevelError
+		}
+
+		ctx := gocontext.Background()
+		if !l.Enabled(ctx, lvl) {
+			return
+		}
+
+		attrs := []slog.Attr{
+			slog.String("op", ev.Op),
+			slog.String("direction", ev.Direction.String()),
+			slog.String("data", hex.EncodeToString(ev.Data)),
+			slog.Uint64("bits", uint64(ev.Bits)),
+		}
+
+		if ev.Parity != nil {
+			attrs = append(attrs, slog.String("parity", hex.EncodeToString(ev.Parity)))
+		}
+
+		attrs = append(attrs, slog.Time("start", ev.Time), slog.Duration("duration", ev.Duration))
+		if ev.Cycles != 0 {
+			attrs = append(attrs, slog.Uint64("cycles", uint64(ev.Cycles)))
+		}
+
+		if ev.Err != nil {
+			attrs = append(attrs, slog.String("error", ev.Err.Error()))
+		}
+
+		l.LogAttrs(ctx, lvl, "nfc transfer", attrs...)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+//go:build go1.21
+// +build go1.21
+
+package nfc
+
+import "log/slog"
+import "strings"
+import "testing"
+import "time"
+
+func TestSlogTracer(t *testing.T) {
+	var b strings.Builder
+	h := slog.NewTextHandler(&b, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "start" {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	})
+
+	tr := SlogTracer(slog.New(h), slog.LevelDebug)
+	tr(TraceEvent{Op: "TargetSendBits", Direction: TraceSend, Data: []byte{0x0a}, Bits: 4, Parity: []byte{0}, Duration: time.Millisecond})
+
+	want := `level=DEBUG msg="nfc transfer" op=TargetSendBits direction=send data=0a bits=4 parity=00 duration=1ms` + "\n"
+	if b.String() != want {
+		t.Errorf("got %s, want %s", b.String(), want)
+	}
+
+	// debug records are filtered by the default level, errors are not
+	b.Reset()
+	tr = SlogTracer(slog.New(slog.NewTextHandler(&b, nil)), slog.LevelDebug)
+	tr(TraceEvent{Op: "TargetReceiveBytes", Direction: TraceReceive})
+	tr(TraceEvent{Op: "TargetReceiveBytes", Direction: TraceReceive, Err: Error(ETGRELEASED)})
+	if n := strings.Count(b.String(), "\n"); n != 1 || !strings.Contains(b.String(), "level=ERROR") {
+		t.Errorf("unexpected records %q", b.String())
+	}
+}
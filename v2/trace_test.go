@@ -0,0 +1,83 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import "bytes"
+import "strings"
+import "testing"
+import "time"
+
+// Check which events are reported for the different kinds of transfers.
+func TestTrace(t *testing.T) {
+	var events []TraceEvent
+	d := Device{state: newDeviceState()}
+	d.SetTracer(func(ev TraceEvent) { events = append(events, ev) })
+
+	start := time.Now()
+	tx := []byte{0x30, 0x04}
+	rx := []byte{0x01, 0x02, 0x03, 0xff}
+
+	d.trace("InitiatorTransceiveBytes", start, traceBytes(tx, len(tx)), traceBytes(rx, 3), 0, nil)
+	d.trace("InitiatorTransceiveBytes", start, traceBytes(tx, len(tx)), traceBytes(rx, ETIMEOUT), 0, Error(ETIMEOUT))
+	d.trace("TargetTransceiveBits", start, traceFrame{}, traceBits(rx, []byte{1, 0, 1, 1}, 4), 0, nil)
+	d.trace("TargetReceiveBytes", start, traceFrame{}, traceBytes(rx, ETGRELEASED), 0, Error(ETGRELEASED))
+
+	want := []TraceEvent{
+		{Op: "InitiatorTransceiveBytes", Direction: TraceSend, Data: tx, Bits: 16},
+		{Op: "InitiatorTransceiveBytes", Direction: TraceReceive, Data: rx[:3], Bits: 24},
+		{Op: "InitiatorTransceiveBytes", Direction: TraceSend, Data: tx, Bits: 16, Err: Error(ETIMEOUT)},
+		{Op: "TargetTransceiveBits", Direction: TraceReceive, Data: rx[:1], Bits: 4, Parity: []byte{1}},
+		{Op: "TargetReceiveBytes", Direction: TraceReceive, Data: []byte{}, Bits: 0, Err: Error(ETGRELEASED)},
+	}
+
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+
+	for i, ev := range events {
+		w := want[i]
+		if ev.Op != w.Op || ev.Direction != w.Direction || !bytes.Equal(ev.Data, w.Data) ||
+			ev.Bits != w.Bits || !bytes.Equal(ev.Parity, w.Parity) || ev.Err != w.Err || !ev.Time.Equal(start) {
+			t.Errorf("event %d: got %+v, want %+v", i, ev, w)
+		}
+	}
+
+	// the tracer must not see later modifications of the buffers
+	rx[0] = 0xee
+	if events[1].Data[0] != 0x01 {
+		t.Error("traced data aliases the receive buffer")
+	}
+
+	d.SetTracer(nil)
+	d.trace("InitiatorTransceiveBytes", start, traceBytes(tx, len(tx)), traceBytes(rx, 3), 0, nil)
+	if len(events) != len(want) {
+		t.Error("event reported after removing the tracer")
+	}
+}
+
+func TestHexDumpTracer(t *testing.T) {
+	var b strings.Builder
+	tr := HexDumpTracer(&b)
+	start := time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)
+
+	tr(TraceEvent{Op: "InitiatorTransceiveBits", Direction: TraceSend, Data: []byte{0x26}, Bits: 7, Parity: []byte{0}, Time: start, Duration: time.Millisecond})
+	tr(TraceEvent{Op: "InitiatorTransceiveBytes", Direction: TraceReceive, Data: []byte{0x90, 0x00}, Bits: 16, Time: start, Cycles: 1236, Err: Error(ETIMEOUT)})
+
+	want := "03:04:05.000006 InitiatorTransceiveBits >> 26 (7 bits) par 0 1ms\n" +
+		"03:04:05.000006 InitiatorTransceiveBytes << 90 00 0s 1236 cycles error: timeout\n"
+	if b.String() != want {
+		t.Errorf("got\n%swant\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,340 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+// the package has its own type called context
+import gocontext "context"
+import "errors"
+import "sync"
+import "time"
+
+// AID of the NDEF tag application of NFC Forum Type 4 Tags, version 2.0.
+var Type4AID = []byte{0xd2, 0x76, 0x00, 0x00, 0x85, 0x01, 0x01}
+
+// File identifiers of the Type 4 Tag NDEF application.
+const (
+	Type4FileCC   = 0xe103 // capability container
+	Type4FileNDEF = 0xe104 // NDEF file
+)
+
+// Maximum amount of data read or written with one APDU (MLe and MLc). The
+// PN53x cannot transfer much more than this in one frame.
+const type4MaxData = 0xf0
+
+// Default size of the NDEF file, including the two byte NLEN field.
+const type4DefaultFileSize = 1024
+
+// Returned by an NDEFStore if the NDEF message cannot be written.
+var ErrNDEFReadOnly = errors.New("NDEF message is read only")
+
+// NDEFStore holds the NDEF message of an emulated tag. The emulator reads
+// the message at the beginning of each session, so changes made through the
+// store become visible the next time a reader selects the tag.
+type NDEFStore interface {
+	// Return the current NDEF message.
+	ReadNDEF() ([]byte, error)
+
+	// Replace the NDEF message with msg after a reader wrote it. Return
+	// ErrNDEFReadOnly or some other error to refuse the write.
+	WriteNDEF(msg []byte) error
+}
+
+// NDEFBuffer is an NDEFStore keeping the NDEF message in memory. It is safe
+// for concurrent use.
+type NDEFBuffer struct {
+	mu       sync.Mutex
+	msg      []byte
+	readOnly bool
+}
+
+// Make a new NDEFBuffer holding msg. If readOnly is set, writes are refused.
+func NewNDEFBuffer(msg []byte, readOnly bool) *NDEFBuffer {
+	return &NDEFBuffer{msg: append([]byte(nil), msg...), readOnly: readOnly}
+}
+
+// Return a copy of the current NDEF message.
+func (b *NDEFBuffer) ReadNDEF() ([]byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]byte(nil), b.msg...), nil
+}
+
+// Replace the NDEF message unless the buffer is read only.
+func (b *NDEFBuffer) WriteNDEF(msg []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.readOnly {
+		return ErrNDEFReadOnly
+	}
+
+	b.msg = append([]byte(nil), msg...)
+	return nil
+}
+
+// Type4Emulator emulates an NFC Forum Type 4 Tag (version 2.0) holding the
+// NDEF message of an NDEFStore. The ISO14443-4 layer is handled by the
+// device, which therefore needs to support it (e.g. the PN532).
+type Type4Emulator struct {
+	Device   TargetDevice    // device used to emulate the tag
+	Target   ISO14443aTarget // the tag emulated, must have an ATS
+	Store    NDEFStore       // holds the NDEF message
+	FileSize int             // size of the NDEF file including NLEN
+	ReadOnly bool            // if set, the tag is advertised as read only
+	Timeout  time.Duration   // see Device.TargetReceiveBytesTimeout()
+
+	selected bool   // NDEF application selected
+	file     uint16 // currently selected file or 0
+	ndef     []byte // NDEF file image: NLEN followed by the message
+}
+
+// Make a new Type4Emulator using d and store with a default target and file
+// size. The timeout is NoTimeout, so the emulator waits for readers
+// indefinitely.
+func NewType4Emulator(d TargetDevice, store NDEFStore) *Type4Emulator {
+	return &Type4Emulator{
+		Device:   d,
+		Target:   defaultISODEPTarget(),
+		Store:    store,
+		FileSize: type4DefaultFileSize,
+	}
+}
+
+// Emulate the tag for one session like CardEmulator.Run() does, with the
+// NDEF application registered under Type4AID: wait for a reader with
+// TargetInit() and answer its commands until it deselects or releases the
+// tag, in which case nil is returned, or until ctx is cancelled, in which
+// case ctx.Err() is returned. Call Run() in a loop to serve one reader after
+// another. Pending writes to the NDEF file are committed to the store as
+// soon as the reader writes a nonzero NLEN.
+func (e *Type4Emulator) Run(ctx gocontext.Context) error {
+	e.selected = false
+	e.file = 0
+
+	c := &CardEmulator{Device: e.Device, Target: e.Target, Timeout: e.Timeout}
+	c.Register(Type4AID, e.Application())
+
+	return c.Run(ctx)
+}
+
+// Process a command APDU and return the response APDU.
+func (e *Type4Emulator) Process(cmd []byte) []byte {
+	a, err := ParseAPDU(cmd)
+	if err != nil {
+		return apduResponse(nil, SWWrongLength)
+	}
+
+	return apduResponse(e.process(a))
+}
+
+// Process a parsed command APDU, returning response data and status word.
+func (e *Type4Emulator) process(a APDU) ([]byte, uint16) {
+	if a.CLA != 0x00 {
+		return nil, SWCLANotSupported
+	}
+
+	switch a.INS {
+	case APDUSelect:
+		return nil, e.selectFile(a)
+	case APDUReadBinary:
+		return e.readBinary(a)
+	case APDUUpdateBinary:
+		return nil, e.updateBinary(a)
+	default:
+		return nil, SWINSNotSupported
+	}
+}
+
+// Return a CardApplication serving the NDEF application of e, to be
+// registered with a CardEmulator under Type4AID. This way, a Type 4 Tag can
+// be emulated alongside other applications. The fields Device, Target and
+// Timeout of e are not used in this case.
+func (e *Type4Emulator) Application() CardApplication {
+	return type4Application{e}
+}
+
+// Adapter from Type4Emulator to CardApplication.
+type type4Application struct {
+	e *Type4Emulator
+}
+
+func (t type4Application) Select(a APDU) ([]byte, error) {
+	return nil, swError(t.e.selectFile(a))
+}
+
+func (t type4Application) Process(a APDU) ([]byte, error) {
+	data, sw := t.e.process(a)
+	return data, swError(sw)
+}
+
+func (t type4Application) Deselect() {
+	t.e.selected = false
+	t.e.file = 0
+}
+
+// Load the NDEF file image from the store.
+func (e *Type4Emulator) load() error {
+	msg, err := e.Store.ReadNDEF()
+	if err != nil {
+		return err
+	}
+
+	if len(msg) > 0x7ffd {
+		return errors.New("NDEF message too long")
+	}
+
+	e.ndef = make([]byte, 2+len(msg))
+	e.ndef[0] = byte(len(msg) >> 8)
+	e.ndef[1] = byte(len(msg))
+	copy(e.ndef[2:], msg)
+
+	return nil
+}
+
+// The size of the NDEF file as advertised in the capability container.
+func (e *Type4Emulator) fileSize() int {
+	size := e.FileSize
+	if size < len(e.ndef) {
+		size = len(e.ndef)
+	}
+
+	if size > 0x7fff {
+		size = 0x7fff
+	}
+
+	return size
+}
+
+// Generate the capability container.
+func (e *Type4Emulator) cc() []byte {
+	size := e.fileSize()
+	write := byte(0x00)
+	if e.ReadOnly {
+		write = 0xff
+	}
+
+	return []byte{
+		0x00, 0x0f, // CCLEN
+		0x20,               // mapping version 2.0
+		0x00, type4MaxData, // MLe
+		0x00, type4MaxData, // MLc
+		0x04, 0x06, // NDEF file control TLV
+		byte(Type4FileNDEF >> 8), byte(Type4FileNDEF & 0xff),
+		byte(size >> 8), byte(size),
+		0x00, // read access
+		write,
+	}
+}
+
+// Handle SELECT.
+func (e *Type4Emulator) selectFile(a APDU) uint16 {
+	switch {
+	case a.P1 == 0x04 && a.P2 == 0x00:
+		// select by name
+		if string(a.Data) != string(Type4AID) {
+			e.selected = false
+			return SWFileNotFound
+		}
+
+		if err := e.load(); err != nil {
+			return SWUnknown
+		}
+
+		e.selected = true
+		e.file = 0
+		return SWSuccess
+
+	case a.P1 == 0x00 && a.P2 == 0x0c:
+		// select by file identifier
+		if !e.selected || len(a.Data) != 2 {
+			return SWFileNotFound
+		}
+
+		id := uint16(a.Data[0])<<8 | uint16(a.Data[1])
+		if id != Type4FileCC && id != Type4FileNDEF {
+			return SWFileNotFound
+		}
+
+		e.file = id
+		return SWSuccess
+
+	default:
+		return SWIncorrectP1P2
+	}
+}
+
+// Handle READ BINARY.
+func (e *Type4Emulator) readBinary(a APDU) ([]byte, uint16) {
+	var file []byte
+	switch e.file {
+	case Type4FileCC:
+		file = e.cc()
+	case Type4FileNDEF:
+		file = e.ndef
+	default:
+		return nil, SWConditionsNotSatisfied
+	}
+
+	offset := int(a.P1)<<8 | int(a.P2)
+	if a.P1&0x80 != 0 || offset > len(file) {
+		return nil, SWWrongP1P2
+	}
+
+	le := a.Le
+	if le > type4MaxData {
+		le = type4MaxData
+	}
+
+	end := offset + le
+	if end > len(file) {
+		end = len(file)
+	}
+
+	return file[offset:end], SWSuccess
+}
+
+// Handle UPDATE BINARY. Once the reader writes a nonzero NLEN, the message
+// is committed to the store.
+func (e *Type4Emulator) updateBinary(a APDU) uint16 {
+	if e.file != Type4FileNDEF || e.ReadOnly {
+		return SWSecurityNotSatisfied
+	}
+
+	offset := int(a.P1)<<8 | int(a.P2)
+	end := offset + len(a.Data)
+	if a.P1&0x80 != 0 || end > e.fileSize() {
+		return SWWrongP1P2
+	}
+
+	if end > len(e.ndef) {
+		e.ndef = append(e.ndef, make([]byte, end-len(e.ndef))...)
+	}
+
+	copy(e.ndef[offset:], a.Data)
+
+	nlen := int(e.ndef[0])<<8 | int(e.ndef[1])
+	if offset < 2 && nlen != 0 {
+		if 2+nlen > len(e.ndef) {
+			return SWWrongData
+		}
+
+		if err := e.Store.WriteNDEF(e.ndef[2 : 2+nlen]); err != nil {
+			return SWSecurityNotSatisfied
+		}
+	}
+
+	return SWSuccess
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2026 Robert Clausecker <fuzxxl@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify it
+// under the terms of the GNU Lesser General Public License as published by the
+// Free Software Foundation, version 3.
+//
+// This program is distributed in the hope that it will be useful, but WITHOUT
+// ANY WARRANTY; without even the implied warranty of MERCHANTABILITY or
+// FITNESS FOR A PARTICULAR PURPOSE.  See the GNU General Public License for
+// more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>
+
+package nfc
+
+import gocontext "context"
+import "bytes"
+import "testing"
+
+// A TargetDevice playing back a script of frames sent by the initiator and
+// recording the answers of the emulator. Once the script is exhausted,
+// ETGRELEASED is returned.
+type fakeTargetDevice struct {
+	script [][]byte
+	sent   [][]byte
+}
+
+func (f *fakeTargetDevice) next(rx []byte) (int, error) {
+	if len(f.script) == 0 {
+		return 0, Error(ETGRELEASED)
+	}
+
+	n := copy(rx, f.script[0])
+	f.script = f.script[1:]
+	return n, nil
+}
+
+func (f *fakeTargetDevice) TargetInit(t Target, rx []byte, timeout int) (int, Target, error) {
+	n, err := f.next(rx)
+	return n, t, err
+}
+
+func (f *fakeTargetDevice) TargetSendBytes(tx []byte, timeout int) (int, error) {
+	f.sent = append(f.sent, append([]byte(nil), tx...))
+	return len(tx), nil
+}
+
+func (f *fakeTargetDevice) TargetReceiveBytes(rx []byte, timeout int) (int, error) {
+	return f.next(rx)
+}
+
+func (f *fakeTargetDevice) TargetSendBits(tx []byte, txPar []byte, txLength uint) (int, error) {
+	f.sent = append(f.sent, append([]byte(nil), tx[:(txLength+7)/8]...))
+	return int(txLength), nil
+}
+
+func (f *fakeTargetDevice) TargetTransceiveBits(rx []byte, rxPar []byte, rxLength uint) (int, error) {
+	n, err := f.next(rx)
+	return 8 * n, err
+}
+
+func (f *fakeTargetDevice) SetPropertyBool(property int, value bool) error {
+	return nil
+}
+
+// Run the NDEF read and write procedures against a Type4Emulator.
+func TestType4Emulator(t *testing.T) {
+	msg := []byte{0xd1, 0x01, 0x04, 'U', 0x04, 'a', '.', 'b'}
+	newMsg := []byte{0xd1, 0x01, 0x03, 'T', 0x00, 'h', 'i'}
+
+	dev := &fakeTargetDevice{script: [][]byte{
+		{0x00, 0xa4, 0x04, 0x00, 0x07, 0xd2, 0x76, 0x00, 0x00, 0x85, 0x01, 0x01, 0x00},
+		{0x00, 0xa4, 0x00, 0x0c, 0x02, 0xe1, 0x03},
+		{0x00, 0xb0, 0x00, 0x00, 0x0f},
+		{0x00, 0xa4, 0x00, 0x0c, 0x02, 0xe1, 0x04},
+		{0x00, 0xb0, 0x00, 0x00, 0x02},
+		{0x00, 0xb0, 0x00, 0x02, 0x08},
+		{0x00, 0xd6, 0x00, 0x00, 0x02, 0x00, 0x00},
+		append([]byte{0x00, 0xd6, 0x00, 0x02, byte(len(newMsg))}, newMsg...),
+		{0x00, 0xd6, 0x00, 0x00, 0x02, 0x00, byte(len(newMsg))},
+		{0x00, 0xa4, 0x00, 0x0c, 0x02, 0xe1, 0x05},
+		{0x00, 0xca, 0x00, 0x00},
+	}}
+
+	store := NewNDEFBuffer(msg, false)
+	e := NewType4Emulator(dev, store)
+	if err := e.Run(gocontext.Background()); err != nil {
+		t.Fatal("Run():", err)
+	}
+
+	ok := []byte{0x90, 0x00}
+	want := [][]byte{
+		ok,
+		ok,
+		{0x00, 0x0f, 0x20, 0x00, 0xf0, 0x00, 0xf0, 0x04, 0x06, 0xe1, 0x04, 0x04, 0x00, 0x00, 0x00, 0x90, 0x00},
+		ok,
+		{0x00, byte(len(msg)), 0x90, 0x00},
+		append(append([]byte(nil), msg...), ok...),
+		ok,
+		ok,
+		ok,
+		{0x6a, 0x82},
+		{0x6d, 0x00},
+	}
+
+	if len(dev.sent) != len(want) {
+		t.Fatalf("got %d responses, want %d", len(dev.sent), len(want))
+	}
+
+	for i := range want {
+		if !bytes.Equal(dev.sent[i], want[i]) {
+			t.Errorf("response %d: got % x, want % x", i, dev.sent[i], want[i])
+		}
+	}
+
+	if m, _ := store.ReadNDEF(); !bytes.Equal(m, newMsg) {
+		t.Errorf("stored message % x, want % x", m, newMsg)
+	}
+
+	ctx, cancel := gocontext.WithCancel(gocontext.Background())
+	cancel()
+	if err := e.Run(ctx); err != gocontext.Canceled {
+		t.Errorf("Run() with cancelled context: got error %v, want %v", err, gocontext.Canceled)
+	}
+}